@@ -0,0 +1,311 @@
+// Package aiff reads and writes AIFF and AIFF-C audio files. Samples are
+// deinterleaved into one slice per channel and normalized to the range [-1,1].
+//
+// Supported are uncompressed integer samples of up to 32 bits in both byte
+// orders, 32 and 64 bit floats and µ-law and A-law companded samples. All
+// chunks other than the format and sample data are kept as raw bytes so they
+// can be written back unchanged.
+package aiff
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/gonutz/dsp/pcm"
+)
+
+// Compression types of AIFF-C files. Plain AIFF files always use None.
+const (
+	// None is big-endian signed integer data.
+	None = "NONE"
+	// Twos is the same as None.
+	Twos = "twos"
+	// Sowt is little-endian signed integer data.
+	Sowt = "sowt"
+	// Raw is unsigned 8 bit data.
+	Raw = "raw "
+	// In24 is 24 bit big-endian signed integer data.
+	In24 = "in24"
+	// In32 is 32 bit big-endian signed integer data.
+	In32 = "in32"
+	// Float32 is 32 bit big-endian IEEE float data.
+	Float32 = "fl32"
+	// Float64 is 64 bit big-endian IEEE float data.
+	Float64 = "fl64"
+	// MuLaw is 8 bit G.711 µ-law data.
+	MuLaw = "ulaw"
+	// ALaw is 8 bit G.711 A-law data.
+	ALaw = "alaw"
+)
+
+// aifcVersion is the timestamp of the AIFF-C specification, written to the
+// FVER chunk.
+const aifcVersion = 0xA2805140
+
+// Format describes the layout of the sample data.
+type Format struct {
+	ChannelCount int
+	SampleRate   float64
+	// SampleSize is the number of significant bits in a sample. Integer
+	// samples are stored in the smallest number of whole bytes that hold them.
+	// For float and companded data this value is informational only.
+	SampleSize int
+	// AIFC is true for AIFF-C files.
+	AIFC bool
+	// Compression is one of the compression types, e.g. None or Sowt. The
+	// upper case variants FL32, FL64, ULAW and ALAW are also understood. Plain
+	// AIFF files must use None.
+	Compression string
+	// CompressionName is the human readable name of the compression that is
+	// stored in AIFF-C files.
+	CompressionName string
+}
+
+func (f Format) validate() error {
+	if f.ChannelCount <= 0 || f.ChannelCount > 0x7FFF {
+		return errors.New("aiff: invalid channel count")
+	}
+	if !(f.SampleRate > 0) || math.IsInf(f.SampleRate, 0) {
+		return errors.New("aiff: invalid sample rate")
+	}
+	if !f.AIFC && f.Compression != None {
+		return errors.New("aiff: plain AIFF files must use compression NONE")
+	}
+	if len(f.Compression) != 4 {
+		return errors.New("aiff: compression types must have 4 characters")
+	}
+	if len(f.CompressionName) > 255 {
+		return errors.New("aiff: compression name is too long")
+	}
+	s, ok := f.sampleFormat()
+	if !ok {
+		return errors.New("aiff: unsupported compression type " + f.Compression)
+	}
+	if err := s.Validate(); err != nil {
+		return errors.New("aiff: unsupported sample size")
+	}
+	return nil
+}
+
+func (f Format) sampleFormat() (pcm.SampleFormat, bool) {
+	bits := (f.SampleSize + 7) / 8 * 8
+	switch f.Compression {
+	case None, Twos:
+		return pcm.SampleFormat{Encoding: pcm.Signed, Bits: bits, BigEndian: true}, true
+	case Sowt:
+		return pcm.SampleFormat{Encoding: pcm.Signed, Bits: bits}, true
+	case Raw:
+		return pcm.SampleFormat{Encoding: pcm.Unsigned, Bits: bits, BigEndian: true}, true
+	case In24:
+		return pcm.SampleFormat{Encoding: pcm.Signed, Bits: 24, BigEndian: true}, true
+	case In32:
+		return pcm.SampleFormat{Encoding: pcm.Signed, Bits: 32, BigEndian: true}, true
+	case Float32, "FL32":
+		return pcm.SampleFormat{Encoding: pcm.Float, Bits: 32, BigEndian: true}, true
+	case Float64, "FL64":
+		return pcm.SampleFormat{Encoding: pcm.Float, Bits: 64, BigEndian: true}, true
+	case MuLaw, "ULAW":
+		return pcm.SampleFormat{Encoding: pcm.MuLaw, Bits: 8}, true
+	case ALaw, "ALAW":
+		return pcm.SampleFormat{Encoding: pcm.ALaw, Bits: 8}, true
+	}
+	return pcm.SampleFormat{}, false
+}
+
+func (f Format) frameSize() int {
+	s, _ := f.sampleFormat()
+	return s.Size() * f.ChannelCount
+}
+
+// Chunk is an IFF chunk that this package does not parse, e.g. a NAME, MARK
+// or INST chunk.
+type Chunk struct {
+	// ID is the four character code of the chunk.
+	ID   string
+	Data []byte
+}
+
+// File is a complete AIFF or AIFF-C file in memory.
+type File struct {
+	Format
+	// Chunks holds all chunks other than FVER, COMM and SSND.
+	Chunks []Chunk
+	// Samples contains one slice per channel, all of the same length.
+	Samples [][]float64
+}
+
+// Float32 returns the samples converted to float32.
+func (f *File) Float32() [][]float32 {
+	return pcm.Float32(f.Samples)
+}
+
+// Decode reads a whole AIFF or AIFF-C file from r.
+func Decode(r io.Reader) (*File, error) {
+	ar, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	// Leave room for one more frame so the final Read can report io.EOF.
+	samples := make([][]float64, ar.Format.ChannelCount)
+	for i := range samples {
+		samples[i] = make([]float64, ar.FrameCount()+1)
+	}
+	n := 0
+	for {
+		var m int
+		m, err = ar.Read(offset(samples, n))
+		n += m
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range samples {
+		samples[i] = samples[i][:n]
+	}
+	return &File{
+		Format:  ar.Format,
+		Chunks:  ar.Chunks,
+		Samples: samples,
+	}, nil
+}
+
+func offset(channels [][]float64, n int) [][]float64 {
+	o := make([][]float64, len(channels))
+	for i := range o {
+		o[i] = channels[i][n:]
+	}
+	return o
+}
+
+// Encode writes f as an AIFF or AIFF-C file to w. All channels in f.Samples
+// must have the same length and there must be f.ChannelCount of them.
+func Encode(w io.Writer, f *File) error {
+	if err := f.Format.validate(); err != nil {
+		return err
+	}
+	if len(f.Samples) != f.ChannelCount {
+		return errors.New("aiff: number of sample channels does not match format")
+	}
+	frames := len(f.Samples[0])
+	for _, c := range f.Samples {
+		if len(c) != frames {
+			return errors.New("aiff: channels have different lengths")
+		}
+	}
+	dataSize := int64(frames) * int64(f.frameSize())
+	if dataSize > 0xFFFFFFFF-1024 {
+		return errors.New("aiff: too much sample data for an AIFF file")
+	}
+
+	var chunks bytes.Buffer
+	formType := "AIFF"
+	if f.AIFC {
+		formType = "AIFC"
+		writeChunk(&chunks, "FVER", be32(aifcVersion))
+	}
+	writeChunk(&chunks, "COMM", encodeCommon(f.Format, frames))
+	for _, c := range f.Chunks {
+		if len(c.ID) != 4 {
+			return errors.New("aiff: chunk IDs must have 4 characters")
+		}
+		writeChunk(&chunks, c.ID, c.Data)
+	}
+	chunks.WriteString("SSND")
+	chunks.Write(be32(uint32(8 + dataSize)))
+	chunks.Write(be32(0)) // offset
+	chunks.Write(be32(0)) // block size
+
+	var header bytes.Buffer
+	header.WriteString("FORM")
+	header.Write(be32(uint32(int64(4+chunks.Len()) + dataSize + dataSize%2)))
+	header.WriteString(formType)
+	header.Write(chunks.Bytes())
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+
+	s, _ := f.sampleFormat()
+	if err := pcm.Encode(w, s, f.Samples); err != nil {
+		return err
+	}
+	if dataSize%2 == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+func encodeCommon(f Format, frames int) []byte {
+	var b bytes.Buffer
+	b.Write(be16(uint16(f.ChannelCount)))
+	b.Write(be32(uint32(frames)))
+	b.Write(be16(uint16(f.SampleSize)))
+	rate := encodeExtended(f.SampleRate)
+	b.Write(rate[:])
+	if f.AIFC {
+		b.WriteString(f.Compression)
+		// The name is a Pascal string, padded to an even length.
+		b.WriteByte(byte(len(f.CompressionName)))
+		b.WriteString(f.CompressionName)
+		if len(f.CompressionName)%2 == 0 {
+			b.WriteByte(0)
+		}
+	}
+	return b.Bytes()
+}
+
+func decodeCommon(data []byte, aifc bool) (f Format, frames int, err error) {
+	if len(data) < 18 {
+		return f, 0, errors.New("aiff: COMM chunk is too short")
+	}
+	f.ChannelCount = int(u16(data[0:]))
+	frames = int(u32(data[2:]))
+	f.SampleSize = int(u16(data[6:]))
+	f.SampleRate = decodeExtended(data[8:18])
+	f.Compression = None
+	f.AIFC = aifc
+	if aifc {
+		if len(data) < 22 {
+			return f, 0, errors.New("aiff: AIFF-C COMM chunk is too short")
+		}
+		f.Compression = string(data[18:22])
+		if len(data) > 22 {
+			n := int(data[22])
+			if 23+n > len(data) {
+				return f, 0, errors.New("aiff: compression name is truncated")
+			}
+			f.CompressionName = string(data[23 : 23+n])
+		}
+	}
+	return f, frames, f.validate()
+}
+
+func writeChunk(w *bytes.Buffer, id string, data []byte) {
+	w.WriteString(id)
+	w.Write(be32(uint32(len(data))))
+	w.Write(data)
+	if len(data)%2 == 1 {
+		w.WriteByte(0)
+	}
+}
+
+func be16(v uint16) []byte {
+	return []byte{byte(v >> 8), byte(v)}
+}
+
+func be32(v uint32) []byte {
+	return []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+func u16(b []byte) uint16 {
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+func u32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
@@ -0,0 +1,240 @@
+package aiff
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+// form builds a FORM file of the given type from the given chunks, each of
+// which already includes its header.
+func form(formType string, chunks ...[]byte) []byte {
+	var body []byte
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	b := []byte("FORM")
+	b = append(b, be32(uint32(4+len(body)))...)
+	b = append(b, formType...)
+	return append(b, body...)
+}
+
+func chunk(id string, data ...byte) []byte {
+	var b bytes.Buffer
+	writeChunk(&b, id, data)
+	return b.Bytes()
+}
+
+func join(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+var rate44100 = []byte{0x40, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0}
+
+func TestExtendedSampleRates(t *testing.T) {
+	check.Eq(t, decodeExtended(rate44100), 44100)
+	for _, rate := range []float64{1, 8000, 11025, 22050, 44100, 48000, 96000, 0.5, 44100.5, 1e6} {
+		b := encodeExtended(rate)
+		check.EqExact(t, decodeExtended(b[:]), rate)
+	}
+	b := encodeExtended(44100)
+	check.Eq(t, b[:], rate44100)
+	check.Eq(t, decodeExtended(make([]byte, 10)), 0)
+}
+
+func TestDecode16BitStereoAIFF(t *testing.T) {
+	f, err := Decode(bytes.NewReader(form("AIFF",
+		chunk("COMM", join(
+			[]byte{0, 2},       // channels
+			[]byte{0, 0, 0, 2}, // frames
+			[]byte{0, 16},      // sample size
+			rate44100,
+		)...),
+		chunk("SSND", join(
+			[]byte{0, 0, 0, 0, 0, 0, 0, 0},
+			[]byte{0x40, 0x00, 0xC0, 0x00},
+			[]byte{0x80, 0x00, 0x20, 0x00},
+		)...),
+	)))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Format, Format{
+		ChannelCount: 2,
+		SampleRate:   44100,
+		SampleSize:   16,
+		Compression:  None,
+	})
+	check.Eq(t, f.Samples, [][]float64{{0.5, -1}, {-0.5, 0.25}})
+	check.Eq(t, f.Float32(), [][]float32{{0.5, -1}, {-0.5, 0.25}})
+}
+
+func TestDecodeAIFCCompressionTypes(t *testing.T) {
+	comm := func(compression string, size byte) []byte {
+		return chunk("COMM", join(
+			[]byte{0, 1, 0, 0, 0, 2, 0, size},
+			rate44100,
+			[]byte(compression),
+			[]byte{0, 0},
+		)...)
+	}
+	ssnd := func(data ...byte) []byte {
+		return chunk("SSND", join(make([]byte, 8), data)...)
+	}
+	tests := []struct {
+		comm, ssnd []byte
+		samples    []float64
+	}{
+		{comm("sowt", 16), ssnd(0x00, 0x40, 0x00, 0xC0), []float64{0.5, -0.5}},
+		{comm("fl32", 32), ssnd(0x3F, 0xC0, 0, 0, 0xBE, 0x80, 0, 0), []float64{1.5, -0.25}},
+		{comm("FL32", 32), ssnd(0x3F, 0xC0, 0, 0, 0xBE, 0x80, 0, 0), []float64{1.5, -0.25}},
+		{comm("ulaw", 16), ssnd(0xFF, 0x80), []float64{0, 32124.0 / 32768}},
+		{comm("alaw", 16), ssnd(0xD5, 0x2A), []float64{8.0 / 32768, -32256.0 / 32768}},
+		{comm("in24", 24), ssnd(0xC0, 0, 0, 0x40, 0, 0), []float64{-0.5, 0.5}},
+	}
+	for _, test := range tests {
+		f, err := Decode(bytes.NewReader(form("AIFC",
+			chunk("FVER", be32(aifcVersion)...),
+			test.comm,
+			test.ssnd,
+		)))
+		check.Eq(t, err, nil, string(test.comm[26:30]))
+		check.Eq(t, f.AIFC, true)
+		check.Eq(t, f.Samples, [][]float64{test.samples}, string(test.comm[26:30]))
+	}
+}
+
+func TestSSNDOffsetIsSkipped(t *testing.T) {
+	f, err := Decode(bytes.NewReader(form("AIFF",
+		chunk("COMM", join([]byte{0, 1, 0, 0, 0, 1, 0, 8}, rate44100)...),
+		chunk("SSND", 0, 0, 0, 2, 0, 0, 0, 0, 99, 99, 0x40),
+	)))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Samples, [][]float64{{0.5}})
+}
+
+func TestFilesRoundTrip(t *testing.T) {
+	samples := [][]float64{{0, 0.5, -0.5, -1, 0.25}, {0.75, 0, 0.125, -0.125, 0}}
+	formats := []Format{
+		{SampleSize: 8, Compression: None},
+		{SampleSize: 16, Compression: None},
+		{SampleSize: 24, Compression: None},
+		{SampleSize: 32, Compression: None},
+		{SampleSize: 16, Compression: None, AIFC: true, CompressionName: "not compressed"},
+		{SampleSize: 16, Compression: Sowt, AIFC: true},
+		{SampleSize: 32, Compression: Float32, AIFC: true, CompressionName: "32-bit floating point"},
+		{SampleSize: 64, Compression: Float64, AIFC: true},
+		{SampleSize: 24, Compression: In24, AIFC: true},
+		{SampleSize: 8, Compression: Raw, AIFC: true},
+	}
+	for _, format := range formats {
+		format.ChannelCount = 2
+		format.SampleRate = 22050
+		in := &File{
+			Format:  format,
+			Chunks:  []Chunk{{ID: "NAME", Data: []byte("odd")}},
+			Samples: samples,
+		}
+		var b bytes.Buffer
+		check.Eq(t, Encode(&b, in), nil, format)
+		check.Eq(t, b.Len()%2, 0)
+		out, err := Decode(&b)
+		check.Eq(t, err, nil, format)
+		check.Eq(t, out, in, format)
+	}
+}
+
+func TestCompandedFilesRoundTripApproximately(t *testing.T) {
+	for _, compression := range []string{MuLaw, ALaw} {
+		in := &File{
+			Format: Format{
+				ChannelCount: 1,
+				SampleRate:   8000,
+				SampleSize:   16,
+				AIFC:         true,
+				Compression:  compression,
+			},
+			Samples: [][]float64{{0, 0.5, -0.5, 0.01}},
+		}
+		var b bytes.Buffer
+		check.Eq(t, Encode(&b, in), nil)
+		out, err := Decode(&b)
+		check.Eq(t, err, nil)
+		check.EqEps(t, out.Samples, in.Samples, 0.02)
+	}
+}
+
+func TestChunksAfterSoundDataAreReadAtTheEnd(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(form("AIFF",
+		chunk("COMM", join([]byte{0, 1, 0, 0, 0, 3, 0, 8}, rate44100)...),
+		chunk("SSND", 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3),
+		chunk("ANNO", 'h', 'i'),
+	)))
+	check.Eq(t, err, nil)
+	check.Eq(t, r.FrameCount(), 3)
+	check.Eq(t, len(r.Chunks), 0)
+	dst := [][]float32{make([]float32, 2)}
+	n, err := r.ReadFloat32(dst)
+	check.Eq(t, n, 2)
+	check.Eq(t, err, nil)
+	n, err = r.ReadFloat32(dst)
+	check.Eq(t, n, 1)
+	check.Eq(t, err, nil)
+	n, err = r.ReadFloat32(dst)
+	check.Eq(t, n, 0)
+	check.Eq(t, err, io.EOF)
+	check.Eq(t, r.Chunks, []Chunk{{ID: "ANNO", Data: []byte("hi")}})
+}
+
+func TestInvalidFilesAreReported(t *testing.T) {
+	comm := chunk("COMM", join([]byte{0, 1, 0, 0, 0, 1, 0, 16}, rate44100)...)
+	files := [][]byte{
+		nil,
+		form("WAVE"),
+		form("AIFF"),
+		form("AIFF", comm),
+		form("AIFF", chunk("SSND", make([]byte, 10)...), comm),
+		form("AIFF", chunk("COMM", 0, 1), chunk("SSND", make([]byte, 10)...)),
+		form("AIFF", chunk("COMM", join([]byte{0, 1, 0, 0, 0, 1, 0, 40}, rate44100)...), chunk("SSND", make([]byte, 13)...)),
+		form("AIFC", chunk("COMM", join([]byte{0, 1, 0, 0, 0, 1, 0, 16}, rate44100, []byte("zzzz"), []byte{0, 0})...), chunk("SSND", make([]byte, 10)...)),
+		form("AIFF", comm, chunk("SSND", 0, 0, 0, 9, 0, 0, 0, 0)),
+	}
+	for i, file := range files {
+		_, err := Decode(bytes.NewReader(file))
+		check.Neq(t, err, nil, i)
+	}
+}
+
+func TestTruncatedSampleDataIsAnError(t *testing.T) {
+	file := form("AIFF",
+		chunk("COMM", join([]byte{0, 1, 0, 0, 0, 3, 0, 16}, rate44100)...),
+		chunk("SSND", 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4, 5, 6),
+	)
+	for _, cut := range []int{1, 2, 4} {
+		_, err := Decode(bytes.NewReader(file[:len(file)-cut]))
+		check.Eq(t, err, io.ErrUnexpectedEOF, cut)
+	}
+}
+
+func TestMissingPadByteAfterSampleDataIsIgnored(t *testing.T) {
+	file := form("AIFF",
+		chunk("COMM", join([]byte{0, 1, 0, 0, 0, 1, 0, 8}, rate44100)...),
+		chunk("SSND", 0, 0, 0, 0, 0, 0, 0, 0, 0x40),
+	)
+	f, err := Decode(bytes.NewReader(file[:len(file)-1]))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Samples, [][]float64{{0.5}})
+}
+
+func TestPlainAIFFMustNotBeCompressed(t *testing.T) {
+	var b bytes.Buffer
+	err := Encode(&b, &File{
+		Format:  Format{ChannelCount: 1, SampleRate: 1, SampleSize: 16, Compression: Sowt},
+		Samples: [][]float64{{0}},
+	})
+	check.Neq(t, err, nil)
+}
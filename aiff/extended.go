@@ -0,0 +1,52 @@
+package aiff
+
+import "math"
+
+// decodeExtended converts an 80 bit IEEE 754 extended precision number, as
+// used for the sample rate in the COMM chunk, to a float64.
+func decodeExtended(b []byte) float64 {
+	sign := 1.0
+	if b[0]&0x80 != 0 {
+		sign = -1
+	}
+	exponent := int(b[0]&0x7F)<<8 | int(b[1])
+	var mantissa uint64
+	for i := 2; i < 10; i++ {
+		mantissa = mantissa<<8 | uint64(b[i])
+	}
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+	if exponent == 0x7FFF {
+		if mantissa<<1 == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	// The mantissa has an explicit integer bit at the top.
+	return sign * math.Ldexp(float64(mantissa), exponent-16383-63)
+}
+
+// encodeExtended converts x to an 80 bit IEEE 754 extended precision number.
+func encodeExtended(x float64) [10]byte {
+	var b [10]byte
+	if x == 0 {
+		return b
+	}
+	if x < 0 {
+		b[0] = 0x80
+		x = -x
+	}
+	frac, exp := math.Frexp(x)
+	// x = frac * 2^exp with frac in [0.5,1), shift the mantissa so that the
+	// integer bit is at the top.
+	mantissa := uint64(math.Ldexp(frac, 64))
+	exponent := exp - 1 + 16383
+	b[0] |= byte(exponent >> 8 & 0x7F)
+	b[1] = byte(exponent)
+	for i := 9; i >= 2; i-- {
+		b[i] = byte(mantissa)
+		mantissa >>= 8
+	}
+	return b
+}
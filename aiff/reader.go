@@ -0,0 +1,222 @@
+package aiff
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/gonutz/dsp/pcm"
+)
+
+// Reader reads the samples of an AIFF or AIFF-C file in blocks, without
+// loading the whole file into memory.
+type Reader struct {
+	// Format is the format of the samples.
+	Format Format
+	// Chunks holds all unparsed chunks read so far. Chunks that follow the
+	// sample data are only available after Read returned io.EOF.
+	Chunks []Chunk
+
+	r          io.Reader
+	formLeft   int64
+	frameCount int
+	framesLeft int
+	// ssndTail is the number of bytes in the SSND chunk after the sample
+	// frames, not including the pad byte.
+	ssndTail int64
+	ssndPad  int64
+	samples  *pcm.Reader
+	err      error
+}
+
+// NewReader reads the AIFF header from r, up to the start of the sample data.
+// The COMM chunk must come before the SSND chunk.
+func NewReader(r io.Reader) (*Reader, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, errors.New("aiff: file is too short")
+	}
+	formType := string(header[8:12])
+	if string(header[0:4]) != "FORM" || formType != "AIFF" && formType != "AIFC" {
+		return nil, errors.New("aiff: not an AIFF or AIFF-C file")
+	}
+
+	ar := &Reader{r: r, formLeft: int64(u32(header[4:])) - 4}
+	haveFormat := false
+	for {
+		id, size, err := ar.readChunkHeader()
+		if err == io.EOF {
+			return nil, errors.New("aiff: missing SSND chunk")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if id == "SSND" {
+			if !haveFormat {
+				return nil, errors.New("aiff: SSND chunk before COMM chunk")
+			}
+			if size < 8 || size > ar.formLeft {
+				return nil, errors.New("aiff: invalid SSND chunk size")
+			}
+			var ssnd [8]byte
+			if _, err := io.ReadFull(r, ssnd[:]); err != nil {
+				return nil, errors.New("aiff: truncated SSND chunk")
+			}
+			ar.formLeft -= 8
+			offset := int64(u32(ssnd[:]))
+			if offset > size-8 {
+				return nil, errors.New("aiff: invalid SSND data offset")
+			}
+			if _, err := ar.skip(offset); err != nil {
+				return nil, errors.New("aiff: truncated SSND chunk")
+			}
+			dataSize := size - 8 - offset
+			frameSize := int64(ar.Format.frameSize())
+			if int64(ar.frameCount)*frameSize > dataSize {
+				ar.frameCount = int(dataSize / frameSize)
+			}
+			dataSize = int64(ar.frameCount) * frameSize
+			ar.framesLeft = ar.frameCount
+			ar.ssndTail = size - 8 - offset - dataSize
+			ar.ssndPad = size % 2
+			s, _ := ar.Format.sampleFormat()
+			ar.samples, _ = pcm.NewReader(
+				io.LimitReader(r, dataSize), s, ar.Format.ChannelCount,
+			)
+			ar.formLeft -= dataSize
+			return ar, nil
+		}
+
+		data, err := ar.readChunkData(size)
+		if err != nil {
+			return nil, err
+		}
+		switch id {
+		case "COMM":
+			ar.Format, ar.frameCount, err = decodeCommon(data, formType == "AIFC")
+			if err != nil {
+				return nil, err
+			}
+			haveFormat = true
+		case "FVER":
+			// The version is re-created when writing.
+		default:
+			ar.Chunks = append(ar.Chunks, Chunk{ID: id, Data: data})
+		}
+	}
+}
+
+// FrameCount returns the total number of frames in the file. A frame contains
+// one sample for each channel.
+func (r *Reader) FrameCount() int {
+	return r.frameCount
+}
+
+// Read reads the next frames into dst which must contain one slice per
+// channel. It reads at most as many frames as the shortest slice in dst can
+// hold and returns the number of frames read. At the end of the sample data it
+// returns 0 and io.EOF. If the file ends before all frames in the header were
+// read, it returns io.ErrUnexpectedEOF.
+func (r *Reader) Read(dst [][]float64) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.samples.Read(dst)
+	return r.afterRead(n, err)
+}
+
+// ReadFloat32 is like Read but converts the samples to float32.
+func (r *Reader) ReadFloat32(dst [][]float32) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.samples.ReadFloat32(dst)
+	return r.afterRead(n, err)
+}
+
+// afterRead counts the n frames that were read and reads the chunks after
+// the sample data once it ends.
+func (r *Reader) afterRead(n int, err error) (int, error) {
+	r.framesLeft -= n
+	if err == io.EOF {
+		if r.framesLeft > 0 {
+			err = io.ErrUnexpectedEOF
+		} else {
+			err = r.readTrailingChunks()
+		}
+		if err == nil {
+			err = io.EOF
+		}
+	}
+	r.err = err
+	return n, err
+}
+
+func (r *Reader) readTrailingChunks() error {
+	if _, err := r.skip(r.ssndTail); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	if _, err := r.skip(r.ssndPad); err != nil {
+		// A missing pad byte at the end of the file is not a problem.
+		return nil
+	}
+	for {
+		id, size, err := r.readChunkHeader()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data, err := r.readChunkData(size)
+		if err != nil {
+			return err
+		}
+		if id != "COMM" && id != "FVER" && id != "SSND" {
+			r.Chunks = append(r.Chunks, Chunk{ID: id, Data: data})
+		}
+	}
+}
+
+// readChunkHeader returns io.EOF if the FORM chunk or the file ends before the
+// next chunk.
+func (r *Reader) readChunkHeader() (id string, size int64, err error) {
+	if r.formLeft < 8 {
+		return "", 0, io.EOF
+	}
+	var header [8]byte
+	n, err := io.ReadFull(r.r, header[:])
+	if n == 0 && err == io.EOF {
+		return "", 0, io.EOF
+	}
+	if err != nil {
+		return "", 0, errors.New("aiff: truncated chunk header")
+	}
+	r.formLeft -= 8
+	return string(header[:4]), int64(u32(header[4:])), nil
+}
+
+// readChunkData reads the chunk contents and skips the pad byte.
+func (r *Reader) readChunkData(size int64) ([]byte, error) {
+	if size > r.formLeft {
+		return nil, errors.New("aiff: chunk is larger than the file")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, errors.New("aiff: truncated chunk")
+	}
+	r.formLeft -= size
+	if size%2 == 1 && r.formLeft > 0 {
+		if _, err := r.skip(1); err != nil {
+			return nil, errors.New("aiff: truncated chunk")
+		}
+	}
+	return data, nil
+}
+
+func (r *Reader) skip(n int64) (int64, error) {
+	m, err := io.CopyN(ioutil.Discard, r.r, n)
+	r.formLeft -= m
+	return m, err
+}
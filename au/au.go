@@ -0,0 +1,264 @@
+// Package au reads and writes Sun/NeXT .au audio files. Samples are
+// deinterleaved into one slice per channel and normalized to the range [-1,1].
+//
+// Supported are linear PCM samples of 8 to 32 bits, 32 and 64 bit floats and
+// µ-law and A-law companded samples.
+package au
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/gonutz/dsp/pcm"
+)
+
+// Encoding is the type of samples in a file.
+type Encoding uint32
+
+// These are the supported sample encodings.
+const (
+	MuLaw    Encoding = 1
+	Linear8  Encoding = 2
+	Linear16 Encoding = 3
+	Linear24 Encoding = 4
+	Linear32 Encoding = 5
+	Float    Encoding = 6
+	Double   Encoding = 7
+	ALaw     Encoding = 27
+)
+
+// unknownSize is stored as the data size if the size is not known in advance.
+const unknownSize = 0xFFFFFFFF
+
+// headerSize is the size of the fixed part of the header, without annotation.
+const headerSize = 24
+
+// Format describes the layout of the sample data.
+type Format struct {
+	Encoding     Encoding
+	SampleRate   int
+	ChannelCount int
+}
+
+func (f Format) validate() error {
+	if _, ok := f.sampleFormat(); !ok {
+		return errors.New("au: unsupported encoding")
+	}
+	if f.ChannelCount <= 0 || f.ChannelCount > 0xFFFF {
+		return errors.New("au: invalid channel count")
+	}
+	if f.SampleRate <= 0 || int64(f.SampleRate) > 0xFFFFFFFF {
+		return errors.New("au: invalid sample rate")
+	}
+	return nil
+}
+
+func (f Format) sampleFormat() (pcm.SampleFormat, bool) {
+	switch f.Encoding {
+	case MuLaw:
+		return pcm.SampleFormat{Encoding: pcm.MuLaw, Bits: 8}, true
+	case ALaw:
+		return pcm.SampleFormat{Encoding: pcm.ALaw, Bits: 8}, true
+	case Linear8:
+		return pcm.SampleFormat{Encoding: pcm.Signed, Bits: 8, BigEndian: true}, true
+	case Linear16:
+		return pcm.SampleFormat{Encoding: pcm.Signed, Bits: 16, BigEndian: true}, true
+	case Linear24:
+		return pcm.SampleFormat{Encoding: pcm.Signed, Bits: 24, BigEndian: true}, true
+	case Linear32:
+		return pcm.SampleFormat{Encoding: pcm.Signed, Bits: 32, BigEndian: true}, true
+	case Float:
+		return pcm.SampleFormat{Encoding: pcm.Float, Bits: 32, BigEndian: true}, true
+	case Double:
+		return pcm.SampleFormat{Encoding: pcm.Float, Bits: 64, BigEndian: true}, true
+	}
+	return pcm.SampleFormat{}, false
+}
+
+// File is a complete .au file in memory.
+type File struct {
+	Format
+	// Annotation is the free-form data between the header and the samples.
+	// It is usually a zero-terminated text.
+	Annotation []byte
+	// Samples contains one slice per channel, all of the same length.
+	Samples [][]float64
+}
+
+// Float32 returns the samples converted to float32.
+func (f *File) Float32() [][]float32 {
+	return pcm.Float32(f.Samples)
+}
+
+// Reader reads the samples of an .au file in blocks, without loading the whole
+// file into memory.
+type Reader struct {
+	// Format is the format of the samples.
+	Format Format
+	// Annotation is the free-form data between the header and the samples.
+	Annotation []byte
+
+	frameCount int
+	framesLeft int
+	data       io.Reader
+	samples    *pcm.Reader
+}
+
+// NewReader reads the .au header from r, up to the start of the sample data.
+func NewReader(r io.Reader) (*Reader, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, errors.New("au: file is too short")
+	}
+	if string(header[:4]) != ".snd" {
+		return nil, errors.New("au: not an .au file")
+	}
+	dataOffset := u32(header[4:])
+	dataSize := u32(header[8:])
+	f := Format{
+		Encoding:     Encoding(u32(header[12:])),
+		SampleRate:   int(u32(header[16:])),
+		ChannelCount: int(u32(header[20:])),
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	if dataOffset < headerSize {
+		return nil, errors.New("au: data offset is inside the header")
+	}
+
+	annotationSize := int64(dataOffset - headerSize)
+	annotation, err := ioutil.ReadAll(io.LimitReader(r, annotationSize))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(annotation)) != annotationSize {
+		return nil, errors.New("au: truncated annotation")
+	}
+
+	s, _ := f.sampleFormat()
+	frameSize := s.Size() * f.ChannelCount
+	data := r
+	frameCount := -1
+	if dataSize != unknownSize {
+		frameCount = int(int64(dataSize) / int64(frameSize))
+		data = io.LimitReader(r, int64(frameCount)*int64(frameSize))
+	}
+	samples, _ := pcm.NewReader(data, s, f.ChannelCount)
+	return &Reader{
+		Format:     f,
+		Annotation: annotation,
+		frameCount: frameCount,
+		framesLeft: frameCount,
+		data:       data,
+		samples:    samples,
+	}, nil
+}
+
+// FrameCount returns the total number of frames in the file. A frame contains
+// one sample for each channel. If the header does not specify the data size,
+// -1 is returned and the samples extend to the end of the file.
+func (r *Reader) FrameCount() int {
+	return r.frameCount
+}
+
+// Read reads the next frames into dst which must contain one slice per
+// channel. It reads at most as many frames as the shortest slice in dst can
+// hold and returns the number of frames read. At the end of the sample data it
+// returns 0 and io.EOF. If the file ends before all frames in the header were
+// read, it returns io.ErrUnexpectedEOF.
+func (r *Reader) Read(dst [][]float64) (int, error) {
+	n, err := r.samples.Read(dst)
+	return r.afterRead(n, err)
+}
+
+// ReadFloat32 is like Read but converts the samples to float32.
+func (r *Reader) ReadFloat32(dst [][]float32) (int, error) {
+	n, err := r.samples.ReadFloat32(dst)
+	return r.afterRead(n, err)
+}
+
+// afterRead counts the n frames that were read and reports a file that ends
+// before its data size.
+func (r *Reader) afterRead(n int, err error) (int, error) {
+	if r.frameCount < 0 {
+		return n, err
+	}
+	r.framesLeft -= n
+	if err == io.EOF && r.framesLeft > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Decode reads a whole .au file from r.
+func Decode(r io.Reader) (*File, error) {
+	ar, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	s, _ := ar.Format.sampleFormat()
+	samples, err := pcm.Decode(ar.data, s, ar.Format.ChannelCount)
+	if err != nil {
+		return nil, err
+	}
+	if ar.frameCount >= 0 && len(samples[0]) < ar.frameCount {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return &File{
+		Format:     ar.Format,
+		Annotation: ar.Annotation,
+		Samples:    samples,
+	}, nil
+}
+
+// Encode writes f as an .au file to w. All channels in f.Samples must have the
+// same length and there must be f.ChannelCount of them. The annotation is
+// padded with zeros to a multiple of 8 bytes, at least 4 bytes are written.
+func Encode(w io.Writer, f *File) error {
+	if err := f.Format.validate(); err != nil {
+		return err
+	}
+	if len(f.Samples) != f.ChannelCount {
+		return errors.New("au: number of sample channels does not match format")
+	}
+	frames := len(f.Samples[0])
+	for _, c := range f.Samples {
+		if len(c) != frames {
+			return errors.New("au: channels have different lengths")
+		}
+	}
+	s, _ := f.sampleFormat()
+	dataSize := int64(frames) * int64(s.Size()*f.ChannelCount)
+	if dataSize >= unknownSize {
+		return errors.New("au: too much sample data for an .au file")
+	}
+
+	annotationSize := (len(f.Annotation) + 7) / 8 * 8
+	if annotationSize == 0 {
+		annotationSize = 4
+	}
+	var header bytes.Buffer
+	header.WriteString(".snd")
+	header.Write(be32(uint32(headerSize + annotationSize)))
+	header.Write(be32(uint32(dataSize)))
+	header.Write(be32(uint32(f.Encoding)))
+	header.Write(be32(uint32(f.SampleRate)))
+	header.Write(be32(uint32(f.ChannelCount)))
+	header.Write(f.Annotation)
+	header.Write(make([]byte, annotationSize-len(f.Annotation)))
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+	return pcm.Encode(w, s, f.Samples)
+}
+
+func be32(v uint32) []byte {
+	return []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+func u32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
@@ -0,0 +1,156 @@
+package au
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func header(offset, size, encoding, rate, channels uint32) []byte {
+	var b []byte
+	b = append(b, ".snd"...)
+	for _, v := range []uint32{offset, size, encoding, rate, channels} {
+		b = append(b, be32(v)...)
+	}
+	return b
+}
+
+func join(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func TestDecode16BitStereo(t *testing.T) {
+	f, err := Decode(bytes.NewReader(join(
+		header(28, 8, 3, 8000, 2),
+		[]byte("hi\x00\x00"),
+		[]byte{0x40, 0x00, 0xC0, 0x00, 0x80, 0x00, 0x20, 0x00},
+		[]byte{0x12, 0x34}, // junk after the data
+	)))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Format, Format{Encoding: Linear16, SampleRate: 8000, ChannelCount: 2})
+	check.Eq(t, f.Annotation, []byte("hi\x00\x00"))
+	check.Eq(t, f.Samples, [][]float64{{0.5, -1}, {-0.5, 0.25}})
+	check.Eq(t, f.Float32(), [][]float32{{0.5, -1}, {-0.5, 0.25}})
+}
+
+func TestDecodeCompandedData(t *testing.T) {
+	f, err := Decode(bytes.NewReader(join(
+		header(24, 2, 1, 8000, 1),
+		[]byte{0xFF, 0x80},
+	)))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Samples, [][]float64{{0, 32124.0 / 32768}})
+
+	f, err = Decode(bytes.NewReader(join(
+		header(24, 2, 27, 8000, 1),
+		[]byte{0xD5, 0x2A},
+	)))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Samples, [][]float64{{8.0 / 32768, -32256.0 / 32768}})
+}
+
+func TestUnknownDataSizeReadsToEndOfFile(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(join(
+		header(24, unknownSize, 2, 8000, 1),
+		[]byte{0x40, 0xC0, 0x00},
+	)))
+	check.Eq(t, err, nil)
+	check.Eq(t, r.FrameCount(), -1)
+	dst := [][]float32{make([]float32, 10)}
+	n, err := r.ReadFloat32(dst)
+	check.Eq(t, n, 3)
+	check.Eq(t, err, nil)
+	check.Eq(t, dst[0][:n], []float32{0.5, -0.5, 0})
+	n, err = r.ReadFloat32(dst)
+	check.Eq(t, n, 0)
+	check.Eq(t, err, io.EOF)
+}
+
+func TestTruncatedSampleDataIsAnError(t *testing.T) {
+	file := join(
+		header(24, 6, 3, 8000, 1),
+		[]byte{0x40, 0x00, 0xC0, 0x00, 0x20, 0x00},
+	)
+	for _, cut := range []int{1, 2, 4} {
+		_, err := Decode(bytes.NewReader(file[:len(file)-cut]))
+		check.Eq(t, err, io.ErrUnexpectedEOF, cut)
+
+		r, err := NewReader(bytes.NewReader(file[:len(file)-cut]))
+		check.Eq(t, err, nil)
+		dst := [][]float64{make([]float64, 10)}
+		for err == nil {
+			_, err = r.Read(dst)
+		}
+		check.Eq(t, err, io.ErrUnexpectedEOF, cut)
+	}
+}
+
+func TestFilesRoundTrip(t *testing.T) {
+	samples := [][]float64{{0, 0.5, -0.5, -1, 0.25}, {0.75, 0, 0.125, -0.125, 0}}
+	for _, encoding := range []Encoding{Linear8, Linear16, Linear24, Linear32, Float, Double} {
+		in := &File{
+			Format:     Format{Encoding: encoding, SampleRate: 44100, ChannelCount: 2},
+			Annotation: []byte("annotation\x00\x00\x00\x00\x00\x00"),
+			Samples:    samples,
+		}
+		var b bytes.Buffer
+		check.Eq(t, Encode(&b, in), nil, encoding)
+		out, err := Decode(&b)
+		check.Eq(t, err, nil, encoding)
+		check.Eq(t, out, in, encoding)
+	}
+
+	for _, encoding := range []Encoding{MuLaw, ALaw} {
+		in := &File{
+			Format:  Format{Encoding: encoding, SampleRate: 8000, ChannelCount: 2},
+			Samples: samples,
+		}
+		var b bytes.Buffer
+		check.Eq(t, Encode(&b, in), nil, encoding)
+		out, err := Decode(&b)
+		check.Eq(t, err, nil, encoding)
+		check.EqEps(t, out.Samples, in.Samples, 0.02, encoding)
+	}
+}
+
+func TestEncodePadsAnnotation(t *testing.T) {
+	var b bytes.Buffer
+	err := Encode(&b, &File{
+		Format:  Format{Encoding: Linear8, SampleRate: 8000, ChannelCount: 1},
+		Samples: [][]float64{{0.5}},
+	})
+	check.Eq(t, err, nil)
+	check.Eq(t, b.Bytes(), join(header(28, 1, 2, 8000, 1), []byte{0, 0, 0, 0, 0x40}))
+
+	b.Reset()
+	err = Encode(&b, &File{
+		Format:     Format{Encoding: Linear8, SampleRate: 8000, ChannelCount: 1},
+		Annotation: []byte("abc"),
+		Samples:    [][]float64{{0.5}},
+	})
+	check.Eq(t, err, nil)
+	check.Eq(t, b.Bytes(), join(header(32, 1, 2, 8000, 1), []byte("abc\x00\x00\x00\x00\x00"), []byte{0x40}))
+}
+
+func TestInvalidFilesAreReported(t *testing.T) {
+	files := [][]byte{
+		nil,
+		join([]byte("RIFF"), make([]byte, 20)),
+		header(24, 0, 99, 8000, 1),
+		header(24, 0, 3, 0, 1),
+		header(24, 0, 3, 8000, 0),
+		header(16, 0, 3, 8000, 1),
+		header(40, 0, 3, 8000, 1),
+		join(header(24, 4, 3, 8000, 1), []byte{1, 2, 3}),
+	}
+	for i, file := range files {
+		_, err := Decode(bytes.NewReader(file))
+		check.Neq(t, err, nil, i)
+	}
+}
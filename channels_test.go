@@ -0,0 +1,64 @@
+package dsp
+
+import (
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestInterleaveAndDeinterleaveAreInverse(t *testing.T) {
+	c := Deinterleave([]FLOAT{1, 2, 3, 4, 5, 6, 7}, 3)
+	check.Eq(t, c, Channels{{1, 4}, {2, 5}, {3, 6}})
+	check.Eq(t, Interleave(c), []FLOAT{1, 2, 3, 4, 5, 6})
+	check.Eq(t, Interleave(Channels{{1, 2, 3}, {4, 5}}), []FLOAT{1, 4, 2, 5})
+	check.Eq(t, len(Interleave(nil)), 0)
+}
+
+func TestDeinterleaveWithoutChannelsIsEmpty(t *testing.T) {
+	check.Eq(t, len(Deinterleave([]FLOAT{1, 2, 3}, 0)), 0)
+	check.Eq(t, len(Deinterleave([]FLOAT{1, 2, 3}, -2)), 0)
+	check.Eq(t, len(NewChannels(-1, 5)), 0)
+	check.Eq(t, NewChannels(1, -5), Channels{{}})
+}
+
+func TestChannelsLenIsShortestChannel(t *testing.T) {
+	check.Eq(t, Channels{}.Len(), 0)
+	check.Eq(t, Channels{{1, 2, 3}, {1, 2}}.Len(), 2)
+	check.Eq(t, NewChannels(2, 5), Channels{{0, 0, 0, 0, 0}, {0, 0, 0, 0, 0}})
+}
+
+func TestMapAppliesFunctionToEachChannel(t *testing.T) {
+	c := Channels{{1, 2, 3}, {4, 5, 6}}
+	check.Eq(t, c.Map(Negative), Channels{{-1, -2, -3}, {-4, -5, -6}})
+	check.Eq(t, c.Map(func(a []FLOAT) []FLOAT { return AverageFilter(a, 2) }),
+		Channels{{1.5, 2.5}, {4.5, 5.5}})
+
+	copied := c.Copy()
+	c[0][0] = 9
+	check.Eq(t, copied[0][0], 1)
+}
+
+func TestSelectReordersChannels(t *testing.T) {
+	c := Channels{{1}, {2}, {3}}
+	check.Eq(t, c.Select(2, 0), Channels{{3}, {1}})
+	check.Eq(t, c.Select(1, 1), Channels{{2}, {2}})
+	check.Eq(t, len(c.Select()), 0)
+	check.Eq(t, c.Select(-1, 1, 3), Channels{{2}})
+}
+
+func TestMixAppliesMatrix(t *testing.T) {
+	c := Channels{{1, 2}, {10, 20}, {100, 200}}
+	check.Eq(t, c.Mix([][]FLOAT{{1, 1, 0}, {0, 0, 0.5}}), Channels{{11, 22}, {50, 100}})
+	check.Eq(t, c.Mix([][]FLOAT{{1, 1, 1, 1000}}), Channels{{111, 222}})
+	check.Eq(t, Downmix(Channels{{1, 2}, {3, 6}}), []FLOAT{2, 4})
+	check.Eq(t, Upmix([]FLOAT{1, 2}, 3), Channels{{1, 2}, {1, 2}, {1, 2}})
+}
+
+func TestMidSideEncodingRoundTrips(t *testing.T) {
+	mid, side := MidSideEncode([]FLOAT{1, 0, 3}, []FLOAT{1, 2, -1})
+	check.Eq(t, mid, []FLOAT{1, 1, 1})
+	check.Eq(t, side, []FLOAT{0, -1, 2})
+	left, right := MidSideDecode(mid, side)
+	check.Eq(t, left, []FLOAT{1, 0, 3})
+	check.Eq(t, right, []FLOAT{1, 2, -1})
+}
@@ -0,0 +1,75 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestComplexArithmeticUsesShortestLength(t *testing.T) {
+	a := []COMPLEX{1 + 2i, 3 - 1i, 5}
+	b := []COMPLEX{1i, 2}
+	check.Eq(t, AddComplex(a, b), []COMPLEX{1 + 3i, 5 - 1i})
+	check.Eq(t, SubComplex(a, b), []COMPLEX{1 + 1i, 1 - 1i})
+	check.Eq(t, MulComplex(a, b), []COMPLEX{-2 + 1i, 6 - 2i})
+	check.Eq(t, MulConj(a, b), []COMPLEX{2 - 1i, 6 - 2i})
+	check.Eq(t, DotComplex(a, b), COMPLEX(8-3i))
+	check.Eq(t, len(AddComplex()), 0)
+	check.Eq(t, len(MulComplex()), 0)
+}
+
+func TestMulAccumulateAddsProducts(t *testing.T) {
+	acc := []COMPLEX{1, 1i}
+	MulAccumulate(acc, []COMPLEX{1i, 2}, []COMPLEX{1i, 1 + 1i})
+	check.Eq(t, acc, []COMPLEX{0, 2 + 3i})
+}
+
+func TestComplexCopyScaleReverse(t *testing.T) {
+	a := []COMPLEX{1, 2i, 3}
+	c := CopyComplex(a)
+	c[0] = 5
+	check.Eq(t, a[0], COMPLEX(1))
+	check.Eq(t, ScaleComplex(a, 1i), []COMPLEX{1i, -2, 3i})
+	check.Eq(t, ReverseComplex(a), []COMPLEX{3, 2i, 1})
+	check.Eq(t, Conj(a), []COMPLEX{1, -2i, 3})
+}
+
+func TestComplexAverageAndDerivative(t *testing.T) {
+	a := []COMPLEX{1, 1i, 3, 2 - 1i}
+	check.Eq(t, AverageComplex(a), COMPLEX(1.5))
+	check.Eq(t, AverageComplex(nil), COMPLEX(0))
+	check.Eq(t, AverageFilterComplex(a, 2), []COMPLEX{0.5 + 0.5i, 1.5 + 0.5i, 2.5 - 0.5i})
+	check.Eq(t, AverageFilterComplex(a, 10), []COMPLEX{1.5})
+	check.Eq(t, AverageFilterComplex(a, 1), a)
+	check.Eq(t, DerivativeComplex(a), []COMPLEX{-1 + 1i, 3 - 1i, -1 - 1i})
+	check.Eq(t, DerivativeComplex([]COMPLEX{1}), []COMPLEX{0})
+}
+
+func TestComplexAverageFilterMatchesRealParts(t *testing.T) {
+	re := randomFloats(10000, 1)
+	im := randomFloats(10000, 2)
+	filtered := AverageFilterComplex(Complex(re, im), 7)
+	check.Eq(t, Real(filtered), AverageFilter(re, 7))
+	check.Eq(t, Imag(filtered), AverageFilter(im, 7))
+}
+
+func TestComplexConversions(t *testing.T) {
+	c := Complex([]FLOAT{3, 0, -1}, []FLOAT{4, 2})
+	check.Eq(t, c, []COMPLEX{3 + 4i, 2i})
+	re, im := RealImag(c)
+	check.Eq(t, re, []FLOAT{3, 0})
+	check.Eq(t, im, []FLOAT{4, 2})
+
+	magnitude, phase := ToPolar([]COMPLEX{3 + 4i, -2, -1i})
+	check.Eq(t, magnitude, []FLOAT{5, 2, 1})
+	check.EqEps(t, phase[0], math.Atan2(4, 3), 1e-6)
+	check.EqEps(t, phase[1], math.Pi, 1e-6)
+	check.EqEps(t, phase[2], -math.Pi/2, 1e-6)
+
+	p := FromPolar(magnitude, phase)
+	check.EqEps(t, real(p[0]), 3, 1e-5)
+	check.EqEps(t, imag(p[0]), 4, 1e-5)
+	check.EqEps(t, real(p[1]), -2, 1e-5)
+	check.EqEps(t, imag(p[2]), -1, 1e-5)
+}
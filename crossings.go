@@ -0,0 +1,150 @@
+package dsp
+
+// CrossingDirection selects which level crossings are detected.
+type CrossingDirection int
+
+const (
+	// AnyCrossing detects both rising and falling crossings.
+	AnyCrossing CrossingDirection = iota
+	// RisingCrossing detects crossings from below to above the level.
+	RisingCrossing
+	// FallingCrossing detects crossings from above to below the level.
+	FallingCrossing
+)
+
+// Crossing is the place where a signal crosses a level.
+type Crossing struct {
+	// Index is the last sample before the crossing.
+	Index int
+	// Offset is the linearly interpolated position of the crossing between
+	// sample Index and Index+1, from 0 to 1.
+	Offset FLOAT
+	// Rising is true if the signal rises through the level and false if it
+	// falls.
+	Rising bool
+}
+
+// Position returns the fractional sample position of the crossing, Index +
+// Offset.
+func (c Crossing) Position() FLOAT {
+	return FLOAT(float64(c.Index) + float64(c.Offset))
+}
+
+// LevelCrossings returns all places where a crosses the given level in the
+// given direction. Values equal to level count as above it, so a signal that
+// rises to exactly level and falls again crosses it twice.
+func LevelCrossings(a []FLOAT, level FLOAT, direction CrossingDirection) []Crossing {
+	var crossings []Crossing
+	for i := 0; i+1 < len(a); i++ {
+		below, nextBelow := a[i] < level, a[i+1] < level
+		if below == nextBelow {
+			continue
+		}
+		rising := below
+		if rising && direction == FallingCrossing || !rising && direction == RisingCrossing {
+			continue
+		}
+		offset := (float64(level) - float64(a[i])) / (float64(a[i+1]) - float64(a[i]))
+		crossings = append(crossings, Crossing{
+			Index:  i,
+			Offset: FLOAT(offset),
+			Rising: rising,
+		})
+	}
+	return crossings
+}
+
+// ZeroCrossings returns all places where a crosses 0 in the given direction,
+// see LevelCrossings.
+func ZeroCrossings(a []FLOAT, direction CrossingDirection) []Crossing {
+	return LevelCrossings(a, 0, direction)
+}
+
+// ZeroCrossingRate splits a into frames of frameLength samples that start
+// every hop samples and returns, for each frame, the fraction of neighboring
+// samples in the frame that lie on different sides of 0. Only whole frames
+// are used. frameLength is at least 2 and hop at least 1.
+func ZeroCrossingRate(a []FLOAT, frameLength, hop int) []FLOAT {
+	if frameLength < 2 {
+		frameLength = 2
+	}
+	if hop < 1 {
+		hop = 1
+	}
+	var rates []FLOAT
+	for start := 0; start+frameLength <= len(a); start += hop {
+		count := 0
+		for i := start; i+1 < start+frameLength; i++ {
+			if (a[i] < 0) != (a[i+1] < 0) {
+				count++
+			}
+		}
+		rates = append(rates, FLOAT(count)/FLOAT(frameLength-1))
+	}
+	return rates
+}
+
+// Interval is a range of samples from Start up to, but not including, End.
+type Interval struct {
+	Start, End int
+}
+
+// Len returns the number of samples in the interval.
+func (i Interval) Len() int {
+	return i.End - i.Start
+}
+
+// SchmittTrigger detects when a signal is on, with hysteresis. It turns on
+// when the signal rises to High or above and turns off again when it falls
+// below Low. Noise around a single threshold thus does not make it switch on
+// and off quickly. If Low is greater than High, it is treated as High.
+type SchmittTrigger struct {
+	High, Low FLOAT
+	// MinOff is the smallest number of samples between two on intervals. On
+	// intervals with shorter gaps between them are merged.
+	MinOff int
+	// MinOn is the smallest number of samples in an on interval. Shorter
+	// intervals are removed, after merging intervals with short gaps.
+	MinOn int
+}
+
+// Intervals returns the intervals in which the trigger is on for signal a.
+// The trigger starts off.
+func (s SchmittTrigger) Intervals(a []FLOAT) []Interval {
+	low := s.Low
+	if low > s.High {
+		low = s.High
+	}
+	var intervals []Interval
+	on := false
+	start := 0
+	for i, x := range a {
+		if !on && x >= s.High {
+			on = true
+			start = i
+		} else if on && x < low {
+			on = false
+			intervals = append(intervals, Interval{Start: start, End: i})
+		}
+	}
+	if on {
+		intervals = append(intervals, Interval{Start: start, End: len(a)})
+	}
+
+	merged := intervals[:0]
+	for _, interval := range intervals {
+		last := len(merged) - 1
+		if last >= 0 && interval.Start-merged[last].End < s.MinOff {
+			merged[last].End = interval.End
+		} else {
+			merged = append(merged, interval)
+		}
+	}
+	long := merged[:0]
+	for _, interval := range merged {
+		if interval.Len() >= s.MinOn {
+			long = append(long, interval)
+		}
+	}
+	return long
+}
@@ -0,0 +1,133 @@
+// Package csv reads and writes signals in delimited text files, like CSV or
+// tab separated files, with one sample of each signal per row. A column can
+// hold the time of each row, from which the sample rate is inferred.
+package csv
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gonutz/dsp/pcm"
+)
+
+// Format describes a delimited text file that holds one sample of each signal
+// per row.
+type Format struct {
+	// Delimiter separates the fields in a row, 0 means ','.
+	Delimiter rune
+	// Header is true if the first row contains the column names.
+	Header bool
+	// DecimalComma is true if numbers use a comma as decimal separator, like
+	// "3,14". The Delimiter must be something else then, usually ';'.
+	DecimalComma bool
+	// Columns selects the columns to read by index, starting at 0. If neither
+	// Columns nor ColumnNames are given, all columns except the time column
+	// are read.
+	Columns []int
+	// ColumnNames selects the columns to read by their names in the header.
+	ColumnNames []string
+	// HasTime is true if a column contains the time of each row in seconds.
+	// It is used to infer the sample rate. The time column is TimeColumn, or
+	// the column named TimeColumnName if that is given.
+	HasTime        bool
+	TimeColumn     int
+	TimeColumnName string
+	// Missing decides what happens to empty fields and fields that contain
+	// NA, N/A, null or None.
+	Missing MissingValues
+}
+
+func (f Format) withDefaults() (Format, error) {
+	if f.Delimiter == 0 {
+		f.Delimiter = ','
+	}
+	if f.DecimalComma && f.Delimiter == ',' {
+		return f, errors.New("csv: delimiter cannot be a comma with DecimalComma")
+	}
+	return f, nil
+}
+
+// MissingValues is the policy for missing values in a file.
+type MissingValues int
+
+const (
+	// MissingIsError makes reading fail at the first missing value.
+	MissingIsError MissingValues = iota
+	// MissingIsNaN reads missing values as NaN.
+	MissingIsNaN
+	// MissingIsZero reads missing values as 0.
+	MissingIsZero
+	// MissingRepeatsPrevious replaces a missing value with the previous value
+	// in the same column, or 0 in the first row.
+	MissingRepeatsPrevious
+	// MissingSkipsRow skips all rows that have a missing value.
+	MissingSkipsRow
+)
+
+// Data holds the signals of a delimited text file.
+type Data struct {
+	// Names are the header names of the signals, empty without a header.
+	Names []string
+	// Signals holds the selected columns.
+	Signals [][]float64
+	// Time holds the time column, it is nil if there is none.
+	Time []float64
+	// SampleRate is inferred from the time column when reading, see
+	// Reader.SampleRate. When writing, it is used to write the time column.
+	SampleRate float64
+}
+
+// Float32 returns the signals converted to float32.
+func (d *Data) Float32() [][]float32 {
+	return pcm.Float32(d.Signals)
+}
+
+// Decode reads all rows of a delimited text file from r.
+func Decode(r io.Reader, format Format) (*Data, error) {
+	c, err := NewReader(r, format)
+	if err != nil {
+		return nil, err
+	}
+	data := &Data{
+		Names:      c.Names(),
+		Signals:    make([][]float64, len(c.columns)),
+		SampleRate: c.SampleRate(),
+	}
+	block := make([][]float64, len(c.columns))
+	for i := range block {
+		block[i] = make([]float64, 4096)
+	}
+	var time []float64
+	if format.HasTime {
+		time = make([]float64, 4096)
+		data.Time = []float64{}
+	}
+	for {
+		n, err := c.Read(block, time)
+		for i := range block {
+			data.Signals[i] = append(data.Signals[i], block[i][:n]...)
+		}
+		if time != nil {
+			data.Time = append(data.Time, time[:n]...)
+		}
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Encode writes d.Signals with the header d.Names to w, see Writer. The time
+// column is computed from d.SampleRate, d.Time is not used.
+func Encode(w io.Writer, format Format, d *Data) error {
+	c, err := NewWriter(w, format, d.SampleRate, d.Names)
+	if err != nil {
+		return err
+	}
+	if err := c.Write(d.Signals); err != nil {
+		return err
+	}
+	return c.Flush()
+}
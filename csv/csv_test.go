@@ -0,0 +1,218 @@
+package csv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestReadCSVSelectsColumnsByName(t *testing.T) {
+	data, err := Decode(strings.NewReader(
+		"time, voltage, current, temp\n"+
+			"0.000, 1.5, -2, 20\n"+
+			"0.001, 2.5, -3, 21\n"+
+			"0.002, 3.5, -4, 22\n",
+	), Format{
+		Header:         true,
+		ColumnNames:    []string{"temp", "voltage"},
+		HasTime:        true,
+		TimeColumnName: "time",
+	})
+	check.Eq(t, err, nil)
+	check.Eq(t, data.Names, []string{"temp", "voltage"})
+	check.Eq(t, data.Signals, [][]float64{{20, 21, 22}, {1.5, 2.5, 3.5}})
+	check.Eq(t, data.Time, []float64{0, 0.001, 0.002})
+	check.EqEps(t, data.SampleRate, 1000, 0.01)
+}
+
+func TestReadCSVWithoutHeaderReadsAllButTimeColumn(t *testing.T) {
+	data, err := Decode(strings.NewReader(
+		"1\t10\t100\n"+
+			"2\t20\t200\n",
+	), Format{
+		Delimiter:  '\t',
+		HasTime:    true,
+		TimeColumn: 1,
+	})
+	check.Eq(t, err, nil)
+	check.Eq(t, data.Names, []string{"", ""})
+	check.Eq(t, data.Signals, [][]float64{{1, 2}, {100, 200}})
+	check.Eq(t, data.Time, []float64{10, 20})
+	check.Eq(t, data.SampleRate, 0.1)
+
+	data, err = Decode(strings.NewReader("1,2\n3,4\n"), Format{Columns: []int{1}})
+	check.Eq(t, err, nil)
+	check.Eq(t, data.Signals, [][]float64{{2, 4}})
+	check.Eq(t, data.Time, nil)
+	check.Eq(t, data.SampleRate, 0)
+}
+
+func TestReadCSVWithDecimalComma(t *testing.T) {
+	data, err := Decode(strings.NewReader(
+		"a;b\n"+
+			"1,5;-0,25\n"+
+			"\"2,5\";3\n",
+	), Format{
+		Delimiter:    ';',
+		Header:       true,
+		DecimalComma: true,
+	})
+	check.Eq(t, err, nil)
+	check.Eq(t, data.Signals, [][]float64{{1.5, 2.5}, {-0.25, 3}})
+
+	_, err = Decode(strings.NewReader("1"), Format{DecimalComma: true})
+	check.Neq(t, err, nil)
+}
+
+func TestMissingValuePolicies(t *testing.T) {
+	file := "1,2\n,NA\n5,\n"
+	read := func(m MissingValues) ([][]float64, error) {
+		data, err := Decode(strings.NewReader(file), Format{Missing: m})
+		if err != nil {
+			return nil, err
+		}
+		return data.Signals, nil
+	}
+
+	_, err := read(MissingIsError)
+	check.Eq(t, err.Error(), "csv: row 2, column 1: missing value")
+
+	s, err := read(MissingIsNaN)
+	check.Eq(t, err, nil)
+	check.Eq(t, math.IsNaN(s[0][1]), true)
+	check.Eq(t, math.IsNaN(s[1][1]), true)
+	check.Eq(t, math.IsNaN(s[1][2]), true)
+
+	s, err = read(MissingIsZero)
+	check.Eq(t, err, nil)
+	check.Eq(t, s, [][]float64{{1, 0, 5}, {2, 0, 0}})
+
+	s, err = read(MissingRepeatsPrevious)
+	check.Eq(t, err, nil)
+	check.Eq(t, s, [][]float64{{1, 1, 5}, {2, 2, 2}})
+
+	s, err = read(MissingSkipsRow)
+	check.Eq(t, err, nil)
+	check.Eq(t, s, [][]float64{{1}, {2}})
+}
+
+func TestInvalidCSVNumbersAreReported(t *testing.T) {
+	_, err := Decode(strings.NewReader("x\n1\n2x\n"), Format{Header: true})
+	check.Eq(t, err.Error(), `csv: row 3, column 1: invalid number "2x"`)
+
+	_, err = Decode(strings.NewReader("a\n1\n"), Format{Header: true, ColumnNames: []string{"b"}})
+	check.Neq(t, err, nil)
+	_, err = Decode(strings.NewReader(""), Format{})
+	check.Neq(t, err, nil)
+}
+
+func TestCSVReaderStreamsInBlocks(t *testing.T) {
+	var file bytes.Buffer
+	file.WriteString("t,x\n")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&file, "%g,%d\n", float64(i)/100, i)
+	}
+	r, err := NewReader(bytes.NewReader(file.Bytes()), Format{
+		Header:  true,
+		HasTime: true,
+	})
+	check.Eq(t, err, nil)
+	check.EqEps(t, r.SampleRate(), 100, 0.001)
+
+	var x, time []float64
+	block := [][]float64{make([]float64, 333)}
+	timeBlock := make([]float64, 500)
+	for {
+		n, err := r.Read(block, timeBlock)
+		x = append(x, block[0][:n]...)
+		time = append(time, timeBlock[:n]...)
+		if err == io.EOF {
+			break
+		}
+		check.Eq(t, err, nil)
+	}
+	check.Eq(t, len(x), 5000)
+	for i := range x {
+		check.Eq(t, x[i], float64(i))
+		check.EqEps(t, time[i], float64(i)/100, 1e-9)
+	}
+}
+
+func TestWriteCSVWritesTimeAndSignals(t *testing.T) {
+	var b bytes.Buffer
+	err := Encode(&b, Format{
+		Delimiter:    ';',
+		Header:       true,
+		DecimalComma: true,
+		HasTime:      true,
+	}, &Data{
+		Names:      []string{"left", "right"},
+		Signals:    [][]float64{{0.5, -1, 2}, {0, 1.25, 3}},
+		SampleRate: 4,
+	})
+	check.Eq(t, err, nil)
+	check.Eq(t, b.String(), ""+
+		"time;left;right\n"+
+		"0;0,5;0\n"+
+		"0,25;-1;1,25\n"+
+		"0,5;2;3\n",
+	)
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	signals := [][]float64{
+		{0.1, 0.2, 1e-7, -3.25, math.Pi},
+		{1, 2, 3, 4, 5},
+	}
+	format := Format{Header: true, HasTime: true, TimeColumnName: "seconds"}
+	var b bytes.Buffer
+	w, err := NewWriter(&b, format, 8000, []string{"a", "b"})
+	check.Eq(t, err, nil)
+	check.Eq(t, w.Write([][]float64{signals[0][:2], signals[1][:2]}), nil)
+	check.Eq(t, w.Write([][]float64{signals[0][2:], signals[1][2:]}), nil)
+	check.Eq(t, w.Flush(), nil)
+
+	data, err := Decode(&b, format)
+	check.Eq(t, err, nil)
+	check.Eq(t, data.Names, []string{"a", "b"})
+	check.Eq(t, data.Signals, signals)
+	check.EqEps(t, data.SampleRate, 8000, 0.01)
+}
+
+func TestCSVWriterChecksSignals(t *testing.T) {
+	var b bytes.Buffer
+	w, err := NewWriter(&b, Format{}, 1, []string{"a", "b"})
+	check.Eq(t, err, nil)
+	check.Neq(t, w.Write([][]float64{{1}}), nil)
+	check.Neq(t, w.Write([][]float64{{1}, {1, 2}}), nil)
+	_, err = NewWriter(&b, Format{HasTime: true}, 0, nil)
+	check.Neq(t, err, nil)
+}
+
+func TestFloat32ValuesAreWrittenShortest(t *testing.T) {
+	var b bytes.Buffer
+	w, err := NewWriter(&b, Format{}, 1, []string{"a", "b"})
+	check.Eq(t, err, nil)
+	check.Eq(t, w.WriteFloat32([][]float32{{0.1, 1e-7}, {2, -3.25}}), nil)
+	check.Eq(t, w.Write([][]float64{{float64(float32(0.1))}, {0.1}}), nil)
+	check.Eq(t, w.Flush(), nil)
+	check.Eq(t, b.String(), ""+
+		"0.1,2\n"+
+		"1e-07,-3.25\n"+
+		"0.10000000149011612,0.1\n",
+	)
+
+	r, err := NewReader(&b, Format{})
+	check.Eq(t, err, nil)
+	dst := [][]float32{make([]float32, 10), make([]float32, 10)}
+	n, err := r.ReadFloat32(dst, nil)
+	check.Eq(t, err, nil)
+	check.Eq(t, n, 3)
+	check.Eq(t, dst[0][:n], []float32{0.1, 1e-7, 0.1})
+	check.Eq(t, dst[1][:n], []float32{2, -3.25, 0.1})
+}
@@ -0,0 +1,374 @@
+package csv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rateRows is the number of rows that Reader reads ahead to infer the
+// sample rate from the time column.
+const rateRows = 1024
+
+// Reader reads signals from a delimited text file row by row, without
+// loading the whole file into memory.
+type Reader struct {
+	format     Format
+	r          *csv.Reader
+	row        int
+	names      []string
+	columns    []int
+	timeColumn int
+	sampleRate float64
+	previous   []float64
+	// pending holds rows that were read ahead, each with the time first.
+	pending [][]float64
+	err     error
+	buf64   [][]float64
+	time64  []float64
+}
+
+// NewReader reads the header from r, if there is one, and selects the
+// columns to read. If the format has a time column, the first rows are read to
+// infer the sample rate.
+func NewReader(r io.Reader, format Format) (*Reader, error) {
+	format, err := format.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	c := &Reader{format: format, timeColumn: -1}
+	c.r = csv.NewReader(r)
+	c.r.Comma = format.Delimiter
+	c.r.FieldsPerRecord = -1
+	c.r.TrimLeadingSpace = format.Delimiter != ' ' && format.Delimiter != '\t'
+	c.r.ReuseRecord = true
+
+	var header []string
+	if format.Header {
+		record, err := c.read()
+		if err == io.EOF {
+			return nil, errors.New("csv: file has no header")
+		}
+		if err != nil {
+			return nil, err
+		}
+		header = append([]string(nil), record...)
+		for i := range header {
+			header[i] = strings.TrimSpace(header[i])
+		}
+	}
+
+	if format.HasTime {
+		c.timeColumn = format.TimeColumn
+		if format.TimeColumnName != "" {
+			c.timeColumn = indexOf(header, format.TimeColumnName)
+			if c.timeColumn == -1 {
+				return nil, errors.New("csv: no time column named " + format.TimeColumnName)
+			}
+		}
+		if c.timeColumn < 0 {
+			return nil, errors.New("csv: invalid time column")
+		}
+	}
+
+	c.columns = format.Columns
+	if len(format.ColumnNames) > 0 {
+		c.columns = nil
+		for _, name := range format.ColumnNames {
+			i := indexOf(header, name)
+			if i == -1 {
+				return nil, errors.New("csv: no column named " + name)
+			}
+			c.columns = append(c.columns, i)
+		}
+	}
+	for _, i := range c.columns {
+		if i < 0 {
+			return nil, errors.New("csv: invalid column index")
+		}
+	}
+
+	// Read ahead to infer the sample rate and, if no columns were selected
+	// and there is no header, the number of columns.
+	readAhead := 0
+	if c.columns == nil && header == nil {
+		readAhead = 1
+	}
+	if format.HasTime {
+		readAhead = rateRows
+	}
+	width := len(header)
+	for len(c.pending) < readAhead && c.err == nil {
+		record, err := c.read()
+		if err != nil {
+			c.err = err
+			break
+		}
+		if width == 0 {
+			width = len(record)
+		}
+		if c.columns == nil {
+			c.selectAllColumns(width)
+		}
+		row, err := c.parse(record)
+		if err != nil {
+			c.err = err
+			break
+		}
+		if row != nil {
+			c.pending = append(c.pending, row)
+		}
+	}
+	if c.columns == nil {
+		c.selectAllColumns(width)
+	}
+	if c.err != nil && c.err != io.EOF {
+		return nil, c.err
+	}
+	if len(c.columns) == 0 {
+		return nil, errors.New("csv: file has no columns to read")
+	}
+
+	c.names = make([]string, len(c.columns))
+	for i, col := range c.columns {
+		if col < len(header) {
+			c.names[i] = header[col]
+		}
+	}
+	c.sampleRate = inferSampleRate(c.pending)
+	return c, nil
+}
+
+func indexOf(list []string, s string) int {
+	for i := range list {
+		if list[i] == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Reader) selectAllColumns(width int) {
+	c.columns = []int{}
+	for i := 0; i < width; i++ {
+		if i != c.timeColumn {
+			c.columns = append(c.columns, i)
+		}
+	}
+}
+
+// inferSampleRate returns the inverse of the median time step between rows or
+// 0 if that is not positive.
+func inferSampleRate(rows [][]float64) float64 {
+	if len(rows) < 2 {
+		return 0
+	}
+	steps := make([]float64, len(rows)-1)
+	for i := range steps {
+		steps[i] = rows[i+1][0] - rows[i][0]
+	}
+	sort.Float64s(steps)
+	step := steps[len(steps)/2]
+	if !(step > 0) {
+		return 0
+	}
+	return 1 / step
+}
+
+// Names returns the header names of the selected columns. Without a header the
+// names are empty.
+func (c *Reader) Names() []string {
+	return c.names
+}
+
+// SampleRate returns the sample rate that was inferred from the time column. It
+// is 0 if there is no time column or the time does not increase.
+func (c *Reader) SampleRate() float64 {
+	return c.sampleRate
+}
+
+// Read reads the next rows into dst, which must contain one slice per selected
+// column. If time is not nil, the time of each row is stored in it. Read reads
+// at most as many rows as the shortest slice can hold and returns the number
+// of rows read. At the end of the file it returns 0 and io.EOF.
+func (c *Reader) Read(dst [][]float64, time []float64) (int, error) {
+	if len(dst) != len(c.columns) {
+		return 0, errors.New("csv: Read needs one buffer per column")
+	}
+	n := -1
+	for _, d := range dst {
+		if n == -1 || len(d) < n {
+			n = len(d)
+		}
+	}
+	if time != nil && (n == -1 || len(time) < n) {
+		n = len(time)
+	}
+
+	rows := 0
+	for rows < n {
+		row, err := c.next()
+		if err != nil {
+			if rows > 0 {
+				return rows, nil
+			}
+			return 0, err
+		}
+		if time != nil {
+			time[rows] = row[0]
+		}
+		for i := range dst {
+			dst[i][rows] = row[i+1]
+		}
+		rows++
+	}
+	return rows, nil
+}
+
+// ReadFloat32 is like Read but converts the values to float32.
+func (c *Reader) ReadFloat32(dst [][]float32, time []float32) (int, error) {
+	if len(dst) != len(c.columns) {
+		return 0, errors.New("csv: Read needs one buffer per column")
+	}
+	if c.buf64 == nil {
+		c.buf64 = make([][]float64, len(dst))
+		for i := range c.buf64 {
+			c.buf64[i] = make([]float64, 4096)
+		}
+		c.time64 = make([]float64, 4096)
+	}
+	buf := make([][]float64, len(dst))
+	for i := range buf {
+		buf[i] = c.buf64[i]
+		if len(dst[i]) < len(buf[i]) {
+			buf[i] = buf[i][:len(dst[i])]
+		}
+	}
+	var time64 []float64
+	if time != nil {
+		time64 = c.time64
+		if len(time) < len(time64) {
+			time64 = time64[:len(time)]
+		}
+	}
+	n, err := c.Read(buf, time64)
+	for i := range buf {
+		for j := 0; j < n; j++ {
+			dst[i][j] = float32(buf[i][j])
+		}
+	}
+	if time != nil {
+		for j := 0; j < n; j++ {
+			time[j] = float32(time64[j])
+		}
+	}
+	return n, err
+}
+
+func (c *Reader) next() ([]float64, error) {
+	if len(c.pending) > 0 {
+		row := c.pending[0]
+		c.pending = c.pending[1:]
+		return row, nil
+	}
+	for c.err == nil {
+		record, err := c.read()
+		if err != nil {
+			c.err = err
+			break
+		}
+		row, err := c.parse(record)
+		if err != nil {
+			c.err = err
+			break
+		}
+		if row != nil {
+			return row, nil
+		}
+	}
+	return nil, c.err
+}
+
+func (c *Reader) read() ([]string, error) {
+	record, err := c.r.Read()
+	if err == nil {
+		c.row++
+	}
+	return record, err
+}
+
+// parse returns the time and the selected values of a record. It returns nil
+// if the row is skipped.
+func (c *Reader) parse(record []string) ([]float64, error) {
+	if len(c.previous) != len(c.columns) {
+		c.previous = make([]float64, len(c.columns))
+	}
+	row := make([]float64, 1+len(c.columns))
+	if c.timeColumn != -1 {
+		t, ok, err := c.parseField(record, c.timeColumn)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if c.format.Missing == MissingSkipsRow {
+				return nil, nil
+			}
+			return nil, c.fieldError(c.timeColumn, "missing time")
+		}
+		row[0] = t
+	}
+	for i, col := range c.columns {
+		v, ok, err := c.parseField(record, col)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			switch c.format.Missing {
+			case MissingIsNaN:
+				v = math.NaN()
+			case MissingIsZero:
+				v = 0
+			case MissingRepeatsPrevious:
+				v = c.previous[i]
+			case MissingSkipsRow:
+				return nil, nil
+			default:
+				return nil, c.fieldError(col, "missing value")
+			}
+		}
+		row[1+i] = v
+	}
+	for i := range c.columns {
+		c.previous[i] = row[1+i]
+	}
+	return row, nil
+}
+
+// parseField parses record[col]. It returns false if the value is missing.
+func (c *Reader) parseField(record []string, col int) (float64, bool, error) {
+	if col >= len(record) {
+		return 0, false, nil
+	}
+	s := strings.TrimSpace(record[col])
+	switch strings.ToLower(s) {
+	case "", "na", "n/a", "null", "none":
+		return 0, false, nil
+	}
+	if c.format.DecimalComma {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, c.fieldError(col, "invalid number "+strconv.Quote(record[col]))
+	}
+	return v, true, nil
+}
+
+func (c *Reader) fieldError(col int, msg string) error {
+	return fmt.Errorf("csv: row %d, column %d: %s", c.row, col+1, msg)
+}
@@ -0,0 +1,141 @@
+package csv
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Writer writes signals to a delimited text file row by row. It uses the
+// Delimiter, Header, DecimalComma and HasTime settings of its Format. The time
+// column is written first, its header is TimeColumnName or "time".
+type Writer struct {
+	w          *csv.Writer
+	format     Format
+	sampleRate float64
+	channels   int
+	rows       int
+	record     []string
+}
+
+// NewWriter creates a Writer for len(names) signals and writes the header if
+// the format has one. The sample rate is used for the time column.
+func NewWriter(w io.Writer, format Format, sampleRate float64, names []string) (*Writer, error) {
+	format, err := format.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if format.HasTime && !(sampleRate > 0) {
+		return nil, errors.New("csv: time column needs a positive sample rate")
+	}
+	c := &Writer{
+		w:          csv.NewWriter(w),
+		format:     format,
+		sampleRate: sampleRate,
+		channels:   len(names),
+	}
+	c.w.Comma = format.Delimiter
+	if format.Header {
+		if format.HasTime {
+			name := format.TimeColumnName
+			if name == "" {
+				name = "time"
+			}
+			c.record = append(c.record, name)
+		}
+		c.record = append(c.record, names...)
+		if err := c.w.Write(c.record); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Write appends one row per sample. src must contain one signal per name given
+// to NewWriter, all of the same length. Numbers are written with the fewest
+// digits that read back as the same float64.
+func (c *Writer) Write(src [][]float64) error {
+	n, err := c.rowCount(len(src), func(i int) int { return len(src[i]) })
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		c.startRow()
+		for _, s := range src {
+			c.record = append(c.record, c.formatFloat(s[i], 64))
+		}
+		if err := c.endRow(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFloat32 is like Write but for float32 samples. Numbers are written with
+// the fewest digits that read back as the same float32.
+func (c *Writer) WriteFloat32(src [][]float32) error {
+	n, err := c.rowCount(len(src), func(i int) int { return len(src[i]) })
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		c.startRow()
+		for _, s := range src {
+			c.record = append(c.record, c.formatFloat(float64(s[i]), 32))
+		}
+		if err := c.endRow(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowCount checks that there are count signals of the same length and returns
+// that length.
+func (c *Writer) rowCount(count int, length func(i int) int) (int, error) {
+	if count != c.channels {
+		return 0, errors.New("csv: Write needs one signal per column")
+	}
+	n := 0
+	if count > 0 {
+		n = length(0)
+	}
+	for i := 0; i < count; i++ {
+		if length(i) != n {
+			return 0, errors.New("csv: signals have different lengths")
+		}
+	}
+	return n, nil
+}
+
+func (c *Writer) startRow() {
+	c.record = c.record[:0]
+	if c.format.HasTime {
+		t := float64(c.rows) / c.sampleRate
+		c.record = append(c.record, c.formatFloat(t, 64))
+	}
+}
+
+func (c *Writer) endRow() error {
+	if err := c.w.Write(c.record); err != nil {
+		return err
+	}
+	c.rows++
+	return nil
+}
+
+func (c *Writer) formatFloat(x float64, bits int) string {
+	s := strconv.FormatFloat(x, 'g', -1, bits)
+	if c.format.DecimalComma {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+// Flush writes all buffered rows to the underlying io.Writer.
+func (c *Writer) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
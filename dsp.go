@@ -76,20 +76,40 @@ func AverageFilter(a []FLOAT, width int) []FLOAT {
 	}
 
 	b := make([]FLOAT, len(a)-(width-1))
-	f := 1.0 / FLOAT(width)
+	averageFilterRange(a, b, width, 0, len(b))
+	return b
+}
 
-	var slidingSum FLOAT
-	for i := 0; i < width; i++ {
-		slidingSum += a[i]
+// averageFilterBlock returns the number of outputs after which the sliding sum
+// in AverageFilter is computed anew. This keeps rounding errors from
+// accumulating over long inputs and makes it possible to compute independent
+// blocks in parallel with the exact same results.
+func averageFilterBlock(width int) int {
+	const minBlock = 4096
+	if width > minBlock {
+		return width
 	}
-	b[0] = slidingSum * f
+	return minBlock
+}
 
-	for i := 1; i < len(b); i++ {
-		slidingSum += a[i+width-1] - a[i-1]
+// averageFilterRange computes b[lo:hi] for AverageFilter. lo must be a multiple
+// of averageFilterBlock(width).
+func averageFilterRange(a, b []FLOAT, width, lo, hi int) {
+	f := 1.0 / FLOAT(width)
+	block := averageFilterBlock(width)
+
+	var slidingSum FLOAT
+	for i := lo; i < hi; i++ {
+		if i%block == 0 {
+			slidingSum = 0
+			for j := 0; j < width; j++ {
+				slidingSum += a[i+j]
+			}
+		} else {
+			slidingSum += a[i+width-1] - a[i-1]
+		}
 		b[i] = slidingSum * f
 	}
-
-	return b
 }
 
 // MedianFilter returns a new array of median filtered values over a. The
@@ -108,14 +128,19 @@ func MedianFilter(a []FLOAT, width int) []FLOAT {
 		return Copy(a)
 	}
 
-	buf := make([]FLOAT, width)
 	b := make([]FLOAT, len(a)-width+1)
-	for i := range b {
+	medianFilterRange(a, b, width, 0, len(b))
+	return b
+}
+
+// medianFilterRange computes b[lo:hi] for MedianFilter.
+func medianFilterRange(a, b []FLOAT, width, lo, hi int) {
+	buf := make([]FLOAT, width)
+	for i := lo; i < hi; i++ {
 		copy(buf, a[i:])
 		sort.Sort(floats(buf))
 		b[i] = buf[width/2]
 	}
-	return b
 }
 
 type floats []FLOAT
@@ -0,0 +1,130 @@
+package dsp
+
+import "math"
+
+// Unwrap removes the jumps from a that come from wrapping angles into a range
+// of one period, e.g. the jumps from pi to -pi in the output of Angle. Where
+// two neighboring values differ by more than discontinuity, multiples of
+// period are added to all following values to make the difference less than
+// period/2. A period of 0 or less means 2*pi, a discontinuity less than
+// period/2 means period/2.
+func Unwrap(a []float32, discontinuity, period float32) []float32 {
+	x := make([]float64, len(a))
+	for i := range a {
+		x[i] = float64(a[i])
+	}
+	unwrap(x, float64(discontinuity), float64(period))
+	return tofloat32(x)
+}
+
+// unwrap is Unwrap in place. It works with float64 because unwrapped phases
+// grow large.
+func unwrap(x []float64, discontinuity, period float64) {
+	if period <= 0 {
+		period = 2 * math.Pi
+	}
+	if discontinuity < period/2 {
+		discontinuity = period / 2
+	}
+	var offset float64
+	last := 0.0
+	for i := range x {
+		if i > 0 {
+			d := x[i] - last
+			if math.Abs(d) >= discontinuity {
+				// Bring the difference into [-period/2, period/2), with a
+				// positive jump of exactly period/2 kept positive.
+				m := d + period/2 - period*math.Floor((d+period/2)/period) - period/2
+				if m == -period/2 && d > 0 {
+					m = period / 2
+				}
+				offset += m - d
+			}
+		}
+		last = x[i]
+		x[i] += offset
+	}
+}
+
+// WrapPi returns the angles in a, in radians, wrapped into the range [-pi, pi).
+func WrapPi(a []float32) []float32 {
+	b := make([]float32, len(a))
+	for i := range a {
+		b[i] = float32(wrap(float64(a[i])+math.Pi) - math.Pi)
+	}
+	return b
+}
+
+// Wrap2Pi returns the angles in a, in radians, wrapped into the range
+// [0, 2*pi).
+func Wrap2Pi(a []float32) []float32 {
+	b := make([]float32, len(a))
+	for i := range a {
+		b[i] = float32(wrap(float64(a[i])))
+	}
+	return b
+}
+
+// wrap returns x wrapped into [0, 2*pi).
+func wrap(x float64) float64 {
+	x = math.Mod(x, 2*math.Pi)
+	if x < 0 {
+		x += 2 * math.Pi
+	}
+	if x >= 2*math.Pi {
+		// Adding 2*pi to a tiny negative x rounds to 2*pi.
+		x = 0
+	}
+	return x
+}
+
+// Degrees returns the angles in a converted from radians to degrees.
+func Degrees(a []float32) []float32 {
+	b := make([]float32, len(a))
+	for i := range a {
+		b[i] = float32(float64(a[i]) * 180 / math.Pi)
+	}
+	return b
+}
+
+// Radians returns the angles in a converted from degrees to radians.
+func Radians(a []float32) []float32 {
+	b := make([]float32, len(a))
+	for i := range a {
+		b[i] = float32(float64(a[i]) * math.Pi / 180)
+	}
+	return b
+}
+
+// CircularMean returns the mean direction of the angles in a, in radians, in
+// the range [-pi, pi]. Unlike Average it treats angles that differ by 2*pi as
+// equal, e.g. the mean of 350 and 10 degrees is 0 and not 180 degrees. It
+// returns 0 if a is empty.
+func CircularMean(a []float32) float32 {
+	s, c := sumSinCos(a)
+	if s == 0 && c == 0 {
+		return 0
+	}
+	return float32(math.Atan2(s, c))
+}
+
+// CircularVariance returns the circular variance of the angles in a, in
+// radians. It is 1 minus the length of the mean of the unit vectors in the
+// directions of a, 0 if all angles are equal and up to 1 for angles that are
+// spread evenly around the circle. It returns 0 if a is empty.
+func CircularVariance(a []float32) float32 {
+	if len(a) == 0 {
+		return 0
+	}
+	s, c := sumSinCos(a)
+	return float32(1 - math.Hypot(s, c)/float64(len(a)))
+}
+
+func sumSinCos(a []float32) (sinSum, cosSum float64) {
+	for _, x := range a {
+		s, c := math.Sincos(float64(x))
+		sinSum += s
+		cosSum += c
+	}
+	return
+}
@@ -0,0 +1,63 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestUnwrapRemovesJumps(t *testing.T) {
+	const pi = math.Pi
+	check.Eq(t, len(Unwrap(nil, 0, 0)), 0)
+	wrapped := WrapPi(Range(0, 20))
+	unwrapped := Unwrap(wrapped, 0, 0)
+	for i := range unwrapped {
+		check.EqEps(t, unwrapped[i], i, 1e-5, i)
+	}
+	unwrapped = Unwrap([]float32{0, 3 * pi / 4, -3 * pi / 4, 0}, 0, 0)
+	check.EqEps(t, unwrapped[2], 5*pi/4, 1e-6)
+	check.EqEps(t, unwrapped[3], 2*pi, 1e-6)
+}
+
+func TestUnwrapUsesPeriodAndDiscontinuity(t *testing.T) {
+	check.Eq(t, Unwrap([]float32{350, 10, 30, 340}, 0, 360), []float32{350, 370, 390, 340})
+	// Jumps smaller than the discontinuity are kept.
+	check.Eq(t, Unwrap([]float32{0, 5, 0, 7}, 6, 8), []float32{0, 5, 0, -1})
+	// Jumps of exactly half a period are kept.
+	check.Eq(t, Unwrap([]float32{0, 4, 0, -4}, 0, 8), []float32{0, 4, 0, -4})
+}
+
+func TestWrapAngles(t *testing.T) {
+	const pi = math.Pi
+	a := []float32{0, pi / 2, 3 * pi / 2, -7 * pi / 2, 5, 13}
+	w := WrapPi(a)
+	want := []float64{0, pi / 2, -pi / 2, pi / 2, 5 - 2*pi, 13 - 4*pi}
+	for i := range w {
+		check.EqEps(t, w[i], want[i], 1e-5, i)
+	}
+	w = Wrap2Pi(a)
+	want = []float64{0, pi / 2, 3 * pi / 2, pi / 2, 5, 13 - 4*pi}
+	for i := range w {
+		check.EqEps(t, w[i], want[i], 1e-5, i)
+	}
+	check.Eq(t, Wrap2Pi([]float32{-1e-30})[0] < 2*math.Pi, true)
+}
+
+func TestDegreesAndRadians(t *testing.T) {
+	r := Radians([]float32{0, 90, -180, 360})
+	check.Eq(t, r, []float32{0, math.Pi / 2, -math.Pi, 2 * math.Pi})
+	check.Eq(t, Degrees(r), []float32{0, 90, -180, 360})
+}
+
+func TestCircularMeanAndVariance(t *testing.T) {
+	check.EqEps(t, CircularMean(Radians([]float32{350, 10})), 0, 1e-6)
+	check.EqEps(t, math.Abs(float64(CircularMean(Radians([]float32{170, -170, 180})))), math.Pi, 1e-6)
+	check.EqEps(t, CircularMean([]float32{0.5, 0.5}), 0.5, 1e-6)
+	check.Eq(t, CircularMean(nil), 0)
+
+	check.EqEps(t, CircularVariance([]float32{1, 1, 1}), 0, 1e-6)
+	check.EqEps(t, CircularVariance(Radians([]float32{0, 90, 180, 270})), 1, 1e-6)
+	check.EqEps(t, CircularVariance(Radians([]float32{0, 90})), 1-math.Sqrt2/2, 1e-6)
+	check.Eq(t, CircularVariance(nil), 0)
+}
@@ -0,0 +1,65 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestZeroCrossingsAreInterpolated(t *testing.T) {
+	a := []float32{-1, 3, 2, -2, 0, -1}
+	check.Eq(t, ZeroCrossings(a, AnyCrossing), []Crossing{
+		{Index: 0, Offset: 0.25, Rising: true},
+		{Index: 2, Offset: 0.5, Rising: false},
+		{Index: 3, Offset: 1, Rising: true},
+		{Index: 4, Offset: 0, Rising: false},
+	})
+	check.Eq(t, ZeroCrossings(a, RisingCrossing), []Crossing{
+		{Index: 0, Offset: 0.25, Rising: true},
+		{Index: 3, Offset: 1, Rising: true},
+	})
+	check.Eq(t, ZeroCrossings(a, FallingCrossing)[0].Position(), 2.5)
+	check.Eq(t, len(ZeroCrossings(nil, AnyCrossing)), 0)
+}
+
+func TestLevelCrossingsCountCycles(t *testing.T) {
+	a := AddOffset(Sine(1000, 1, 7, 0.1, 1000), 2)
+	crossings := LevelCrossings(a, 2.5, RisingCrossing)
+	check.Eq(t, len(crossings), 7)
+	for i, c := range crossings {
+		// sin(x) = 0.5 at x = pi/6.
+		want := (float64(i) + 1.0/12 - 0.1/(2*math.Pi)) * 1000 / 7
+		check.EqEps(t, c.Position(), want, 1e-2, i)
+	}
+}
+
+func TestZeroCrossingRatePerFrame(t *testing.T) {
+	a := []float32{1, -1, 1, -1, 1, 1, 1, 1, 1}
+	check.Eq(t, ZeroCrossingRate(a, 5, 2), []float32{1, 0.5, 0})
+	check.Eq(t, ZeroCrossingRate(a, 10, 1), []float32(nil))
+	check.Eq(t, len(ZeroCrossingRate(a, 0, 0)), 8)
+}
+
+func TestSchmittTriggerHasHysteresis(t *testing.T) {
+	a := []float32{0, 0.6, 1, 0.4, 0.9, 0.4, 0.1, 0.6, 0.8, 1, 0.2}
+	s := SchmittTrigger{High: 0.75, Low: 0.25}
+	check.Eq(t, s.Intervals(a), []Interval{{2, 6}, {8, 10}})
+	s.Low = 0.5
+	check.Eq(t, s.Intervals(a), []Interval{{2, 3}, {4, 5}, {8, 10}})
+	s.Low = 1
+	check.Eq(t, s.Intervals(a), []Interval{{2, 3}, {4, 5}, {8, 10}})
+	check.Eq(t, SchmittTrigger{High: 0.5, Low: 0}.Intervals([]float32{0, 1, 1}), []Interval{{1, 3}})
+	check.Eq(t, len(s.Intervals(nil)), 0)
+}
+
+func TestSchmittTriggerFiltersShortIntervals(t *testing.T) {
+	a := []float32{1, 1, 0, 1, 1, 0, 0, 0, 1, 0, 0, 0}
+	s := SchmittTrigger{High: 0.5, Low: 0.5}
+	check.Eq(t, s.Intervals(a), []Interval{{0, 2}, {3, 5}, {8, 9}})
+	s.MinOff = 2
+	check.Eq(t, s.Intervals(a), []Interval{{0, 5}, {8, 9}})
+	s.MinOn = 2
+	check.Eq(t, s.Intervals(a), []Interval{{0, 5}})
+	check.Eq(t, Interval{3, 7}.Len(), 4)
+}
@@ -0,0 +1,279 @@
+package dsp
+
+import "math"
+
+// FilterType selects the kind of anti-alias filter for Decimate and
+// Interpolate.
+type FilterType int
+
+const (
+	// FIRFilterType is a Kaiser windowed sinc lowpass with its cutoff at the
+	// lower Nyquist frequency. It has linear phase and its delay is always
+	// compensated.
+	FIRFilterType FilterType = iota
+	// IIRFilterType is a Chebyshev type I lowpass with 0.05 dB passband
+	// ripple and its cutoff at 80% of the lower Nyquist frequency.
+	IIRFilterType
+)
+
+// AntiAliasFilter configures the lowpass filter of Decimate and Interpolate.
+// The zero value is an FIR filter of the default order.
+type AntiAliasFilter struct {
+	Type FilterType
+	// Order is the number of FIR filter taps minus 1 or the order of the IIR
+	// filter. It is rounded up to an even number. 0 selects the defaults of
+	// 20*factor for FIR and 8 for IIR filters.
+	Order int
+	// ZeroPhase runs the IIR filter forward and backward so that its phase
+	// shift cancels and the output is aligned with the input. Without it, the
+	// IIR filter is causal and delays the signal, depending on the frequency.
+	// FIR filters always have zero phase.
+	ZeroPhase bool
+}
+
+func (f AntiAliasFilter) order(factor int) int {
+	order := f.Order
+	if order <= 0 {
+		order = 20 * factor
+		if f.Type == IIRFilterType {
+			order = 8
+		}
+	}
+	return order + order%2
+}
+
+// Decimate lowpass filters a and then keeps every factor'th sample, starting
+// with the first, like EveryNth. The filter removes all frequencies that would
+// alias above the new Nyquist frequency. Output sample k corresponds to input
+// sample k*factor. At the ends, the FIR filter continues the signal by an odd
+// reflection. A factor of 1 or less returns a copy of a.
+func Decimate(a []float32, factor int, filter AntiAliasFilter) []float32 {
+	if factor <= 1 {
+		return Copy(a)
+	}
+	order := filter.order(factor)
+	n := (len(a) + factor - 1) / factor
+	if filter.Type == IIRFilterType {
+		filtered := chebyshevLowpass(order, 0.8/float64(factor)).filter(a, filter.ZeroPhase)
+		return EveryNth(filtered, factor)
+	}
+
+	half := order / 2
+	h := kaiserLowpass(half, 1/float64(factor))
+	var sum float64
+	for _, x := range h {
+		sum += x
+	}
+	for i := range h {
+		h[i] /= sum
+	}
+	b := make([]float32, n)
+	for k := range b {
+		// Center the filter on input sample k*factor.
+		i := k * factor
+		var acc float64
+		for j, x := range h {
+			acc += x * extended(a, i+j-half)
+		}
+		b[k] = float32(acc)
+	}
+	return b
+}
+
+// Interpolate increases the sample rate of a by factor. It inserts factor-1
+// zeros after every sample and lowpass filters the result, removing the images
+// above the old Nyquist frequency. Output sample k*factor corresponds to input
+// sample k. The FIR filter keeps the input samples unchanged. A factor of 1 or
+// less returns a copy of a.
+func Interpolate(a []float32, factor int, filter AntiAliasFilter) []float32 {
+	if factor <= 1 {
+		return Copy(a)
+	}
+	order := filter.order(factor)
+	b := make([]float32, len(a)*factor)
+	if filter.Type == IIRFilterType {
+		for i, x := range a {
+			b[i*factor] = x * float32(factor)
+		}
+		return chebyshevLowpass(order, 0.8/float64(factor)).filter(b, filter.ZeroPhase)
+	}
+
+	// The sinc has its zeros at multiples of factor, so every output sample
+	// only gets contributions from the input samples around it and the
+	// gain of factor that makes up for the inserted zeros is built in.
+	half := order / 2
+	h := kaiserLowpass(half, 1/float64(factor))
+	for i := range h {
+		h[i] *= float64(factor)
+	}
+	for m := range b {
+		// Input sample k lands on output sample k*factor and contributes
+		// h[m-k*factor+half].
+		first := floorDiv(m-half+factor-1, factor)
+		var acc float64
+		for k := first; k*factor <= m+half; k++ {
+			acc += h[m-k*factor+half] * extended(a, k)
+		}
+		b[m] = float32(acc)
+	}
+	return b
+}
+
+// extended returns a[i] for indices inside of a and continues a by an odd
+// reflection at both ends, which keeps the signal's slope there and avoids
+// the jumps to zero that padding with zeros would cause.
+func extended(a []float32, i int) float64 {
+	n := len(a)
+	if i < 0 {
+		j := -i
+		if j >= n {
+			j = n - 1
+		}
+		return 2*float64(a[0]) - float64(a[j])
+	}
+	if i >= n {
+		j := 2*(n-1) - i
+		if j < 0 {
+			j = 0
+		}
+		return 2*float64(a[n-1]) - float64(a[j])
+	}
+	return float64(a[i])
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// kaiserLowpass returns a Kaiser windowed sinc lowpass filter with 2*half+1
+// taps. The cutoff is relative to the Nyquist frequency. The window is designed
+// for about 86 dB of stop band attenuation.
+func kaiserLowpass(half int, cutoff float64) []float64 {
+	beta := kaiserBeta(86.7)
+	h := make([]float64, 2*half+1)
+	for i := range h {
+		n := float64(i - half)
+		h[i] = cutoff * kaiserSinc(cutoff*n, cutoff*float64(half+1), beta)
+	}
+	return h
+}
+
+// biquad is a second order IIR filter section with a0 = 1.
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+}
+
+// iirFilter is a cascade of biquad sections.
+type iirFilter []biquad
+
+// chebyshevLowpass designs a Chebyshev type I lowpass filter with 0.05 dB
+// passband ripple of the given even order. The cutoff is relative to the
+// Nyquist frequency. The analog prototype is converted with the bilinear
+// transform.
+func chebyshevLowpass(order int, cutoff float64) iirFilter {
+	const rippleDB = 0.05
+	eps := math.Sqrt(math.Pow(10, rippleDB/10) - 1)
+	mu := math.Asinh(1/eps) / float64(order)
+	warped := 2 * math.Tan(math.Pi*cutoff/2)
+
+	f := make(iirFilter, order/2)
+	for k := range f {
+		// Use the pole in the upper half of the s-plane, its conjugate is
+		// part of the same section.
+		theta := math.Pi * float64(2*k+1) / float64(2*order)
+		re := -math.Sinh(mu) * math.Sin(theta) * warped
+		im := math.Cosh(mu) * math.Cos(theta) * warped
+		// z = (2+p)/(2-p)
+		den := (2-re)*(2-re) + im*im
+		zr := ((2+re)*(2-re) - im*im) / den
+		zi := ((2+re)*im + im*(2-re)) / den
+		s := biquad{a1: -2 * zr, a2: zr*zr + zi*zi}
+		// Both zeros are at z = -1, normalize to unit gain at DC.
+		g := (1 + s.a1 + s.a2) / 4
+		s.b0, s.b1, s.b2 = g, 2*g, g
+		f[k] = s
+	}
+	// For even orders, the response at DC is at the bottom of the ripple.
+	gain := 1 / math.Sqrt(1+eps*eps)
+	f[0].b0 *= gain
+	f[0].b1 *= gain
+	f[0].b2 *= gain
+	return f
+}
+
+// dcGain returns the gain of a section for constant input.
+func (s biquad) dcGain() float64 {
+	return (s.b0 + s.b1 + s.b2) / (1 + s.a1 + s.a2)
+}
+
+// run filters x in place. The state of each section is initialized to the
+// steady state for a constant input of x0.
+func (f iirFilter) run(x []float64, x0 float64) {
+	for _, s := range f {
+		g := s.dcGain()
+		z2 := (s.b2 - s.a2*g) * x0
+		z1 := (s.b1-s.a1*g)*x0 + z2
+		for i, in := range x {
+			out := s.b0*in + z1
+			z1 = s.b1*in - s.a1*out + z2
+			z2 = s.b2*in - s.a2*out
+			x[i] = out
+		}
+		x0 *= g
+	}
+}
+
+// filter applies the filter to a. A causal filter starts from silence. The
+// zero phase filter runs forward and backward over the signal, extended at
+// both ends by an odd reflection to reduce transients.
+func (f iirFilter) filter(a []float32, zeroPhase bool) []float32 {
+	if !zeroPhase {
+		x := make([]float64, len(a))
+		for i := range a {
+			x[i] = float64(a[i])
+		}
+		f.run(x, 0)
+		return tofloat32(x)
+	}
+
+	if len(a) == 0 {
+		return nil
+	}
+	pad := 3 * (2*len(f) + 1)
+	if pad > len(a)-1 {
+		pad = len(a) - 1
+	}
+	x := make([]float64, len(a)+2*pad)
+	first, last := float64(a[0]), float64(a[len(a)-1])
+	for i := 0; i < pad; i++ {
+		x[i] = 2*first - float64(a[pad-i])
+		x[len(x)-1-i] = 2*last - float64(a[len(a)-1-pad+i])
+	}
+	for i := range a {
+		x[pad+i] = float64(a[i])
+	}
+	f.run(x, x[0])
+	reverse(x)
+	f.run(x, x[0])
+	reverse(x)
+	return tofloat32(x[pad : pad+len(a)])
+}
+
+func reverse(x []float64) {
+	for i, j := 0, len(x)-1; i < j; i, j = i+1, j-1 {
+		x[i], x[j] = x[j], x[i]
+	}
+}
+
+func tofloat32(x []float64) []float32 {
+	a := make([]float32, len(x))
+	for i := range x {
+		a[i] = float32(x[i])
+	}
+	return a
+}
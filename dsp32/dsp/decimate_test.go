@@ -0,0 +1,121 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+var antiAliasFilters = []AntiAliasFilter{
+	{},
+	{Order: 31},
+	{Type: IIRFilterType, ZeroPhase: true},
+	{Type: IIRFilterType, Order: 6, ZeroPhase: true},
+}
+
+func rms(a []float32) float64 {
+	var sum float64
+	for _, x := range a {
+		sum += float64(x) * float64(x)
+	}
+	return math.Sqrt(sum / float64(len(a)))
+}
+
+// filterTolerance is the largest error in the passband. The passband ripple of
+// the IIR filter applies twice for zero phase filtering, 0.1 dB is about 1.2%.
+func filterTolerance(f AntiAliasFilter) float64 {
+	if f.Type == IIRFilterType {
+		return 1.2e-2
+	}
+	return 2e-3
+}
+
+func TestDecimateKeepsLengthAndAlignmentOfEveryNth(t *testing.T) {
+	a := sine(1001, 0.01)
+	for _, f := range antiAliasFilters {
+		b := Decimate(a, 4, f)
+		check.Eq(t, len(b), len(EveryNth(a, 4)))
+		for k := range b {
+			check.EqEps(t, b[k], a[4*k], filterTolerance(f), f, k)
+		}
+	}
+}
+
+func TestDecimateRemovesFrequenciesAboveNewNyquist(t *testing.T) {
+	// The new Nyquist frequency is 0.125 cycles per input sample.
+	a := sine(4000, 0.3)
+	for _, f := range antiAliasFilters {
+		b := Decimate(a, 4, f)
+		check.Eq(t, rms(b[20:len(b)-20]) < 1e-3, true, f)
+	}
+	check.Eq(t, rms(EveryNth(a, 4)) > 0.5, true)
+}
+
+func TestCausalIIRDecimationDelaysSignal(t *testing.T) {
+	a := sine(2000, 0.01)
+	b := Decimate(a, 2, AntiAliasFilter{Type: IIRFilterType})
+	check.Eq(t, len(b), 1000)
+	// The filter removes the tone above the new Nyquist frequency but delays
+	// the signal.
+	var maxDiff float64
+	for k := 200; k < len(b); k++ {
+		maxDiff = math.Max(maxDiff, math.Abs(float64(b[k]-a[2*k])))
+	}
+	check.Eq(t, maxDiff > 0.05, true)
+}
+
+func TestInterpolateFillsInBandlimitedSamples(t *testing.T) {
+	a := sine(300, 0.05)
+	for _, f := range antiAliasFilters {
+		b := Interpolate(a, 3, f)
+		check.Eq(t, len(b), 900)
+		for m := 30; m < len(b)-30; m++ {
+			want := math.Sin(2 * math.Pi * 0.05 * float64(m) / 3)
+			check.EqEps(t, float64(b[m]), want, filterTolerance(f), f, m)
+		}
+	}
+}
+
+func TestFIRInterpolationKeepsInputSamples(t *testing.T) {
+	a := randomFloats(100, 1)
+	b := Interpolate(a, 5, AntiAliasFilter{})
+	for k := range a {
+		check.EqEps(t, b[5*k], a[k], 1e-5, k)
+	}
+}
+
+func TestDecimateAndInterpolateWithFactorOneCopy(t *testing.T) {
+	a := []float32{1, 2, 3}
+	check.Eq(t, Decimate(a, 1, AntiAliasFilter{}), a)
+	check.Eq(t, Interpolate(a, 0, AntiAliasFilter{}), a)
+	check.Eq(t, len(Decimate(nil, 3, AntiAliasFilter{})), 0)
+	check.Eq(t, len(Interpolate(nil, 3, AntiAliasFilter{Type: IIRFilterType, ZeroPhase: true})), 0)
+}
+
+func TestChebyshevLowpassHasRippleAndStopband(t *testing.T) {
+	f := chebyshevLowpass(8, 0.2)
+	response := func(frequency float64) float64 {
+		// Evaluate H(z) at z = exp(i*pi*frequency).
+		z := complexExp(math.Pi * frequency)
+		h := complex(1, 0)
+		for _, s := range f {
+			num := complex(s.b0, 0) + complex(s.b1, 0)/z + complex(s.b2, 0)/(z*z)
+			den := 1 + complex(s.a1, 0)/z + complex(s.a2, 0)/(z*z)
+			h *= num / den
+		}
+		return 20 * math.Log10(cmplxAbs(h))
+	}
+	for _, freq := range []float64{0, 0.05, 0.1, 0.15, 0.19} {
+		check.EqEps(t, response(freq), -0.025, 0.026, freq)
+	}
+	check.Eq(t, response(0.4) < -60, true)
+}
+
+func complexExp(x float64) complex128 {
+	return complex(math.Cos(x), math.Sin(x))
+}
+
+func cmplxAbs(z complex128) float64 {
+	return math.Hypot(real(z), imag(z))
+}
@@ -76,20 +76,40 @@ func AverageFilter(a []float32, width int) []float32 {
 	}
 
 	b := make([]float32, len(a)-(width-1))
-	f := 1.0 / float32(width)
+	averageFilterRange(a, b, width, 0, len(b))
+	return b
+}
 
-	var slidingSum float32
-	for i := 0; i < width; i++ {
-		slidingSum += a[i]
+// averageFilterBlock returns the number of outputs after which the sliding sum
+// in AverageFilter is computed anew. This keeps rounding errors from
+// accumulating over long inputs and makes it possible to compute independent
+// blocks in parallel with the exact same results.
+func averageFilterBlock(width int) int {
+	const minBlock = 4096
+	if width > minBlock {
+		return width
 	}
-	b[0] = slidingSum * f
+	return minBlock
+}
 
-	for i := 1; i < len(b); i++ {
-		slidingSum += a[i+width-1] - a[i-1]
+// averageFilterRange computes b[lo:hi] for AverageFilter. lo must be a multiple
+// of averageFilterBlock(width).
+func averageFilterRange(a, b []float32, width, lo, hi int) {
+	f := 1.0 / float32(width)
+	block := averageFilterBlock(width)
+
+	var slidingSum float32
+	for i := lo; i < hi; i++ {
+		if i%block == 0 {
+			slidingSum = 0
+			for j := 0; j < width; j++ {
+				slidingSum += a[i+j]
+			}
+		} else {
+			slidingSum += a[i+width-1] - a[i-1]
+		}
 		b[i] = slidingSum * f
 	}
-
-	return b
 }
 
 // MedianFilter returns a new array of median filtered values over a. The
@@ -108,14 +128,19 @@ func MedianFilter(a []float32, width int) []float32 {
 		return Copy(a)
 	}
 
-	buf := make([]float32, width)
 	b := make([]float32, len(a)-width+1)
-	for i := range b {
+	medianFilterRange(a, b, width, 0, len(b))
+	return b
+}
+
+// medianFilterRange computes b[lo:hi] for MedianFilter.
+func medianFilterRange(a, b []float32, width, lo, hi int) {
+	buf := make([]float32, width)
+	for i := lo; i < hi; i++ {
 		copy(buf, a[i:])
 		sort.Sort(floats(buf))
 		b[i] = buf[width/2]
 	}
-	return b
 }
 
 type floats []float32
@@ -0,0 +1,175 @@
+package dsp
+
+import "math"
+
+// FFT returns the discrete Fourier transform of a, computed with a fast
+// Fourier transform. a can have any length, lengths that are powers of 2 are
+// fastest. The result is not normalized, bin k is the sum of
+// a[n] * exp(-2*pi*i*k*n/len(a)). See FFTFreq for the frequency of each bin.
+func FFT(a []complex64) []complex64 {
+	x := toComplex128(a)
+	fft(x, false)
+	return tocomplex64(x)
+}
+
+// IFFT returns the inverse discrete Fourier transform of a, it is normalized
+// so that IFFT(FFT(a)) is a again.
+func IFFT(a []complex64) []complex64 {
+	x := toComplex128(a)
+	fft(x, true)
+	scale := 1 / float64(len(x))
+	for i := range x {
+		x[i] *= complex(scale, 0)
+	}
+	return tocomplex64(x)
+}
+
+// RFFT returns the discrete Fourier transform of the real signal a. Since the
+// negative frequencies of a real signal are the complex conjugates of the
+// positive ones, only the len(a)/2+1 bins for frequencies 0 to the Nyquist
+// frequency are returned. See RFFTFreq for the frequency of each bin.
+func RFFT(a []float32) []complex64 {
+	x := make([]complex128, len(a))
+	for i := range a {
+		x[i] = complex(float64(a[i]), 0)
+	}
+	fft(x, false)
+	if len(x) == 0 {
+		return nil
+	}
+	return tocomplex64(x[:len(x)/2+1])
+}
+
+// IRFFT is the inverse of RFFT. It returns the real signal of length n whose
+// first n/2+1 Fourier coefficients are given in a. Missing coefficients are
+// 0, the imaginary parts of the coefficients at 0 Hz and at the Nyquist
+// frequency are ignored.
+func IRFFT(a []complex64, n int) []float32 {
+	if n <= 0 {
+		return nil
+	}
+	x := make([]complex128, n)
+	for k := 0; k <= n/2 && k < len(a); k++ {
+		x[k] = complex128(a[k])
+		if k > 0 {
+			x[n-k] = complex(real(x[k]), -imag(x[k]))
+		}
+	}
+	x[0] = complex(real(x[0]), 0)
+	if n%2 == 0 {
+		x[n/2] = complex(real(x[n/2]), 0)
+	}
+	fft(x, true)
+	b := make([]float32, n)
+	for i := range b {
+		b[i] = float32(real(x[i]) / float64(n))
+	}
+	return b
+}
+
+func toComplex128(a []complex64) []complex128 {
+	x := make([]complex128, len(a))
+	for i := range a {
+		x[i] = complex128(a[i])
+	}
+	return x
+}
+
+func tocomplex64(x []complex128) []complex64 {
+	a := make([]complex64, len(x))
+	for i := range x {
+		a[i] = complex64(x[i])
+	}
+	return a
+}
+
+// fft computes the unnormalized discrete Fourier transform of x in place. The
+// inverse transform uses exp(+2*pi*i*k*n/N).
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+	if n&(n-1) == 0 {
+		radix2FFT(x, inverse)
+	} else {
+		bluesteinFFT(x, inverse)
+	}
+}
+
+// radix2FFT is the iterative Cooley-Tukey algorithm, len(x) must be a power of
+// 2.
+func radix2FFT(x []complex128, inverse bool) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	twiddle := make([]complex128, n/2)
+	for k := range twiddle {
+		s, c := math.Sincos(sign * 2 * math.Pi * float64(k) / float64(n))
+		twiddle[k] = complex(c, s)
+	}
+	for size := 2; size <= n; size *= 2 {
+		half, step := size/2, n/size
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				t := twiddle[k*step] * x[start+k+half]
+				x[start+k+half] = x[start+k] - t
+				x[start+k] += t
+			}
+		}
+	}
+}
+
+// bluesteinFFT computes a transform of any length as a convolution, which is
+// done with power of 2 transforms.
+func bluesteinFFT(x []complex128, inverse bool) {
+	n := len(x)
+	m := 1
+	for m < 2*n-1 {
+		m *= 2
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	// chirp[k] = exp(sign*pi*i*k^2/n). k^2 is reduced modulo 2n to keep the
+	// angle small and precise.
+	chirp := make([]complex128, n)
+	for k := range chirp {
+		k2 := (uint64(k) * uint64(k)) % uint64(2*n)
+		s, c := math.Sincos(sign * math.Pi * float64(k2) / float64(n))
+		chirp[k] = complex(c, s)
+	}
+	a := make([]complex128, m)
+	b := make([]complex128, m)
+	for k := 0; k < n; k++ {
+		a[k] = x[k] * chirp[k]
+		b[k] = complex(real(chirp[k]), -imag(chirp[k]))
+		if k > 0 {
+			b[m-k] = b[k]
+		}
+	}
+	radix2FFT(a, false)
+	radix2FFT(b, false)
+	for i := range a {
+		a[i] *= b[i]
+	}
+	radix2FFT(a, true)
+	scale := complex(1/float64(m), 0)
+	for k := range x {
+		x[k] = a[k] * scale * chirp[k]
+	}
+}
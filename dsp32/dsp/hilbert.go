@@ -0,0 +1,157 @@
+package dsp
+
+import "math"
+
+// AnalyticSignal returns the analytic signal of a, which is a + i*H(a) where
+// H(a) is the Hilbert transform of a. Its spectrum is that of a with the
+// negative frequencies removed and the positive frequencies doubled. It is
+// computed with an FFT over the whole signal, which treats a as periodic, so
+// values near both ends are less accurate unless a holds whole cycles.
+func AnalyticSignal(a []float32) []complex64 {
+	return tocomplex64(analytic(a))
+}
+
+func analytic(a []float32) []complex128 {
+	n := len(a)
+	x := make([]complex128, n)
+	for i := range a {
+		x[i] = complex(float64(a[i]), 0)
+	}
+	fft(x, false)
+	// Keep 0 Hz and the Nyquist frequency for even n, double the positive
+	// frequencies and clear the negative ones.
+	for k := 1; k < n; k++ {
+		if 2*k < n {
+			x[k] *= 2
+		} else if 2*k > n {
+			x[k] = 0
+		}
+	}
+	fft(x, true)
+	scale := complex(1/float64(n), 0)
+	for i := range x {
+		x[i] *= scale
+	}
+	return x
+}
+
+// HilbertTransform returns the Hilbert transform of a, the imaginary part of
+// AnalyticSignal(a). It shifts the phase of all frequencies by -90 degrees,
+// e.g. it turns a cosine into a sine.
+func HilbertTransform(a []float32) []float32 {
+	x := analytic(a)
+	h := make([]float32, len(x))
+	for i := range h {
+		h[i] = float32(imag(x[i]))
+	}
+	return h
+}
+
+// Envelope returns the instantaneous amplitude of a, the magnitude of its
+// analytic signal. For an amplitude modulated carrier this is the modulating
+// signal.
+func Envelope(a []float32) []float32 {
+	x := analytic(a)
+	e := make([]float32, len(x))
+	for i := range e {
+		e[i] = float32(math.Hypot(real(x[i]), imag(x[i])))
+	}
+	return e
+}
+
+// InstantaneousPhase returns the phase of the analytic signal of a in radians.
+// The phase is unwrapped, see Unwrap, so it does not jump by 2*pi but keeps
+// growing for positive frequencies.
+func InstantaneousPhase(a []float32) []float32 {
+	x := analytic(a)
+	p := make([]float64, len(x))
+	for i := range x {
+		p[i] = math.Atan2(imag(x[i]), real(x[i]))
+	}
+	unwrap(p, 0, 0)
+	return tofloat32(p)
+}
+
+// InstantaneousFrequency returns the frequency in Hz of a at the given sample
+// rate, the rate of change of its instantaneous phase. The result is one
+// sample shorter than a, value i is the frequency between samples i and i+1.
+func InstantaneousFrequency(a []float32, sampleRate float32) []float32 {
+	x := analytic(a)
+	if len(x) <= 1 {
+		return make([]float32, 0)
+	}
+	f := make([]float32, len(x)-1)
+	scale := float64(sampleRate) / (2 * math.Pi)
+	for i := range f {
+		// The phase difference is the angle of x[i+1] * conj(x[i]).
+		d := x[i+1] * complex(real(x[i]), -imag(x[i]))
+		f[i] = float32(math.Atan2(imag(d), real(d)) * scale)
+	}
+	return f
+}
+
+// HilbertFilter returns the impulse response of a Kaiser windowed FIR Hilbert
+// transformer with the given number of taps. taps is made odd and at least 3.
+// The filter delays its input by (taps-1)/2 samples. It is accurate for
+// frequencies between about 3/taps and 0.5-3/taps times the sample rate,
+// lower and higher frequencies are attenuated.
+func HilbertFilter(taps int) []float32 {
+	if taps < 3 {
+		taps = 3
+	}
+	half := taps / 2
+	const beta = 8.6
+	h := make([]float32, 2*half+1)
+	for i := range h {
+		n := i - half
+		if n%2 == 0 {
+			continue
+		}
+		w := kaiserWindow(float64(n)/float64(half+1), beta)
+		h[i] = float32(2 / (math.Pi * float64(n)) * w)
+	}
+	return h
+}
+
+// HilbertTransformer is the streaming version of HilbertTransform. It uses
+// the FIR filter from HilbertFilter, see there for its frequency range.
+type HilbertTransformer struct {
+	fir   *FIR
+	delay ring
+}
+
+// NewHilbertTransformer returns a HilbertTransformer with the given number of
+// taps, see HilbertFilter.
+func NewHilbertTransformer(taps int) *HilbertTransformer {
+	h := HilbertFilter(taps)
+	return &HilbertTransformer{
+		fir:   NewFIR(h),
+		delay: newRing(len(h) / 2),
+	}
+}
+
+// Process implements Processor. It writes the Hilbert transform of in,
+// delayed by Latency samples, to out.
+func (h *HilbertTransformer) Process(in, out []float32) {
+	h.fir.Process(in, out)
+}
+
+// ProcessAnalytic writes the analytic signal of in to out, which must be at
+// least as long as in. The real part is in delayed by Latency samples to line
+// up with the Hilbert transform in the imaginary part.
+func (h *HilbertTransformer) ProcessAnalytic(in []float32, out []complex64) {
+	im := make([]float32, len(in))
+	h.fir.Process(in, im)
+	for i, x := range in {
+		out[i] = complex64(complex(h.delay.push(x), im[i]))
+	}
+}
+
+// Reset implements Processor.
+func (h *HilbertTransformer) Reset() {
+	h.fir.Reset()
+	h.delay.clear()
+}
+
+// Latency implements Processor and returns (taps-1)/2.
+func (h *HilbertTransformer) Latency() int { return len(h.delay.values) }
@@ -0,0 +1,150 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+var interpolationMethods = []InterpolationMethod{
+	LinearInterpolation,
+	NearestInterpolation,
+	CubicHermiteInterpolation,
+	CatmullRomInterpolation,
+	AkimaInterpolation,
+	PCHIPInterpolation,
+	NaturalSplineInterpolation,
+	ClampedSplineInterpolation,
+	NotAKnotSplineInterpolation,
+}
+
+func TestInterpolationGoesThroughAllPoints(t *testing.T) {
+	x := []float32{-1, 0, 0.5, 2, 3, 3.25, 5}
+	y := []float32{3, -1, 2, 2, 0, 1, 4}
+	for _, method := range interpolationMethods {
+		for n := 1; n <= len(x); n++ {
+			p, err := NewInterpolatorXY(x[:n], y[:n], Interpolation{Method: method})
+			check.Eq(t, err, nil)
+			for i := 0; i < n; i++ {
+				check.EqEps(t, p.At(x[i]), y[i], 1e-5, method, n, i)
+			}
+		}
+		p := NewInterpolator(y, Interpolation{Method: method})
+		for i := range y {
+			check.EqEps(t, p.At(float32(i)), y[i], 1e-5, method, i)
+		}
+	}
+}
+
+func TestLinearAndNearestInterpolation(t *testing.T) {
+	linear := NewInterpolator([]float32{0, 2, -2}, Interpolation{})
+	check.Eq(t, linear.AtEach([]float32{0.25, 1.5}), []float32{0.5, 0})
+	nearest := NewInterpolator([]float32{0, 2, -2}, Interpolation{Method: NearestInterpolation})
+	check.Eq(t, nearest.AtEach([]float32{0.25, 0.5, 1.4, 1.6}), []float32{0, 2, 2, -2})
+}
+
+func TestNaturalSplineHasKnownValue(t *testing.T) {
+	// On [0,1], the spline is -x^3/2 + 3x/2.
+	p := NewInterpolator([]float32{0, 1, 0}, Interpolation{Method: NaturalSplineInterpolation})
+	check.EqEps(t, p.At(0.5), 0.6875, 1e-6)
+	check.EqEps(t, p.At(1.5), 0.6875, 1e-6)
+}
+
+func TestSplinesReproduceCubicPolynomials(t *testing.T) {
+	f := func(x float64) float64 { return x*x*x - 2*x*x + 0.5*x + 1 }
+	df := func(x float64) float64 { return 3*x*x - 4*x + 0.5 }
+	x := []float32{-2, -1.5, 0, 0.25, 1, 2.5, 3}
+	y := make([]float32, len(x))
+	for i := range x {
+		y[i] = float32(f(float64(x[i])))
+	}
+	for _, options := range []Interpolation{
+		{Method: NotAKnotSplineInterpolation},
+		{
+			Method:     ClampedSplineInterpolation,
+			StartSlope: float32(df(-2)),
+			EndSlope:   float32(df(3)),
+		},
+	} {
+		p, err := NewInterpolatorXY(x, y, options)
+		check.Eq(t, err, nil)
+		for v := -2.0; v <= 3; v += 0.1 {
+			check.EqEps(t, float64(p.At(float32(v))), f(v), 1e-4, options.Method, v)
+		}
+	}
+}
+
+func TestCubicInterpolationOfSmoothSignalIsAccurate(t *testing.T) {
+	y := sine(200, 0.02)
+	for _, method := range interpolationMethods[2:] {
+		p := NewInterpolator(y, Interpolation{Method: method})
+		// PCHIP flattens the curve at the extremes of the data.
+		eps := 1e-3
+		if method == PCHIPInterpolation {
+			eps = 3e-3
+		}
+		for v := 10.0; v < 190; v += 0.37 {
+			want := math.Sin(2 * math.Pi * 0.02 * v)
+			check.EqEps(t, float64(p.At(float32(v))), want, eps, method, v)
+		}
+	}
+}
+
+func TestShapePreservingMethodsDoNotOvershoot(t *testing.T) {
+	y := []float32{0, 0, 0, 1, 1, 1, 3, 3, 3}
+	for _, method := range []InterpolationMethod{AkimaInterpolation, PCHIPInterpolation} {
+		p := NewInterpolator(y, Interpolation{Method: method})
+		last := p.At(0)
+		for v := float32(0); v <= 8; v += 0.05 {
+			value := p.At(v)
+			check.Eq(t, value >= last-1e-6, true, method, v)
+			last = value
+		}
+		check.Eq(t, p.At(1.5), 0, method)
+		check.Eq(t, p.At(4.5), 1, method)
+	}
+	spline := NewInterpolator(y, Interpolation{Method: NaturalSplineInterpolation})
+	check.Eq(t, spline.At(1.5) < 0, true)
+}
+
+func TestExtrapolation(t *testing.T) {
+	y := []float32{1, 2, 4}
+	at := func(e Extrapolation) []float32 {
+		return NewInterpolator(y, Interpolation{Extrapolation: e}).AtEach([]float32{-1, 3})
+	}
+	check.Eq(t, at(ExtrapolateHold), []float32{1, 4})
+	check.Eq(t, at(ExtrapolateExtend), []float32{0, 6})
+	check.Eq(t, at(ExtrapolateZero), []float32{0, 0})
+	nan := at(ExtrapolateNaN)
+	check.Eq(t, math.IsNaN(float64(nan[0])), true)
+	check.Eq(t, math.IsNaN(float64(nan[1])), true)
+
+	spline := NewInterpolator(y, Interpolation{
+		Method:        NotAKnotSplineInterpolation,
+		Extrapolation: ExtrapolateExtend,
+	})
+	// The parabola through the points is (x^2 + x + 2) / 2.
+	check.EqEps(t, spline.At(-1), 1, 1e-6)
+	check.EqEps(t, spline.At(3), 7, 1e-6)
+}
+
+func TestGridEvaluatesEvenlySpacedPositions(t *testing.T) {
+	p := NewInterpolator(sine(50, 0.05), Interpolation{Method: AkimaInterpolation})
+	check.Eq(t, p.Grid(2, 0.5, 4), p.AtEach([]float32{2, 2.5, 3, 3.5}))
+	check.Eq(t, len(p.Grid(0, 1, -1)), 0)
+}
+
+func TestEmptyInterpolatorReturnsZero(t *testing.T) {
+	p := NewInterpolator(nil, Interpolation{Method: NaturalSplineInterpolation})
+	check.Eq(t, p.At(1), 0)
+}
+
+func TestInvalidInterpolationPointsAreReported(t *testing.T) {
+	_, err := NewInterpolatorXY([]float32{0, 1}, []float32{0}, Interpolation{})
+	check.Neq(t, err, nil)
+	_, err = NewInterpolatorXY([]float32{0, 1, 1}, []float32{0, 1, 2}, Interpolation{})
+	check.Neq(t, err, nil)
+	_, err = NewInterpolatorXY([]float32{0, 2, 1}, []float32{0, 1, 2}, Interpolation{})
+	check.Neq(t, err, nil)
+}
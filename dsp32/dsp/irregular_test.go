@@ -0,0 +1,121 @@
+package dsp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+// jitteredSine samples a sine of the given frequency at about one sample per
+// time unit with random jitter.
+func jitteredSine(n int, frequency float64, seed int64) (time, values []float32) {
+	rnd := rand.New(rand.NewSource(seed))
+	time = make([]float32, n)
+	values = make([]float32, n)
+	for i := range time {
+		t := float64(i) + 0.4*(rnd.Float64()-0.5)
+		time[i] = float32(t)
+		values[i] = float32(math.Sin(2 * math.Pi * frequency * t))
+	}
+	return
+}
+
+func TestResampleIrregularInterpolatesOntoGrid(t *testing.T) {
+	time, values := jitteredSine(200, 0.02, 1)
+	u, err := ResampleIrregular(time, values, IrregularResampling{
+		Step:          0.5,
+		Start:         1,
+		End:           198,
+		Interpolation: Interpolation{Method: NaturalSplineInterpolation},
+	})
+	check.Eq(t, err, nil)
+	check.Eq(t, len(u.Samples), 395)
+	check.Eq(t, u.Start, 1)
+	check.Eq(t, u.Step, 0.5)
+	for i, x := range u.Samples {
+		want := math.Sin(2 * math.Pi * 0.02 * (1 + 0.5*float64(i)))
+		check.EqEps(t, float64(x), want, 1e-3, i)
+		check.Eq(t, u.Filled[i], false, i)
+	}
+}
+
+func TestResampleIrregularSortsAndAveragesEqualTimes(t *testing.T) {
+	u, err := ResampleIrregular(
+		[]float32{2, 0, 1, 2},
+		[]float32{4, 0, 1, 6},
+		IrregularResampling{},
+	)
+	check.Eq(t, err, nil)
+	check.Eq(t, u.Step, 1)
+	check.Eq(t, u.Samples, []float32{0, 1, 5})
+}
+
+func TestResampleIrregularMarksGaps(t *testing.T) {
+	time := []float32{0, 1, 2, 6, 7}
+	values := []float32{1, 2, 3, 7, 8}
+	options := IrregularResampling{Step: 1, Start: -1, End: 8, MaxGap: 2}
+	filled := []bool{true, false, false, false, true, true, true, false, false, true}
+
+	u, err := ResampleIrregular(time, values, options)
+	check.Eq(t, err, nil)
+	check.Eq(t, u.Filled, filled)
+	check.Eq(t, u.Samples, []float32{1, 1, 2, 3, 4, 5, 6, 7, 8, 8})
+
+	options.GapFill = GapRepeatsPrevious
+	u, _ = ResampleIrregular(time, values, options)
+	check.Eq(t, u.Samples, []float32{1, 1, 2, 3, 3, 3, 3, 7, 8, 8})
+
+	options.GapFill = GapIsZero
+	u, _ = ResampleIrregular(time, values, options)
+	check.Eq(t, u.Samples, []float32{0, 1, 2, 3, 0, 0, 0, 7, 8, 0})
+
+	options.GapFill = GapIsNaN
+	u, _ = ResampleIrregular(time, values, options)
+	for i := range u.Samples {
+		check.Eq(t, math.IsNaN(float64(u.Samples[i])), filled[i], i)
+	}
+}
+
+func TestResampleIrregularAveragesBins(t *testing.T) {
+	u, err := ResampleIrregular(
+		[]float32{0.1, -0.2, 0.9, 1.2, 3.1, 2.8},
+		[]float32{1, 3, 4, 6, 10, 12},
+		IrregularResampling{Step: 1, Start: 0, End: 3, BinAverage: true},
+	)
+	check.Eq(t, err, nil)
+	check.Eq(t, u.Samples, []float32{2, 5, 8, 11})
+	check.Eq(t, u.Filled, []bool{false, false, true, false})
+}
+
+func TestInvalidIrregularSamplesAreReported(t *testing.T) {
+	_, err := ResampleIrregular([]float32{0, 1}, []float32{0}, IrregularResampling{})
+	check.Neq(t, err, nil)
+	_, err = ResampleIrregular(nil, nil, IrregularResampling{})
+	check.Neq(t, err, nil)
+	_, err = ResampleIrregular([]float32{0, float32(math.NaN())}, []float32{0, 1}, IrregularResampling{})
+	check.Neq(t, err, nil)
+}
+
+func TestLombScargleFindsFrequencyOfIrregularData(t *testing.T) {
+	time, values := jitteredSine(300, 0.123, 2)
+	frequencies := LombScargleFrequencies(time, 5)
+	check.Eq(t, len(frequencies) > 0, true)
+	check.EqEps(t, float64(MaxValue(frequencies)), 0.5, 0.01)
+
+	power := LombScargle(time, values, frequencies)
+	peak := MaxIndex(power)
+	check.EqEps(t, float64(frequencies[peak]), 0.123, 0.001)
+	check.EqEps(t, float64(power[peak]), 1, 0.01)
+	for i, p := range power {
+		if math.Abs(float64(frequencies[i])-0.123) > 0.01 {
+			check.Eq(t, p < 0.1, true, frequencies[i])
+		}
+	}
+}
+
+func TestLombScargleOfConstantIsZero(t *testing.T) {
+	power := LombScargle([]float32{0, 1, 3}, []float32{2, 2, 2}, []float32{0, 0.1, 0.2})
+	check.Eq(t, power, []float32{0, 0, 0})
+}
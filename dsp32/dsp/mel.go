@@ -0,0 +1,338 @@
+package dsp
+
+import "math"
+
+// MelScale selects the formula for converting between Hz and mel.
+type MelScale int
+
+const (
+	// SlaneyMel is the scale of Malcolm Slaney's Auditory Toolbox, which is
+	// the default in librosa. It is linear below 1000 Hz, which is 15 mel,
+	// and logarithmic above.
+	SlaneyMel MelScale = iota
+	// HTKMel is the scale of the Hidden Markov Model Toolkit,
+	// 2595 * log10(1 + f/700).
+	HTKMel
+)
+
+const (
+	slaneyLinearHz   = 200.0 / 3
+	slaneyBreakHz    = 1000.0
+	slaneyBreakMel   = slaneyBreakHz / slaneyLinearHz
+	slaneyLogStepMel = 0.06875177742094912 // log(6.4) / 27
+)
+
+func hzToMel(f float64, scale MelScale) float64 {
+	if scale == HTKMel {
+		return 2595 * math.Log10(1+f/700)
+	}
+	if f < slaneyBreakHz {
+		return f / slaneyLinearHz
+	}
+	return slaneyBreakMel + math.Log(f/slaneyBreakHz)/slaneyLogStepMel
+}
+
+func melToHz(m float64, scale MelScale) float64 {
+	if scale == HTKMel {
+		return 700 * (math.Pow(10, m/2595) - 1)
+	}
+	if m < slaneyBreakMel {
+		return m * slaneyLinearHz
+	}
+	return slaneyBreakHz * math.Exp(slaneyLogStepMel*(m-slaneyBreakMel))
+}
+
+// HzToMel returns the frequencies in a, in Hz, converted to mel.
+func HzToMel(a []float32, scale MelScale) []float32 {
+	b := make([]float32, len(a))
+	for i := range a {
+		b[i] = float32(hzToMel(float64(a[i]), scale))
+	}
+	return b
+}
+
+// MelToHz returns the frequencies in a, in mel, converted to Hz.
+func MelToHz(a []float32, scale MelScale) []float32 {
+	b := make([]float32, len(a))
+	for i := range a {
+		b[i] = float32(melToHz(float64(a[i]), scale))
+	}
+	return b
+}
+
+// MelFrequencies returns n frequencies in Hz from minHz to maxHz that are
+// evenly spaced on the mel scale.
+func MelFrequencies(n int, minHz, maxHz float32, scale MelScale) []float32 {
+	return tofloat32(melFrequencies(n, float64(minHz), float64(maxHz), scale))
+}
+
+func melFrequencies(n int, minHz, maxHz float64, scale MelScale) []float64 {
+	mels := linspace(hzToMel(minHz, scale), hzToMel(maxHz, scale), n, true)
+	for i := range mels {
+		mels[i] = melToHz(mels[i], scale)
+	}
+	return mels
+}
+
+// TriangularFilterbank returns triangular filters for the bins of a power
+// spectrum of fftSize samples at the given sample rate, as returned by
+// Spectrogram. Filter i rises from edges[i] to its peak at edges[i+1] and
+// falls to edges[i+2], all in Hz, so there are len(edges)-2 filters with
+// fftSize/2+1 weights each. If normalize is true, the filters are scaled to
+// the same area, their peaks are 2/(edges[i+2]-edges[i]), otherwise their
+// peaks are 1.
+func TriangularFilterbank(edges []float32, sampleRate float32, fftSize int, normalize bool) [][]float32 {
+	e := make([]float64, len(edges))
+	for i := range edges {
+		e[i] = float64(edges[i])
+	}
+	return triangularFilterbank(e, float64(sampleRate), fftSize, normalize)
+}
+
+func triangularFilterbank(edges []float64, sampleRate float64, fftSize int, normalize bool) [][]float32 {
+	if len(edges) < 3 || fftSize < 1 {
+		return nil
+	}
+	bins := fftSize/2 + 1
+	filters := make([][]float32, len(edges)-2)
+	for i := range filters {
+		lo, center, hi := edges[i], edges[i+1], edges[i+2]
+		scale := 1.0
+		if normalize {
+			scale = 2 / (hi - lo)
+		}
+		filters[i] = make([]float32, bins)
+		for k := range filters[i] {
+			f := float64(k) * sampleRate / float64(fftSize)
+			rising := (f - lo) / (center - lo)
+			falling := (hi - f) / (hi - center)
+			if w := math.Min(rising, falling); w > 0 {
+				filters[i][k] = float32(w * scale)
+			}
+		}
+	}
+	return filters
+}
+
+// MelOptions configures mel spectrograms. Zero values select the defaults,
+// which are those of librosa.
+type MelOptions struct {
+	// FrameLength is the FFT size, it defaults to 2048.
+	FrameLength int
+	// Hop is the number of samples between frames, it defaults to
+	// FrameLength/4.
+	Hop int
+	// MelCount is the number of mel bands, it defaults to 128.
+	MelCount int
+	// MinFrequency and MaxFrequency are the lower edge of the first and the
+	// upper edge of the last mel band in Hz. MaxFrequency defaults to half the
+	// sample rate.
+	MinFrequency, MaxFrequency float32
+	Scale                      MelScale
+	// Unnormalized makes the peaks of all mel filters 1. By default they are
+	// normalized to the same area, see TriangularFilterbank.
+	Unnormalized bool
+	// TopDB limits the range of LogMelSpectrogram to this many dB below its
+	// maximum. It defaults to 80, a negative value means no limit.
+	TopDB float32
+}
+
+func (o MelOptions) withDefaults(sampleRate float32) MelOptions {
+	if o.FrameLength <= 0 {
+		o.FrameLength = 2048
+	}
+	if o.Hop <= 0 {
+		o.Hop = o.FrameLength / 4
+	}
+	if o.MelCount <= 0 {
+		o.MelCount = 128
+	}
+	if o.MaxFrequency <= 0 {
+		o.MaxFrequency = sampleRate / 2
+	}
+	if o.TopDB == 0 {
+		o.TopDB = 80
+	}
+	return o
+}
+
+// MelFilterbank returns the mel filters for the bins of a power spectrum of
+// fftSize samples, see TriangularFilterbank. The filter edges are melCount+2
+// frequencies from minHz to maxHz, evenly spaced in mel. This matches
+// librosa.filters.mel with norm="slaney" if normalize is true and norm=None
+// otherwise.
+func MelFilterbank(sampleRate float32, fftSize, melCount int, minHz, maxHz float32, scale MelScale, normalize bool) [][]float32 {
+	if melCount < 1 {
+		return nil
+	}
+	edges := melFrequencies(melCount+2, float64(minHz), float64(maxHz), scale)
+	return triangularFilterbank(edges, float64(sampleRate), fftSize, normalize)
+}
+
+// MelSpectrogram returns the power spectrogram of a, see Spectrogram, with the
+// FFT bins of each frame combined into mel bands.
+func MelSpectrogram(a []float32, sampleRate float32, options MelOptions) [][]float32 {
+	o := options.withDefaults(sampleRate)
+	filters := MelFilterbank(sampleRate, o.FrameLength, o.MelCount, o.MinFrequency, o.MaxFrequency, o.Scale, !o.Unnormalized)
+	return ApplyFilterbank(Spectrogram(a, o.FrameLength, o.Hop), filters)
+}
+
+// ApplyFilterbank returns, for every frame of spectrogram, the weighted sums
+// of its bins with the weights of each filter.
+func ApplyFilterbank(spectrogram, filters [][]float32) [][]float32 {
+	out := make([][]float32, len(spectrogram))
+	for n, frame := range spectrogram {
+		out[n] = make([]float32, len(filters))
+		for i, filter := range filters {
+			var sum float64
+			for k, w := range filter {
+				if w != 0 && k < len(frame) {
+					sum += float64(w) * float64(frame[k])
+				}
+			}
+			out[n][i] = float32(sum)
+		}
+	}
+	return out
+}
+
+// LogMelSpectrogram returns the mel spectrogram of a in dB, 10*log10 of the
+// power, see MelSpectrogram and PowerToDB.
+func LogMelSpectrogram(a []float32, sampleRate float32, options MelOptions) [][]float32 {
+	o := options.withDefaults(sampleRate)
+	return PowerToDB(MelSpectrogram(a, sampleRate, o), o.TopDB)
+}
+
+// PowerToDB returns the power values of a spectrogram in dB, 10*log10 of the
+// power. Values below 1e-10 are raised to 1e-10. If topDB is positive, all
+// values are limited to at most topDB below the maximum. This matches
+// librosa.power_to_db.
+func PowerToDB(spectrogram [][]float32, topDB float32) [][]float32 {
+	const minPower = 1e-10
+	out := make([][]float32, len(spectrogram))
+	highest := math.Inf(-1)
+	for n, frame := range spectrogram {
+		out[n] = make([]float32, len(frame))
+		for k, p := range frame {
+			db := 10 * math.Log10(math.Max(float64(p), minPower))
+			out[n][k] = float32(db)
+			highest = math.Max(highest, db)
+		}
+	}
+	if topDB > 0 {
+		lowest := float32(highest - float64(topDB))
+		for _, frame := range out {
+			for k := range frame {
+				if frame[k] < lowest {
+					frame[k] = lowest
+				}
+			}
+		}
+	}
+	return out
+}
+
+// DCT returns the orthonormal discrete cosine transform of type II of a, as
+// used for MFCCs. Value k is
+// sqrt(2/N) * sum of a[n] * cos(pi*k*(2n+1)/(2N)), with the factor for k=0
+// being sqrt(1/N).
+func DCT(a []float32) []float32 {
+	n := len(a)
+	b := make([]float32, n)
+	for k := range b {
+		var sum float64
+		for i, x := range a {
+			sum += float64(x) * math.Cos(math.Pi*float64(k*(2*i+1))/float64(2*n))
+		}
+		scale := math.Sqrt(2 / float64(n))
+		if k == 0 {
+			scale = math.Sqrt(1 / float64(n))
+		}
+		b[k] = float32(sum * scale)
+	}
+	return b
+}
+
+// MFCCOptions configures MFCC. Zero values select the defaults, which are
+// those of librosa.
+type MFCCOptions struct {
+	Mel MelOptions
+	// Count is the number of coefficients per frame, it defaults to 20.
+	// Delta features are not included, see Delta, which uses the HTK
+	// regression formula.
+	Count int
+	// Lifter scales coefficient i by 1 + Lifter/2 * sin(pi*(i+1)/Lifter),
+	// which raises the higher coefficients. 0 turns liftering off, HTK uses
+	// 22.
+	Lifter int
+}
+
+// MFCC returns the mel frequency cepstral coefficients of a, one slice of
+// options.Count coefficients per frame. They are the DCT of each frame of
+// the log mel spectrogram. This matches librosa.feature.mfcc.
+func MFCC(a []float32, sampleRate float32, options MFCCOptions) [][]float32 {
+	count := options.Count
+	if count <= 0 {
+		count = 20
+	}
+	logMel := LogMelSpectrogram(a, sampleRate, options.Mel)
+	for n, frame := range logMel {
+		c := DCT(frame)
+		if count < len(c) {
+			c = c[:count]
+		}
+		if options.Lifter > 0 {
+			l := float64(options.Lifter)
+			for i := range c {
+				c[i] *= float32(1 + l/2*math.Sin(math.Pi*float64(i+1)/l))
+			}
+		}
+		logMel[n] = c
+	}
+	return logMel
+}
+
+// Delta returns the time derivatives of a sequence of feature vectors, e.g.
+// MFCCs, estimated by linear regression over width frames on each side:
+// d[t] = sum of n*(c[t+n]-c[t-n]) for n from 1 to width, divided by
+// 2 * sum of n^2. The first and last frames are repeated at the edges. This
+// is the formula of HTK, a width of 0 or less means 2 as in HTK. Apply Delta
+// twice for delta-delta features.
+//
+// This is not librosa.feature.delta, which fits a line with a Savitzky-Golay
+// filter over 9 frames. Away from the first and last 4 frames both give the
+// same result for a width of 4, at the edges librosa extends the lines fitted
+// to the first and last 9 frames instead of repeating frames.
+func Delta(features [][]float32, width int) [][]float32 {
+	if width <= 0 {
+		width = 2
+	}
+	var norm float64
+	for n := 1; n <= width; n++ {
+		norm += 2 * float64(n*n)
+	}
+	clamp := func(t int) int {
+		if t < 0 {
+			return 0
+		}
+		if t >= len(features) {
+			return len(features) - 1
+		}
+		return t
+	}
+	d := make([][]float32, len(features))
+	for t := range features {
+		d[t] = make([]float32, len(features[t]))
+		for i := range d[t] {
+			var sum float64
+			for n := 1; n <= width; n++ {
+				next, prev := features[clamp(t+n)], features[clamp(t-n)]
+				if i < len(next) && i < len(prev) {
+					sum += float64(n) * float64(next[i]-prev[i])
+				}
+			}
+			d[t][i] = float32(sum / norm)
+		}
+	}
+	return d
+}
@@ -0,0 +1,119 @@
+package dsp
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+// processInRandomBlocks resets p and feeds in to it in blocks of random sizes,
+// including empty blocks.
+func processInRandomBlocks(p Processor, in []float32, seed int64) []float32 {
+	p.Reset()
+	r := rand.New(rand.NewSource(seed))
+	out := make([]float32, len(in))
+	for start := 0; start < len(in); {
+		end := start + r.Intn(50)
+		if end > len(in) {
+			end = len(in)
+		}
+		p.Process(in[start:end], out[start:end])
+		start = end
+	}
+	return out
+}
+
+// processAtOnce resets p and feeds all of in to it in one call.
+func processAtOnce(p Processor, in []float32) []float32 {
+	p.Reset()
+	out := make([]float32, len(in))
+	p.Process(in, out)
+	return out
+}
+
+func TestProcessorsAreIndependentOfBlockSize(t *testing.T) {
+	in := randomFloats(1000, 5)
+	processors := []Processor{
+		&Gain{Factor: 2.5},
+		&Offset{Offset: -1},
+		NewMovingAverage(7),
+		NewMovingMedian(6),
+		&Differentiator{},
+		NewFIR([]float32{0.5, 0.25, -0.125}),
+		Chain{NewMovingAverage(3), &Differentiator{}, &Gain{Factor: 2}},
+	}
+	for i, p := range processors {
+		want := processAtOnce(p, in)
+		for seed := int64(0); seed < 5; seed++ {
+			check.EqExact(t, processInRandomBlocks(p, in, seed), want, i, seed)
+		}
+	}
+}
+
+func TestProcessorsMatchTheirBatchFunctions(t *testing.T) {
+	in := randomFloats(10000, 6)
+
+	check.EqExact(t, processAtOnce(&Gain{Factor: 3}, in), Scale(in, 3))
+	check.EqExact(t, processAtOnce(&Offset{Offset: 3}, in), AddOffset(in, 3))
+
+	for _, width := range []int{1, 2, 5, 100} {
+		avg := NewMovingAverage(width)
+		check.Eq(t, avg.Latency(), width-1)
+		out := processInRandomBlocks(avg, in, 1)
+		check.EqExact(t, out[avg.Latency():], AverageFilter(in, width), width)
+
+		med := NewMovingMedian(width)
+		check.Eq(t, med.Latency(), width-1)
+		out = processInRandomBlocks(med, in, 1)
+		check.EqExact(t, out[med.Latency():], MedianFilter(in, width), width)
+	}
+
+	d := &Differentiator{}
+	check.Eq(t, d.Latency(), 1)
+	check.EqExact(t, processInRandomBlocks(d, in, 1)[1:], Derivative(in))
+
+	h := []float32{1, -2, 0.5, 0.25}
+	fir := NewFIR(h)
+	check.Eq(t, fir.Latency(), 3)
+	check.EqExact(t, processInRandomBlocks(fir, in, 1)[3:], FIRFilter(in, h))
+}
+
+func TestStreamingWarmUpStartsFromZeros(t *testing.T) {
+	check.Eq(t, processAtOnce(NewMovingAverage(2), []float32{2, 4, 6}), []float32{1, 3, 5})
+	check.Eq(t, processAtOnce(NewMovingMedian(3), []float32{2, 4, 6}), []float32{0, 2, 4})
+	check.Eq(t, processAtOnce(&Differentiator{}, []float32{2, 4, 7}), []float32{2, 2, 3})
+	check.Eq(t, processAtOnce(NewFIR([]float32{1, 1}), []float32{1, 2, 3}), []float32{1, 3, 5})
+}
+
+func TestProcessorsCanWorkInPlace(t *testing.T) {
+	a := []float32{1, 2, 3, 4}
+	c := Chain{NewMovingAverage(2), &Differentiator{}, NewFIR([]float32{1, 1})}
+	want := processAtOnce(c, a)
+	c.Reset()
+	c.Process(a, a)
+	check.EqExact(t, a, want)
+}
+
+func TestChainSumsLatencies(t *testing.T) {
+	check.Eq(t, Chain{}.Latency(), 0)
+	c := Chain{NewMovingAverage(3), &Differentiator{}, NewFIR([]float32{1, 2, 3})}
+	check.Eq(t, c.Latency(), 2+1+2)
+
+	in := randomFloats(500, 7)
+	out := processInRandomBlocks(c, in, 2)
+	want := FIRFilter(Derivative(AverageFilter(in, 3)), []float32{1, 2, 3})
+	check.EqEps(t, out[c.Latency():], want, 1e-5)
+}
+
+func TestEmptyChainCopiesInput(t *testing.T) {
+	check.Eq(t, processAtOnce(Chain{}, []float32{1, 2}), []float32{1, 2})
+}
+
+func TestFIRFilter(t *testing.T) {
+	check.Eq(t, FIRFilter([]float32{1, 2, 3}, nil), []float32{1, 2, 3})
+	check.Eq(t, FIRFilter([]float32{1, 2, 3}, []float32{1, 2, 3, 4}), nil)
+	check.Eq(t, FIRFilter([]float32{1, 2, 3}, []float32{2}), []float32{2, 4, 6})
+	check.Eq(t, FIRFilter([]float32{1, 2, 4}, []float32{1, -1}), []float32{1, 2})
+	check.Eq(t, FIRFilter([]float32{1, 0, 0, 0}, []float32{1, 2, 3}), []float32{3, 0})
+}
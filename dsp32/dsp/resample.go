@@ -0,0 +1,356 @@
+package dsp
+
+import "math"
+
+// ResampleQuality selects the lowpass filter that is used for resampling.
+// Higher qualities use longer filters, which are slower but suppress more
+// aliasing and keep more of the passband.
+type ResampleQuality int
+
+const (
+	// ResampleLow attenuates aliasing by about 60 dB and passes frequencies
+	// up to about 77% of the lower Nyquist frequency.
+	ResampleLow ResampleQuality = iota
+	// ResampleMedium attenuates aliasing by about 90 dB and passes
+	// frequencies up to about 88% of the lower Nyquist frequency.
+	ResampleMedium
+	// ResampleHigh attenuates aliasing by about 120 dB and passes
+	// frequencies up to about 94% of the lower Nyquist frequency.
+	ResampleHigh
+)
+
+// resampleKernel is a Kaiser windowed sinc lowpass.
+type resampleKernel struct {
+	// zeroCrossings is the number of sinc zero crossings on each side.
+	zeroCrossings int
+	beta          float64
+	// rolloff is the cutoff frequency relative to the Nyquist frequency.
+	rolloff float64
+}
+
+func newResampleKernel(q ResampleQuality) resampleKernel {
+	attenuation, zeroCrossings := 60.0, 8
+	switch q {
+	case ResampleMedium:
+		attenuation, zeroCrossings = 90, 24
+	case ResampleHigh:
+		attenuation, zeroCrossings = 120, 64
+	}
+	// Kaiser's formulas give the window shape for the attenuation and the
+	// width of the transition band for the filter length. The cutoff is put
+	// below Nyquist so that the stop band starts at Nyquist.
+	transition := (attenuation - 7.95) / (14.36 * 2 * float64(zeroCrossings))
+	return resampleKernel{
+		zeroCrossings: zeroCrossings,
+		beta:          kaiserBeta(attenuation),
+		rolloff:       1 - transition,
+	}
+}
+
+// eval returns the kernel at u, measured in zero crossings of the sinc.
+func (k resampleKernel) eval(u float64) float64 {
+	return kaiserSinc(u, float64(k.zeroCrossings), k.beta)
+}
+
+// kaiserBeta returns the shape parameter of a Kaiser window for a lowpass
+// filter with the given stop band attenuation in dB, after Kaiser's formula.
+func kaiserBeta(attenuation float64) float64 {
+	switch {
+	case attenuation > 50:
+		return 0.1102 * (attenuation - 8.7)
+	case attenuation >= 21:
+		a := attenuation - 21
+		return 0.5842*math.Pow(a, 0.4) + 0.07886*a
+	default:
+		return 0
+	}
+}
+
+// kaiserSinc returns sin(pi*u)/(pi*u) windowed by a Kaiser window with shape
+// beta that is 0 from -halfWidth and below and from halfWidth and above.
+func kaiserSinc(u, halfWidth, beta float64) float64 {
+	if u <= -halfWidth || u >= halfWidth {
+		return 0
+	}
+	sinc := 1.0
+	if u != 0 {
+		sinc = math.Sin(math.Pi*u) / (math.Pi * u)
+	}
+	return sinc * kaiserWindow(u/halfWidth, beta)
+}
+
+// kaiserWindow returns the Kaiser window with shape beta at x, which runs from
+// -1 to 1 over the window.
+func kaiserWindow(x, beta float64) float64 {
+	return besselI0(beta*math.Sqrt(1-x*x)) / besselI0(beta)
+}
+
+// besselI0 is the modified Bessel function of the first kind of order 0.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > sum*1e-17; k++ {
+		f := x / (2 * float64(k))
+		term *= f * f
+		sum += term
+	}
+	return sum
+}
+
+// maxResamplePhases is the largest upsampling factor for which Resampler
+// stores one filter per phase.
+const maxResamplePhases = 1024
+
+// minResampleRatio is the smallest ratio for which the lowpass filter is
+// designed. Smaller ratios use the filter of this ratio so that the number of
+// taps stays bounded, the filter then passes frequencies above the new Nyquist
+// frequency.
+const minResampleRatio = 1.0 / 1024
+
+// resampleTableSize is the number of kernel values per zero crossing that are
+// stored for arbitrary ratios, values in between are interpolated linearly.
+const resampleTableSize = 1024
+
+// Resampler converts the sample rate of a signal that arrives in blocks.
+// Resampling by a rational factor up/down uses a polyphase filter bank,
+// arbitrary ratios use bandlimited sinc interpolation.
+//
+// Output sample m is the input interpolated at time m*down/up, or m/ratio, so
+// the output is not delayed relative to the input. To compute it, the input
+// up to a few samples after that time is needed, which is why Process holds
+// back the last outputs until more input arrives or Flush is called.
+type Resampler struct {
+	up, down int64
+	ratio    float64
+	// cutoff is the cutoff frequency relative to the input Nyquist
+	// frequency.
+	cutoff float64
+	kernel resampleKernel
+	// halfTaps is the number of input samples on each side of the output
+	// time that contribute to an output sample.
+	halfTaps int
+	// phases holds the filter for each output time i + p/up, where phases[p]
+	// is applied to the input samples i-halfTaps+1 through i+halfTaps.
+	phases [][]float32
+	// table holds the kernel at resampleTableSize points per zero crossing
+	// for arbitrary ratios.
+	table []float64
+	taps  []float32
+	// history holds the input samples starting at index base. Samples before
+	// the start of the input are zero.
+	history []float32
+	base    int64
+	inCount int64
+	// outCount is the number of output samples produced so far.
+	outCount int64
+}
+
+// NewResampler returns a Resampler that changes the sample rate by the
+// rational factor up/down, e.g. up=160 and down=147 converts 44100 Hz to 48000
+// Hz. Factors less than 1 are treated as 1. If up equals down, the signal is
+// passed through unchanged. Downsampling by more than 1024 uses the lowpass
+// filter for 1024, which lets some aliasing through, see NewRatioResampler. If up is larger than 1024 after reducing the
+// fraction, the Resampler uses sinc interpolation like NewRatioResampler.
+func NewResampler(up, down int, q ResampleQuality) *Resampler {
+	if up < 1 {
+		up = 1
+	}
+	if down < 1 {
+		down = 1
+	}
+	g := gcd(up, down)
+	up, down = up/g, down/g
+	if up > maxResamplePhases {
+		return NewRatioResampler(float64(up)/float64(down), q)
+	}
+
+	r := newResampler(float64(up)/float64(down), q)
+	r.up, r.down = int64(up), int64(down)
+	if up == 1 && down == 1 {
+		// Leave the signal unchanged instead of lowpass filtering it.
+		r.halfTaps = 1
+		r.phases = [][]float32{{1, 0}}
+		r.Reset()
+		return r
+	}
+	r.phases = make([][]float32, up)
+	for p := range r.phases {
+		frac := float64(p) / float64(up)
+		taps := make([]float32, 2*r.halfTaps)
+		var sum float64
+		for j := range taps {
+			sum += r.weight(frac + float64(r.halfTaps-1-j))
+		}
+		// Normalize each phase to unit gain at DC.
+		for j := range taps {
+			taps[j] = float32(r.weight(frac+float64(r.halfTaps-1-j)) / sum)
+		}
+		r.phases[p] = taps
+	}
+	return r
+}
+
+// NewRatioResampler returns a Resampler that multiplies the sample rate by
+// ratio, which can be any positive number. A ratio that is not positive is
+// treated as 1. Ratios below 1/1024 use the lowpass filter for 1/1024, which
+// lets some aliasing through but keeps the filter length bounded.
+func NewRatioResampler(ratio float64, q ResampleQuality) *Resampler {
+	if !(ratio > 0) || math.IsInf(ratio, 0) {
+		ratio = 1
+	}
+	r := newResampler(ratio, q)
+	z := r.kernel.zeroCrossings
+	r.table = make([]float64, z*resampleTableSize+2)
+	for i := range r.table {
+		r.table[i] = r.kernel.eval(float64(i) / resampleTableSize)
+	}
+	r.taps = make([]float32, 2*r.halfTaps)
+	return r
+}
+
+func newResampler(ratio float64, q ResampleQuality) *Resampler {
+	k := newResampleKernel(q)
+	cutoff := k.rolloff * math.Min(1, math.Max(ratio, minResampleRatio))
+	r := &Resampler{
+		ratio:    ratio,
+		cutoff:   cutoff,
+		kernel:   k,
+		halfTaps: int(math.Ceil(float64(k.zeroCrossings) / cutoff)),
+	}
+	r.Reset()
+	return r
+}
+
+// weight returns the filter value for an input sample that is d samples
+// before the output time.
+func (r *Resampler) weight(d float64) float64 {
+	return r.cutoff * r.kernel.eval(r.cutoff*d)
+}
+
+// tableWeight is like weight but uses the interpolated kernel table.
+func (r *Resampler) tableWeight(d float64) float64 {
+	u := math.Abs(r.cutoff*d) * resampleTableSize
+	i := int(u)
+	if i >= len(r.table)-1 {
+		return 0
+	}
+	f := u - float64(i)
+	return r.cutoff * (r.table[i] + f*(r.table[i+1]-r.table[i]))
+}
+
+// Ratio returns the factor by which the sample rate is changed.
+func (r *Resampler) Ratio() float64 {
+	return r.ratio
+}
+
+// Reset clears the input history so that the next call to Process starts a
+// new signal.
+func (r *Resampler) Reset() {
+	r.history = make([]float32, r.halfTaps, 4*r.halfTaps)
+	r.base = -int64(r.halfTaps)
+	r.inCount = 0
+	r.outCount = 0
+}
+
+// Process appends in to the input and returns all output samples that can be
+// computed from it so far.
+func (r *Resampler) Process(in []float32) []float32 {
+	r.history = append(r.history, in...)
+	r.inCount += int64(len(in))
+	return r.produce(r.inCount, false)
+}
+
+// Flush returns the remaining output samples, assuming that the input is
+// followed by silence, and resets the Resampler. Altogether, the output has
+// ceil(n*ratio) samples for n input samples.
+func (r *Resampler) Flush() []float32 {
+	total := int64(math.Ceil(float64(r.inCount) * r.ratio))
+	if r.up > 0 {
+		total = (r.inCount*r.up + r.down - 1) / r.down
+	}
+	// Pretend that enough zeros follow the input to compute all outputs.
+	r.history = append(r.history, make([]float32, 2*r.halfTaps+1)...)
+	out := r.produce(r.inCount+int64(2*r.halfTaps+1), true)
+	extra := r.outCount - total
+	if extra > 0 {
+		out = out[:int64(len(out))-extra]
+	}
+	r.Reset()
+	return out
+}
+
+// produce computes outputs until one would need input at or after index end.
+func (r *Resampler) produce(end int64, flushing bool) []float32 {
+	var out []float32
+	for {
+		i, frac, phase := r.position(r.outCount)
+		if i+int64(r.halfTaps) >= end {
+			break
+		}
+		if flushing && float64(i)+frac >= float64(r.inCount) {
+			break
+		}
+		first := i - int64(r.halfTaps) + 1 - r.base
+		x := r.history[first : first+int64(2*r.halfTaps)]
+		var taps []float32
+		if r.phases != nil {
+			taps = r.phases[phase]
+		} else {
+			taps = r.taps
+			for j := range taps {
+				taps[j] = float32(r.tableWeight(frac + float64(r.halfTaps-1-j)))
+			}
+		}
+		var sum float32
+		for j, h := range taps {
+			sum += h * x[j]
+		}
+		out = append(out, sum)
+		r.outCount++
+	}
+
+	// Drop the input that is no longer needed.
+	i, _, _ := r.position(r.outCount)
+	drop := i - int64(r.halfTaps) + 1 - r.base
+	if drop > int64(len(r.history)) {
+		drop = int64(len(r.history))
+	}
+	if drop > int64(len(r.history)/2) {
+		r.history = append(r.history[:0], r.history[drop:]...)
+		r.base += drop
+	}
+	return out
+}
+
+// position returns the input time of output sample m as an integer index i and
+// a fraction in [0,1). For rational ratios it also returns the phase.
+func (r *Resampler) position(m int64) (i int64, frac float64, phase int) {
+	if r.up > 0 {
+		n := m * r.down
+		return n / r.up, float64(n%r.up) / float64(r.up), int(n % r.up)
+	}
+	t := float64(m) / r.ratio
+	f := math.Floor(t)
+	return int64(f), t - f, 0
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// Resample changes the sample rate of a by the rational factor up/down. The
+// result has ceil(len(a)*up/down) samples. See NewResampler.
+func Resample(a []float32, up, down int, q ResampleQuality) []float32 {
+	r := NewResampler(up, down, q)
+	return append(r.Process(a), r.Flush()...)
+}
+
+// ResampleRatio changes the sample rate of a by an arbitrary ratio, e.g.
+// 48000.0/44100 to convert from 44.1 kHz to 48 kHz. The result has
+// ceil(len(a)*ratio) samples. See NewRatioResampler.
+func ResampleRatio(a []float32, ratio float64, q ResampleQuality) []float32 {
+	r := NewRatioResampler(ratio, q)
+	return append(r.Process(a), r.Flush()...)
+}
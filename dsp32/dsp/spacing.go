@@ -0,0 +1,177 @@
+package dsp
+
+import "math"
+
+// Linspace returns n evenly spaced values from start to stop. If endpoint is
+// true, the last value is exactly stop, otherwise the values end one step
+// before stop, which divides [start, stop) into n equal parts. n less than 1
+// returns an empty slice, n = 1 returns start.
+//
+// Examples:
+//
+//	Linspace(0, 1, 5, true)  =>  {0.0, 0.25, 0.5, 0.75, 1.0}
+//	Linspace(0, 1, 4, false) =>  {0.0, 0.25, 0.5, 0.75}
+func Linspace(start, stop float32, n int, endpoint bool) []float32 {
+	return tofloat32(linspace(float64(start), float64(stop), n, endpoint))
+}
+
+func linspace(start, stop float64, n int, endpoint bool) []float64 {
+	if n < 1 {
+		return []float64{}
+	}
+	a := make([]float64, n)
+	parts := n
+	if endpoint {
+		parts = n - 1
+	}
+	step := 0.0
+	if parts > 0 {
+		step = (stop - start) / float64(parts)
+	}
+	for i := range a {
+		a[i] = start + float64(i)*step
+	}
+	if endpoint && n > 1 {
+		a[n-1] = stop
+	}
+	return a
+}
+
+// Logspace returns n values that are evenly spaced on a logarithmic scale from
+// 10^start to 10^stop. The endpoint works like in Linspace.
+//
+// Example:
+//
+//	Logspace(0, 3, 4, true)  =>  {1.0, 10.0, 100.0, 1000.0}
+func Logspace(start, stop float32, n int, endpoint bool) []float32 {
+	a := linspace(float64(start), float64(stop), n, endpoint)
+	for i := range a {
+		a[i] = math.Pow(10, a[i])
+	}
+	return tofloat32(a)
+}
+
+// Geomspace returns n values from start to stop, where each value is the
+// previous one times a constant factor. The endpoint works like in Linspace.
+// start and stop must both be positive or both be negative, otherwise the
+// result is empty.
+//
+// Example:
+//
+//	Geomspace(1, 1000, 4, true)  =>  {1.0, 10.0, 100.0, 1000.0}
+func Geomspace(start, stop float32, n int, endpoint bool) []float32 {
+	// Compare the signs, start*stop can underflow to 0 for tiny values.
+	if !((start > 0) == (stop > 0) && start != 0 && stop != 0) {
+		return []float32{}
+	}
+	sign := 1.0
+	if start < 0 {
+		sign = -1
+	}
+	first, last := math.Log(sign*float64(start)), math.Log(sign*float64(stop))
+	a := linspace(first, last, n, endpoint)
+	for i := range a {
+		a[i] = sign * math.Exp(a[i])
+	}
+	// Make the ends exact.
+	if n > 0 {
+		a[0] = float64(start)
+	}
+	if endpoint && n > 1 {
+		a[n-1] = float64(stop)
+	}
+	return tofloat32(a)
+}
+
+// Arange returns the values start, start+step, start+2*step, ... that lie
+// before stop, stop itself is never included. Values that lie within a
+// rounding error of stop count as stop, e.g. Arange(1, 1.3, 0.1) has the 3
+// values 1.0, 1.1 and 1.2. If step is 0 or points away from stop, the result
+// is empty.
+//
+// Examples:
+//
+//	Arange(0, 1, 0.25)  =>  {0.0, 0.25, 0.5, 0.75}
+//	Arange(3, 0, -1)    =>  {3.0, 2.0, 1.0}
+func Arange(start, stop, step float32) []float32 {
+	steps := (float64(stop) - float64(start)) / float64(step)
+	if !(steps > 0) || math.IsInf(steps, 0) {
+		return []float32{}
+	}
+	// The operands are rounded to float32, so the rounding error of steps
+	// depends on their magnitude relative to step.
+	magnitude := (math.Abs(float64(start)) + math.Abs(float64(stop))) / math.Abs(float64(step))
+	n := int(math.Ceil(steps - 4*floatEpsilon*(steps+magnitude)))
+	a := make([]float32, n)
+	for i := range a {
+		a[i] = float32(float64(start) + float64(i)*float64(step))
+	}
+	return a
+}
+
+// floatEpsilon is the difference between 1 and the next larger float32.
+var floatEpsilon = func() float64 {
+	e := float32(1)
+	for float32(1+e/2) > 1 {
+		e /= 2
+	}
+	return float64(e)
+}()
+
+// TimeAxis returns the times in seconds of n samples at the given sample rate,
+// starting at 0.
+//
+// Example:
+//
+//	TimeAxis(4, 2)  =>  {0.0, 0.5, 1.0, 1.5}
+func TimeAxis(n int, sampleRate float32) []float32 {
+	if n < 0 {
+		n = 0
+	}
+	a := make([]float32, n)
+	for i := range a {
+		a[i] = float32(float64(i) / float64(sampleRate))
+	}
+	return a
+}
+
+// FFTFreq returns the frequency in Hz of each bin of an FFT of n samples at
+// the given sample rate. The positive frequencies come first, followed by the
+// negative ones.
+//
+// Examples:
+//
+//	FFTFreq(4, 8)  =>  {0.0, 2.0, -4.0, -2.0}
+//	FFTFreq(5, 5)  =>  {0.0, 1.0, 2.0, -2.0, -1.0}
+func FFTFreq(n int, sampleRate float32) []float32 {
+	if n < 0 {
+		n = 0
+	}
+	a := make([]float32, n)
+	for i := range a {
+		k := i
+		if i > (n-1)/2 {
+			k = i - n
+		}
+		a[i] = float32(float64(k) * float64(sampleRate) / float64(n))
+	}
+	return a
+}
+
+// RFFTFreq returns the frequency in Hz of each bin of a real-valued FFT of n
+// samples at the given sample rate, which are the n/2+1 non-negative
+// frequencies.
+//
+// Example:
+//
+//	RFFTFreq(4, 8)  =>  {0.0, 2.0, 4.0}
+func RFFTFreq(n int, sampleRate float32) []float32 {
+	if n < 1 {
+		return []float32{}
+	}
+	a := make([]float32, n/2+1)
+	for i := range a {
+		a[i] = float32(float64(i) * float64(sampleRate) / float64(n))
+	}
+	return a
+}
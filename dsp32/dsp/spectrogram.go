@@ -0,0 +1,53 @@
+package dsp
+
+import "math"
+
+// Spectrogram returns the power spectrogram of a, the squared magnitudes of
+// the short time Fourier transform with a Hann window of frameLength samples.
+// Frame i is centered at sample i*hop, a is padded with zeros at both ends.
+// Each frame holds frameLength/2+1 bins for the frequencies from 0 Hz to the
+// Nyquist frequency, see RFFTFreq. frameLength is at least 2 and hop at least
+// 1.
+func Spectrogram(a []float32, frameLength, hop int) [][]float32 {
+	if frameLength < 2 {
+		frameLength = 2
+	}
+	if hop < 1 {
+		hop = 1
+	}
+	frames := stft(a, frameLength, hop, true)
+	s := make([][]float32, len(frames))
+	for n, x := range frames {
+		s[n] = make([]float32, len(x))
+		for k, v := range x {
+			s[n][k] = float32(real(v)*real(v) + imag(v)*imag(v))
+		}
+	}
+	return s
+}
+
+// stft returns the positive frequency bins of the Hann windowed spectra of a.
+// Frame i is centered at sample i*hop, a is padded with zeros at both ends.
+// If spectra is false, the windowed frames are returned instead.
+func stft(a []float32, frameLength, hop int, spectra bool) [][]complex128 {
+	window := make([]float64, frameLength)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(frameLength))
+	}
+	frames := make([][]complex128, 1+len(a)/hop)
+	for n := range frames {
+		x := make([]complex128, frameLength)
+		start := n*hop - frameLength/2
+		for i := range x {
+			if j := start + i; j >= 0 && j < len(a) {
+				x[i] = complex(float64(a[j])*window[i], 0)
+			}
+		}
+		if spectra {
+			fft(x, false)
+			x = x[:frameLength/2+1]
+		}
+		frames[n] = x
+	}
+	return frames
+}
@@ -0,0 +1,163 @@
+package dsp
+
+// Channels is a multi-channel signal with one slice of samples per channel.
+// All channels should have the same length.
+type Channels [][]float64
+
+// NewChannels returns channelCount channels of length zeros each. A
+// non-positive channelCount returns no channels.
+func NewChannels(channelCount, length int) Channels {
+	if channelCount <= 0 {
+		return nil
+	}
+	length = max0(length)
+	c := make(Channels, channelCount)
+	for i := range c {
+		c[i] = make([]float64, length)
+	}
+	return c
+}
+
+// Deinterleave splits interleaved frames, like L R L R ..., into channels. An
+// incomplete frame at the end of a is ignored. A non-positive channelCount
+// returns no channels.
+func Deinterleave(a []float64, channelCount int) Channels {
+	if channelCount <= 0 {
+		return nil
+	}
+	c := NewChannels(channelCount, len(a)/channelCount)
+	for i := range c {
+		for j := range c[i] {
+			c[i][j] = a[j*channelCount+i]
+		}
+	}
+	return c
+}
+
+// Interleave returns the samples of all channels as frames, like L R L R ....
+// If the channels have different lengths, the shortest one is used.
+func Interleave(c Channels) []float64 {
+	n := c.Len()
+	a := make([]float64, n*len(c))
+	for i := range c {
+		for j := 0; j < n; j++ {
+			a[j*len(c)+i] = c[i][j]
+		}
+	}
+	return a
+}
+
+// Len returns the length of the shortest channel or 0 if there are no
+// channels.
+func (c Channels) Len() int {
+	if len(c) == 0 {
+		return 0
+	}
+	n := len(c[0])
+	for _, x := range c {
+		if len(x) < n {
+			n = len(x)
+		}
+	}
+	return n
+}
+
+// Copy returns a copy of all channels.
+func (c Channels) Copy() Channels {
+	return c.Map(Copy)
+}
+
+// Map applies f to every channel and returns the results, e.g.
+//
+//	c.Map(func(a []float64) []float64 { return AverageFilter(a, 5) })
+func (c Channels) Map(f func([]float64) []float64) Channels {
+	m := make(Channels, len(c))
+	for i := range c {
+		m[i] = f(c[i])
+	}
+	return m
+}
+
+// Select returns the channels with the given indices in the given order. The
+// slices are not copied. Channels can be repeated or left out, e.g. Select(1,
+// 0) swaps the channels of a stereo signal. Indices that are negative or not
+// less than len(c) are skipped.
+func (c Channels) Select(indices ...int) Channels {
+	s := make(Channels, 0, len(indices))
+	for _, index := range indices {
+		if 0 <= index && index < len(c) {
+			s = append(s, c[index])
+		}
+	}
+	return s
+}
+
+// Mix returns channels that are linear combinations of c. The result has one
+// channel per row of matrix, row i holds the factors of all channels in c for
+// output channel i, so channel i is the sum of matrix[i][j] * c[j] over all j.
+// Factors beyond the last channel of c are ignored.
+func (c Channels) Mix(matrix [][]float64) Channels {
+	n := c.Len()
+	out := NewChannels(len(matrix), n)
+	for i, row := range matrix {
+		for j, factor := range row {
+			if factor == 0 || j >= len(c) {
+				continue
+			}
+			for k := 0; k < n; k++ {
+				out[i][k] += factor * c[j][k]
+			}
+		}
+	}
+	return out
+}
+
+// DownmixMatrix returns the matrix for Mix that averages channelCount
+// channels into one.
+func DownmixMatrix(channelCount int) [][]float64 {
+	return [][]float64{Repeat(1/float64(channelCount), channelCount)}
+}
+
+// UpmixMatrix returns the matrix for Mix that copies one channel into
+// channelCount channels.
+func UpmixMatrix(channelCount int) [][]float64 {
+	m := make([][]float64, channelCount)
+	for i := range m {
+		m[i] = []float64{1}
+	}
+	return m
+}
+
+// MidSideEncodeMatrix returns the matrix for Mix that converts left and right
+// channels to mid and side channels, mid = (L+R)/2 and side = (L-R)/2.
+func MidSideEncodeMatrix() [][]float64 {
+	return [][]float64{{0.5, 0.5}, {0.5, -0.5}}
+}
+
+// MidSideDecodeMatrix returns the matrix for Mix that converts mid and side
+// channels back to left and right channels, L = mid+side and R = mid-side.
+func MidSideDecodeMatrix() [][]float64 {
+	return [][]float64{{1, 1}, {1, -1}}
+}
+
+// Downmix returns the average of all channels.
+func Downmix(c Channels) []float64 {
+	return c.Mix(DownmixMatrix(len(c)))[0]
+}
+
+// Upmix returns channelCount copies of a.
+func Upmix(a []float64, channelCount int) Channels {
+	return Channels{a}.Mix(UpmixMatrix(channelCount))
+}
+
+// MidSideEncode converts a stereo signal to mid and side signals.
+func MidSideEncode(left, right []float64) (mid, side []float64) {
+	ms := Channels{left, right}.Mix(MidSideEncodeMatrix())
+	return ms[0], ms[1]
+}
+
+// MidSideDecode converts mid and side signals back to a stereo signal.
+func MidSideDecode(mid, side []float64) (left, right []float64) {
+	lr := Channels{mid, side}.Mix(MidSideDecodeMatrix())
+	return lr[0], lr[1]
+}
@@ -0,0 +1,261 @@
+package dsp
+
+import "math"
+
+// CopyComplex returns a copy of the given slice.
+func CopyComplex(a []complex128) []complex128 {
+	c := make([]complex128, len(a))
+	copy(c, a)
+	return c
+}
+
+// AddComplex returns an array of the sums of the elements in all arrays of a.
+// If the arrays in a have different lengths, the smallest of all lengths is
+// used for the result.
+func AddComplex(a ...[]complex128) []complex128 {
+	if len(a) == 0 {
+		return nil
+	}
+	sum := make([]complex128, shortestComplex(a))
+	for i := range sum {
+		for j := range a {
+			sum[i] += a[j][i]
+		}
+	}
+	return sum
+}
+
+// SubComplex uses the first array in a as the base and subtracts all other
+// arrays from it. If the arrays in a have different lengths, the smallest of
+// all lengths is used for the result.
+func SubComplex(a ...[]complex128) []complex128 {
+	if len(a) == 0 {
+		return nil
+	}
+	diff := make([]complex128, shortestComplex(a))
+	copy(diff, a[0])
+	for i := range diff {
+		for j := 1; j < len(a); j++ {
+			diff[i] -= a[j][i]
+		}
+	}
+	return diff
+}
+
+// MulComplex returns an array of the products of the elements in all arrays of
+// a. If the arrays in a have different lengths, the smallest of all lengths is
+// used for the result.
+func MulComplex(a ...[]complex128) []complex128 {
+	if len(a) == 0 {
+		return nil
+	}
+	product := make([]complex128, shortestComplex(a))
+	for i := range product {
+		product[i] = 1
+		for j := range a {
+			product[i] *= a[j][i]
+		}
+	}
+	return product
+}
+
+// MulConj returns an array of a[i] * conj(b[i]). If a and b have different
+// lengths, the shorter one is used.
+func MulConj(a, b []complex128) []complex128 {
+	c := make([]complex128, shortestComplex([][]complex128{a, b}))
+	for i := range c {
+		c[i] = a[i] * conj(b[i])
+	}
+	return c
+}
+
+// MulAccumulate adds a[i] * b[i] to acc[i] for all i in acc. a and b must be
+// at least as long as acc.
+func MulAccumulate(acc, a, b []complex128) {
+	for i := range acc {
+		acc[i] += a[i] * b[i]
+	}
+}
+
+// DotComplex returns the inner product of a and b, the sum of a[i] *
+// conj(b[i]). If a and b have different lengths, the shorter one is used.
+func DotComplex(a, b []complex128) complex128 {
+	n := shortestComplex([][]complex128{a, b})
+	var sum complex128
+	for i := 0; i < n; i++ {
+		sum += a[i] * conj(b[i])
+	}
+	return sum
+}
+
+func shortestComplex(a [][]complex128) int {
+	n := len(a[0])
+	for _, v := range a {
+		if len(v) < n {
+			n = len(v)
+		}
+	}
+	return n
+}
+
+// ScaleComplex returns a new array with all values in a scaled by factor.
+func ScaleComplex(a []complex128, factor complex128) []complex128 {
+	b := make([]complex128, len(a))
+	for i := range b {
+		b[i] = a[i] * factor
+	}
+	return b
+}
+
+// ReverseComplex returns a copy of x with elements in reverse order.
+func ReverseComplex(x []complex128) []complex128 {
+	y := make([]complex128, len(x))
+	for i := range y {
+		y[i] = x[len(x)-1-i]
+	}
+	return y
+}
+
+// AverageComplex returns the average value over a or 0 if a is empty.
+func AverageComplex(a []complex128) complex128 {
+	if len(a) == 0 {
+		return 0
+	}
+	var sum complex128
+	for _, v := range a {
+		sum += v
+	}
+	return sum / complex128(complex(float64(len(a)), 0))
+}
+
+// AverageFilterComplex is the complex version of AverageFilter.
+func AverageFilterComplex(a []complex128, width int) []complex128 {
+	if width >= len(a) {
+		width = len(a)
+	}
+	if width <= 1 {
+		return CopyComplex(a)
+	}
+
+	b := make([]complex128, len(a)-(width-1))
+	f := 1 / complex128(complex(float64(width), 0))
+	block := averageFilterBlock(width)
+	var slidingSum complex128
+	for i := range b {
+		if i%block == 0 {
+			slidingSum = 0
+			for j := 0; j < width; j++ {
+				slidingSum += a[i+j]
+			}
+		} else {
+			slidingSum += a[i+width-1] - a[i-1]
+		}
+		b[i] = slidingSum * f
+	}
+	return b
+}
+
+// DerivativeComplex returns a slice one item smaller than a, with the
+// differences between neighboring items. Result 0 is a[1]-a[0] and so on.
+func DerivativeComplex(a []complex128) []complex128 {
+	if len(a) <= 1 {
+		return make([]complex128, len(a))
+	}
+	b := make([]complex128, len(a)-1)
+	for i := range b {
+		b[i] = a[i+1] - a[i]
+	}
+	return b
+}
+
+// Conj returns the complex conjugates of all values in a.
+func Conj(a []complex128) []complex128 {
+	b := make([]complex128, len(a))
+	for i := range b {
+		b[i] = conj(a[i])
+	}
+	return b
+}
+
+func conj(x complex128) complex128 {
+	return complex128(complex(real(x), -imag(x)))
+}
+
+// Complex combines real and imaginary parts, e.g. I and Q signals, into
+// complex values. If re and im have different lengths, the shorter one is
+// used.
+func Complex(re, im []float64) []complex128 {
+	n := len(re)
+	if len(im) < n {
+		n = len(im)
+	}
+	c := make([]complex128, n)
+	for i := range c {
+		c[i] = complex128(complex(re[i], im[i]))
+	}
+	return c
+}
+
+// RealImag splits complex values into their real and imaginary parts.
+func RealImag(a []complex128) (re, im []float64) {
+	re = make([]float64, len(a))
+	im = make([]float64, len(a))
+	for i, x := range a {
+		re[i], im[i] = real(x), imag(x)
+	}
+	return
+}
+
+// Real returns the real parts of all values in a.
+func Real(a []complex128) []float64 {
+	re, _ := RealImag(a)
+	return re
+}
+
+// Imag returns the imaginary parts of all values in a.
+func Imag(a []complex128) []float64 {
+	_, im := RealImag(a)
+	return im
+}
+
+// FromPolar returns the complex values magnitude[i] * exp(i*phase[i]), with
+// the phases in radians. If magnitude and phase have different lengths, the
+// shorter one is used.
+func FromPolar(magnitude, phase []float64) []complex128 {
+	n := len(magnitude)
+	if len(phase) < n {
+		n = len(phase)
+	}
+	c := make([]complex128, n)
+	for i := range c {
+		s, cos := math.Sincos(float64(phase[i]))
+		m := float64(magnitude[i])
+		c[i] = complex128(complex(m*cos, m*s))
+	}
+	return c
+}
+
+// ToPolar returns the magnitudes and phases of all values in a, see Magnitude
+// and Angle.
+func ToPolar(a []complex128) (magnitude, phase []float64) {
+	return Magnitude(a), Angle(a)
+}
+
+// Magnitude returns the absolute values of all values in a.
+func Magnitude(a []complex128) []float64 {
+	m := make([]float64, len(a))
+	for i, x := range a {
+		m[i] = float64(math.Hypot(float64(real(x)), float64(imag(x))))
+	}
+	return m
+}
+
+// Angle returns the phases of all values in a in radians, in the range -pi to
+// pi.
+func Angle(a []complex128) []float64 {
+	p := make([]float64, len(a))
+	for i, x := range a {
+		p[i] = float64(math.Atan2(float64(imag(x)), float64(real(x))))
+	}
+	return p
+}
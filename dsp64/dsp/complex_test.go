@@ -0,0 +1,75 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestComplexArithmeticUsesShortestLength(t *testing.T) {
+	a := []complex128{1 + 2i, 3 - 1i, 5}
+	b := []complex128{1i, 2}
+	check.Eq(t, AddComplex(a, b), []complex128{1 + 3i, 5 - 1i})
+	check.Eq(t, SubComplex(a, b), []complex128{1 + 1i, 1 - 1i})
+	check.Eq(t, MulComplex(a, b), []complex128{-2 + 1i, 6 - 2i})
+	check.Eq(t, MulConj(a, b), []complex128{2 - 1i, 6 - 2i})
+	check.Eq(t, DotComplex(a, b), complex128(8-3i))
+	check.Eq(t, len(AddComplex()), 0)
+	check.Eq(t, len(MulComplex()), 0)
+}
+
+func TestMulAccumulateAddsProducts(t *testing.T) {
+	acc := []complex128{1, 1i}
+	MulAccumulate(acc, []complex128{1i, 2}, []complex128{1i, 1 + 1i})
+	check.Eq(t, acc, []complex128{0, 2 + 3i})
+}
+
+func TestComplexCopyScaleReverse(t *testing.T) {
+	a := []complex128{1, 2i, 3}
+	c := CopyComplex(a)
+	c[0] = 5
+	check.Eq(t, a[0], complex128(1))
+	check.Eq(t, ScaleComplex(a, 1i), []complex128{1i, -2, 3i})
+	check.Eq(t, ReverseComplex(a), []complex128{3, 2i, 1})
+	check.Eq(t, Conj(a), []complex128{1, -2i, 3})
+}
+
+func TestComplexAverageAndDerivative(t *testing.T) {
+	a := []complex128{1, 1i, 3, 2 - 1i}
+	check.Eq(t, AverageComplex(a), complex128(1.5))
+	check.Eq(t, AverageComplex(nil), complex128(0))
+	check.Eq(t, AverageFilterComplex(a, 2), []complex128{0.5 + 0.5i, 1.5 + 0.5i, 2.5 - 0.5i})
+	check.Eq(t, AverageFilterComplex(a, 10), []complex128{1.5})
+	check.Eq(t, AverageFilterComplex(a, 1), a)
+	check.Eq(t, DerivativeComplex(a), []complex128{-1 + 1i, 3 - 1i, -1 - 1i})
+	check.Eq(t, DerivativeComplex([]complex128{1}), []complex128{0})
+}
+
+func TestComplexAverageFilterMatchesRealParts(t *testing.T) {
+	re := randomFloats(10000, 1)
+	im := randomFloats(10000, 2)
+	filtered := AverageFilterComplex(Complex(re, im), 7)
+	check.Eq(t, Real(filtered), AverageFilter(re, 7))
+	check.Eq(t, Imag(filtered), AverageFilter(im, 7))
+}
+
+func TestComplexConversions(t *testing.T) {
+	c := Complex([]float64{3, 0, -1}, []float64{4, 2})
+	check.Eq(t, c, []complex128{3 + 4i, 2i})
+	re, im := RealImag(c)
+	check.Eq(t, re, []float64{3, 0})
+	check.Eq(t, im, []float64{4, 2})
+
+	magnitude, phase := ToPolar([]complex128{3 + 4i, -2, -1i})
+	check.Eq(t, magnitude, []float64{5, 2, 1})
+	check.EqEps(t, phase[0], math.Atan2(4, 3), 1e-6)
+	check.EqEps(t, phase[1], math.Pi, 1e-6)
+	check.EqEps(t, phase[2], -math.Pi/2, 1e-6)
+
+	p := FromPolar(magnitude, phase)
+	check.EqEps(t, real(p[0]), 3, 1e-5)
+	check.EqEps(t, imag(p[0]), 4, 1e-5)
+	check.EqEps(t, real(p[1]), -2, 1e-5)
+	check.EqEps(t, imag(p[2]), -1, 1e-5)
+}
@@ -0,0 +1,70 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func dft(a []complex128) []complex128 {
+	n := len(a)
+	x := make([]complex128, n)
+	for k := range x {
+		for i, v := range a {
+			x[k] += complex128(v) * cmplx.Exp(complex(0, -2*math.Pi*float64(k*i%n)/float64(n)))
+		}
+	}
+	return x
+}
+
+func TestFFTMatchesDFT(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 8, 12, 17, 64, 100} {
+		a := Complex(randomFloats(n, int64(n)), randomFloats(n, int64(n+1)))
+		want := dft(a)
+		got := FFT(a)
+		check.Eq(t, len(got), n)
+		for k := range got {
+			check.EqEps(t, cmplx.Abs(complex128(got[k])-want[k]), 0, 1e-4, n, k)
+		}
+		back := IFFT(got)
+		for i := range back {
+			check.EqEps(t, cmplx.Abs(complex128(back[i]-a[i])), 0, 1e-5, n, i)
+		}
+	}
+	check.Eq(t, len(FFT(nil)), 0)
+	check.Eq(t, len(IFFT(nil)), 0)
+}
+
+func TestFFTOfCosineHasTwoPeaks(t *testing.T) {
+	x := FFT(Complex(Cosine(16, 1, 3, 0, 16), make([]float64, 16)))
+	for k := range x {
+		want := 0.0
+		if k == 3 || k == 13 {
+			want = 8
+		}
+		check.EqEps(t, real(x[k]), want, 1e-5, k)
+		check.EqEps(t, imag(x[k]), 0, 1e-5, k)
+	}
+}
+
+func TestRFFTReturnsNonNegativeFrequencies(t *testing.T) {
+	for _, n := range []int{1, 2, 7, 16, 21} {
+		a := randomFloats(n, int64(n))
+		full := FFT(Complex(a, make([]float64, n)))
+		half := RFFT(a)
+		check.Eq(t, len(half), n/2+1)
+		for k := range half {
+			check.EqEps(t, cmplx.Abs(complex128(half[k]-full[k])), 0, 1e-5, n, k)
+		}
+		back := IRFFT(half, n)
+		check.Eq(t, len(back), n)
+		for i := range back {
+			check.EqEps(t, back[i], a[i], 1e-5, n, i)
+		}
+	}
+	check.Eq(t, len(RFFT(nil)), 0)
+	check.Eq(t, len(IRFFT(nil, 0)), 0)
+	check.Eq(t, IRFFT([]complex128{4}, 4), []float64{1, 1, 1, 1})
+}
@@ -0,0 +1,132 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestSineAndCosine(t *testing.T) {
+	s := Sine(100, 2, 3, 0.5, 100)
+	c := Cosine(100, 2, 3, 0.5, 100)
+	for i := range s {
+		x := 2*math.Pi*3*float64(i)/100 + 0.5
+		check.EqEps(t, float64(s[i]), 2*math.Sin(x), 1e-5, i)
+		check.EqEps(t, float64(c[i]), 2*math.Cos(x), 1e-5, i)
+	}
+}
+
+func TestOscillatorKeepsPhaseAcrossBlocks(t *testing.T) {
+	for _, w := range []Waveform{SineWave, SquareWave, SawtoothWave, TriangleWave, PulseWave} {
+		o := NewOscillator(w, 1, 441, 1, 44100)
+		whole := o.Generate(1000)
+		o.Reset()
+		var blocks []float64
+		for _, n := range []int{1, 99, 250, 0, 650} {
+			blocks = append(blocks, o.Generate(n)...)
+		}
+		check.Eq(t, blocks, whole, w)
+	}
+}
+
+func TestOscillatorFrequencyCanChangeBetweenBlocks(t *testing.T) {
+	o := NewOscillator(SineWave, 1, 1, 0, 4)
+	check.Eq(t, o.Generate(2), []float64{0, 1})
+	o.Frequency = 2
+	a := o.Generate(2)
+	check.EqEps(t, a[0], 0, 1e-6)
+	check.EqEps(t, a[1], 0, 1e-6)
+	check.EqEps(t, o.Phase(), math.Pi, 1e-6)
+}
+
+// aliasing returns the power of a in all DFT bins that are not harmonics of
+// bin k0, relative to the total power. Harmonics above the Nyquist frequency
+// fold back onto such bins.
+func aliasing(a []float64, k0 int) float64 {
+	n := len(a)
+	var alias, total float64
+	for k := 1; k < n/2; k++ {
+		var re, im float64
+		for i, x := range a {
+			re += float64(x) * math.Cos(2*math.Pi*float64(k*i)/float64(n))
+			im -= float64(x) * math.Sin(2*math.Pi*float64(k*i)/float64(n))
+		}
+		power := re*re + im*im
+		total += power
+		if k%k0 != 0 {
+			alias += power
+		}
+	}
+	return alias / total
+}
+
+func TestBandLimitedWaveformsHaveLessAliasing(t *testing.T) {
+	// 37 whole cycles in 2048 samples.
+	const n, cycles = 2048, 37
+	f := float64(cycles) / n
+	for _, w := range []Waveform{SquareWave, SawtoothWave, TriangleWave, PulseWave} {
+		o := NewOscillator(w, 1, f, 0, 1)
+		o.DutyCycle = 0.3
+		bandLimited := aliasing(o.Generate(n), cycles)
+		// A frequency of 0 turns off the corrections but the phase still
+		// advances as we set it.
+		o.Frequency = 0
+		naive := make([]float64, n)
+		for i := range naive {
+			o.phase = frac(float64(f) * float64(i))
+			o.Read(naive[i : i+1])
+		}
+		improvement := 10 * math.Log10(aliasing(naive, cycles)/bandLimited)
+		check.Eq(t, improvement > 10, true, w, improvement)
+	}
+}
+
+func TestPulseHasDutyCycle(t *testing.T) {
+	for _, duty := range []float64{0, 0.1, 0.25, 0.5, 0.8, 1} {
+		// 10 whole cycles.
+		p := Pulse(1000, 2, 1, duty, 0, 100)
+		check.EqEps(t, Average(p), 2*(2*duty-1), 1e-4, duty)
+	}
+	check.Eq(t, Pulse(4, 1, 1, 0.5, 0, 4), Square(4, 1, 1, 0, 4))
+}
+
+func TestChirpSweepsFrequency(t *testing.T) {
+	// crossings returns the number of zero crossings in a window of 500
+	// samples around the given center.
+	crossings := func(a []float64, center int) int {
+		count := 0
+		for i := center - 250; i < center+250; i++ {
+			if (a[i] < 0) != (a[i+1] < 0) {
+				count++
+			}
+		}
+		return count
+	}
+	const n = 10001
+	for _, test := range []struct {
+		method ChirpMethod
+		// frequency is the expected frequency at time x from 0 to 1.
+		frequency func(x float64) float64
+	}{
+		{LinearChirp, func(x float64) float64 { return 0.01 + 0.09*x }},
+		{QuadraticChirp, func(x float64) float64 { return 0.01 + 0.09*x*x }},
+		{LogarithmicChirp, func(x float64) float64 { return 0.01 * math.Pow(10, x) }},
+	} {
+		a := Chirp(n, 1, 0.01, 0.1, 0, 1, test.method)
+		for _, center := range []int{260, n / 2, n - 260} {
+			want := 2 * 500 * test.frequency(float64(center)/(n-1))
+			check.EqEps(t, crossings(a, center), want, 2, test.method, center)
+		}
+	}
+	check.Eq(t, Chirp(50, 1, 3, 3, 0.2, 100, LogarithmicChirp), Sine(50, 1, 3, 0.2, 100))
+}
+
+func TestImpulseStepAndRamp(t *testing.T) {
+	check.Eq(t, Impulse(4, 1), []float64{0, 1, 0, 0})
+	check.Eq(t, Impulse(3, 5), []float64{0, 0, 0})
+	check.Eq(t, Step(4, 2), []float64{0, 0, 1, 1})
+	check.Eq(t, Step(3, -1), []float64{1, 1, 1})
+	check.Eq(t, Ramp(5, 2), []float64{0, 0, 0, 1, 2})
+	check.Eq(t, len(Ramp(-1, 0)), 0)
+}
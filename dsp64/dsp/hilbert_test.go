@@ -0,0 +1,93 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestAnalyticSignalOfCosineIsComplexExponential(t *testing.T) {
+	for _, n := range []int{64, 75} {
+		a := AnalyticSignal(Cosine(n, 2, 5, 0.3, float64(n)))
+		check.Eq(t, len(a), n)
+		for i := range a {
+			x := 2*math.Pi*5*float64(i)/float64(n) + 0.3
+			check.EqEps(t, real(a[i]), 2*math.Cos(x), 1e-5, n, i)
+			check.EqEps(t, imag(a[i]), 2*math.Sin(x), 1e-5, n, i)
+		}
+	}
+	check.Eq(t, len(AnalyticSignal(nil)), 0)
+}
+
+func TestHilbertTransformOfCosineIsSine(t *testing.T) {
+	h := HilbertTransform(Cosine(100, 1, 7, 0, 100))
+	s := Sine(100, 1, 7, 0, 100)
+	for i := range h {
+		check.EqEps(t, h[i], s[i], 1e-5, i)
+	}
+}
+
+func TestEnvelopeOfAmplitudeModulatedSignal(t *testing.T) {
+	const n = 1000
+	carrier := Sine(n, 1, 100, 0, n)
+	envelope := AddOffset(Cosine(n, 0.5, 3, 0, n), 1)
+	signal := make([]float64, n)
+	for i := range signal {
+		signal[i] = carrier[i] * envelope[i]
+	}
+	e := Envelope(signal)
+	for i := range e {
+		check.EqEps(t, e[i], envelope[i], 1e-4, i)
+	}
+}
+
+func TestInstantaneousPhaseAndFrequency(t *testing.T) {
+	const n, sampleRate = 500, 1000
+	a := Sine(n, 3, 50, 0, sampleRate)
+	p := InstantaneousPhase(a)
+	for i := range p {
+		want := 2*math.Pi*50*float64(i)/sampleRate - math.Pi/2
+		check.EqEps(t, p[i], want, 1e-3, i)
+	}
+	f := InstantaneousFrequency(a, sampleRate)
+	check.Eq(t, len(f), n-1)
+	for i := range f {
+		check.EqEps(t, f[i], 50, 1e-2, i)
+	}
+	check.Eq(t, len(InstantaneousFrequency(nil, 1)), 0)
+}
+
+func TestHilbertFilterIsAntisymmetric(t *testing.T) {
+	h := HilbertFilter(10)
+	check.Eq(t, len(h), 11)
+	for i := range h {
+		check.EqEps(t, h[i], -h[len(h)-1-i], 1e-7, i)
+		if (i-5)%2 == 0 {
+			check.Eq(t, h[i], 0, i)
+		}
+	}
+	check.Eq(t, len(HilbertFilter(0)), 3)
+}
+
+func TestHilbertTransformerShiftsPhase(t *testing.T) {
+	const taps = 101
+	for _, f := range []float64{0.05, 0.2, 0.45} {
+		in := Cosine(1000, 1, f, 0, 1)
+		h := NewHilbertTransformer(taps)
+		check.Eq(t, h.Latency(), 50)
+		out := make([]complex128, len(in))
+		h.ProcessAnalytic(in[:333], out[:333])
+		h.ProcessAnalytic(in[333:], out[333:])
+		for i := taps; i < len(in); i++ {
+			x := 2 * math.Pi * float64(f) * float64(i-50)
+			check.EqEps(t, real(out[i]), math.Cos(x), 1e-5, f, i)
+			check.EqEps(t, imag(out[i]), math.Sin(x), 2e-3, f, i)
+		}
+
+		h.Reset()
+		im := make([]float64, len(in))
+		h.Process(in, im)
+		check.Eq(t, im, Imag(out))
+	}
+}
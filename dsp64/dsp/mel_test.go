@@ -0,0 +1,225 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestHzToMelMatchesSlaneyAndHTK(t *testing.T) {
+	mels := HzToMel([]float64{0, 60, 440, 1000, 6400}, SlaneyMel)
+	check.EqEps(t, mels, []float64{0, 0.9, 6.6, 15, 42}, 1e-4)
+	mels = HzToMel([]float64{0, 700, 1000}, HTKMel)
+	check.EqEps(t, mels, []float64{0, 2595 * float64(math.Log10(2)), 999.9855}, 1e-3)
+
+	hz := []float64{0, 100, 999, 1000, 1001, 4000, 11025}
+	for _, scale := range []MelScale{SlaneyMel, HTKMel} {
+		check.EqEps(t, MelToHz(HzToMel(hz, scale), scale), hz, 1e-2)
+	}
+	check.EqEps(t, MelToHz([]float64{3}, SlaneyMel), []float64{200}, 1e-4)
+}
+
+func TestMelFrequenciesMatchesLibrosa(t *testing.T) {
+	// librosa.mel_frequencies(n_mels=40), rounded to 3 decimals.
+	want := []float64{
+		0, 85.317, 170.635, 255.952, 341.269, 426.586, 511.904, 597.221,
+		682.538, 767.855, 853.173, 938.49, 1024.856, 1119.114, 1222.042,
+		1334.436, 1457.167, 1591.187, 1737.532, 1897.337, 2071.84, 2262.393,
+		2470.47, 2697.686, 2945.799, 3216.731, 3512.582, 3835.643, 4188.417,
+		4573.636, 4994.285, 5453.621, 5955.205, 6502.92, 7101.009, 7754.107,
+		8467.272, 9246.028, 10096.408, 11025,
+	}
+	check.EqEps(t, MelFrequencies(40, 0, 11025, SlaneyMel), want, 2e-3)
+}
+
+func TestTriangularFilterbank(t *testing.T) {
+	edges := []float64{0, 2, 4, 6}
+	check.Eq(t, TriangularFilterbank(edges, 8, 8, false), [][]float64{
+		{0, 0.5, 1, 0.5, 0},
+		{0, 0, 0, 0.5, 1},
+	})
+	check.Eq(t, TriangularFilterbank(edges, 8, 8, true), [][]float64{
+		{0, 0.25, 0.5, 0.25, 0},
+		{0, 0, 0, 0.25, 0.5},
+	})
+	check.Eq(t, len(TriangularFilterbank(edges[:2], 8, 8, true)), 0)
+}
+
+func TestMelFilterbankMatchesLibrosa(t *testing.T) {
+	// librosa.filters.mel(sr=22050, n_fft=2048)
+	fb := MelFilterbank(22050, 2048, 128, 0, 11025, SlaneyMel, true)
+	check.Eq(t, len(fb), 128)
+	check.Eq(t, len(fb[0]), 1025)
+	check.EqEps(t, fb[0][:3], []float64{0, 0.01618, 0.03237}, 1e-4)
+
+	// librosa.filters.mel(sr=16000, n_fft=512, n_mels=40, htk=True), row 10,
+	// computed from its formula in Python.
+	fb = MelFilterbank(16000, 512, 40, 0, 8000, HTKMel, true)
+	check.EqEps(t, fb[10][19:26], []float64{
+		0, 0.00442253, 0.00892215, 0.01030155, 0.00607016, 0.00183878, 0,
+	}, 1e-7)
+
+	// Unnormalized filters peak at 1 and neighboring filters add up to 1
+	// between their peaks.
+	fb = MelFilterbank(16000, 512, 40, 0, 8000, HTKMel, false)
+	for k := 20; k < 200; k++ {
+		var sum float64
+		for i := range fb {
+			sum += fb[i][k]
+		}
+		check.EqEps(t, sum, 1, 1e-5)
+	}
+}
+
+func TestMelSpectrogramPeaksAtToneFrequency(t *testing.T) {
+	const sampleRate = 16000
+	a := Sine(16000, 1, 1000, 0, sampleRate)
+	opts := MelOptions{FrameLength: 512, MelCount: 40}
+	s := MelSpectrogram(a, sampleRate, opts)
+	check.Eq(t, len(s), 1+len(a)/128)
+	check.Eq(t, len(s[0]), 40)
+
+	centers := MelFrequencies(42, 0, sampleRate/2, SlaneyMel)[1:41]
+	frame := s[len(s)/2]
+	best := 0
+	for i := range frame {
+		if frame[i] > frame[best] {
+			best = i
+		}
+	}
+	check.Eq(t, math.Abs(float64(centers[best]-1000)) < 100, true)
+
+	db := LogMelSpectrogram(a, sampleRate, opts)
+	_, lowest, _, highest := MinMax(db[len(db)/2])
+	check.EqEps(t, highest-lowest, 80, 1e-3)
+	check.EqEps(t, db[len(db)/2][best], float64(10*math.Log10(float64(frame[best]))), 1e-3)
+}
+
+func TestPowerToDB(t *testing.T) {
+	s := [][]float64{{1, 10}, {100, 1e-12}}
+	check.EqEps(t, PowerToDB(s, 80), [][]float64{{0, 10}, {20, -60}}, 1e-4)
+	check.EqEps(t, PowerToDB(s, 15), [][]float64{{5, 10}, {20, 5}}, 1e-4)
+	check.EqEps(t, PowerToDB(s, -1), [][]float64{{0, 10}, {20, -100}}, 1e-4)
+}
+
+func TestDCTMatchesScipyOrthonormal(t *testing.T) {
+	// scipy.fft.dct([1, 2, 3, 4], norm="ortho")
+	check.EqEps(t, DCT([]float64{1, 2, 3, 4}), []float64{5, -2.2304425, 0, -0.1585127}, 1e-5)
+	check.EqEps(t, DCT([]float64{3, 3, 3, 3, 3, 3, 3, 3, 3}), []float64{9, 0, 0, 0, 0, 0, 0, 0, 0}, 1e-5)
+	check.Eq(t, len(DCT(nil)), 0)
+}
+
+// sineAndChirp returns n samples of a 440 Hz sine of amplitude 0.5 plus a
+// linear chirp of amplitude 0.25 from 100 Hz to 3000 Hz at 8000 Hz.
+func sineAndChirp(n int) []float64 {
+	const sampleRate = 8000
+	duration := float64(n) / sampleRate
+	a := make([]float64, n)
+	for i := range a {
+		t := float64(i) / sampleRate
+		chirp := 100*t + (3000-100)/(2*duration)*t*t
+		a[i] = float64(0.5*math.Sin(2*math.Pi*440*t) + 0.25*math.Sin(2*math.Pi*chirp))
+	}
+	return a
+}
+
+func TestMelFeaturesMatchLibrosa(t *testing.T) {
+	// The values are frame 8 of these librosa 0.10 calls with y being
+	// sineAndChirp(2048), computed with a standalone Python port of their
+	// defaults: a periodic Hann window, centered frames padded with zeros,
+	// Slaney filters, ref=1, amin=1e-10, top_db=80 and an orthonormal DCT.
+	//
+	//	S = librosa.feature.melspectrogram(y=y, sr=8000, n_fft=512,
+	//		hop_length=128, n_mels=40)
+	//	librosa.power_to_db(S)
+	//	librosa.feature.mfcc(y=y, sr=8000, n_fft=512, hop_length=128,
+	//		n_mels=40, n_mfcc=13)
+	a := sineAndChirp(2048)
+	opts := MelOptions{FrameLength: 512, Hop: 128, MelCount: 40}
+
+	mel := MelSpectrogram(a, 8000, opts)
+	check.Eq(t, len(mel), 17)
+	check.EqEps(t, mel[8][5:9], []float64{0.000627126, 32.7474, 74.6881, 0.0199363}, 1e-4)
+	check.EqEps(t, mel[8][20:27], []float64{
+		0.0350459, 0.435145, 1.92499, 4.30906, 5.33025, 3.64979, 1.10927,
+	}, 1e-4)
+
+	db := PowerToDB(mel, 80)
+	check.EqEps(t, db[8][:10], []float64{
+		-59.6432, -59.6432, -59.6432, -58.5764, -49.5000,
+		-32.0265, 15.1518, 18.7325, -17.0036, -44.3809,
+	}, 2e-3)
+	check.EqEps(t, db[8][20:27], []float64{
+		-14.5536, -3.6137, 2.8443, 6.3438, 7.2675, 5.6227, 0.4504,
+	}, 2e-3)
+	check.EqEps(t, db[8][35], -59.6432, 2e-3)
+
+	mfcc := MFCC(a, 8000, MFCCOptions{Mel: opts, Count: 13})
+	check.EqEps(t, mfcc[8], []float64{
+		-249.9388, 11.5020, -68.0604, 72.8455, -5.8189, -113.6361, -30.8435,
+		-7.0635, -23.0917, 16.3566, 23.2912, 29.7394, 36.6690,
+	}, 1e-2)
+}
+
+func TestMFCCIsDCTOfLogMelSpectrogram(t *testing.T) {
+	const sampleRate = 8000
+	a := sineAndChirp(4000)
+	mel := MelOptions{FrameLength: 256, MelCount: 26}
+	logMel := LogMelSpectrogram(a, sampleRate, mel)
+
+	mfcc := MFCC(a, sampleRate, MFCCOptions{Mel: mel, Count: 13})
+	check.Eq(t, len(mfcc), len(logMel))
+	for n := range mfcc {
+		check.EqEps(t, mfcc[n], DCT(logMel[n])[:13], 1e-3)
+	}
+
+	liftered := MFCC(a, sampleRate, MFCCOptions{Mel: mel, Count: 13, Lifter: 22})
+	for i := 0; i < 13; i++ {
+		lift := float64(1 + 11*math.Sin(math.Pi*float64(i+1)/22))
+		check.EqEps(t, liftered[5][i], mfcc[5][i]*lift, 1e-2)
+	}
+
+	check.Eq(t, len(MFCC(a, sampleRate, MFCCOptions{Mel: mel})[0]), 20)
+}
+
+func TestDelta(t *testing.T) {
+	// The regression of a linear ramp is its slope, except near the edges
+	// where the first and last frames are repeated.
+	ramp := make([][]float64, 10)
+	for i := range ramp {
+		ramp[i] = []float64{float64(3 * i), 5}
+	}
+	d := Delta(ramp, 0)
+	for i := 2; i < 8; i++ {
+		check.Eq(t, d[i], []float64{3, 0})
+	}
+	check.EqEps(t, d[0][0], 3*float64(0+1+4)/10, 1e-6)
+	check.EqEps(t, d[1][0], 3*float64(2+2*3)/10, 1e-6)
+
+	check.Eq(t, Delta([][]float64{{0}, {1}, {2}}, 1), [][]float64{{0.5}, {1}, {0.5}})
+	// The regression formula of the HTK Book with the edge frames repeated.
+	check.EqEps(t,
+		Delta([][]float64{{1}, {4}, {2}, {8}, {5}}, 2),
+		[][]float64{{0.5}, {1.5}, {1.2}, {0.5}, {0.3}},
+		1e-6,
+	)
+	// Away from the edges, a width of 4 matches librosa.feature.delta, whose
+	// Savitzky-Golay filter fits a line to 9 frames. Its slope is the sum of
+	// n*c[t+n]/60 for n from -4 to 4.
+	c := [][]float64{{3}, {1}, {4}, {1}, {5}, {9}, {2}, {6}, {5}, {3}, {5}}
+	d4 := Delta(c, 4)
+	for i := 4; i < len(c)-4; i++ {
+		var want float64
+		for n := -4; n <= 4; n++ {
+			want += float64(n) * c[i+n][0] / 60
+		}
+		check.EqEps(t, d4[i][0], want, 1e-6)
+	}
+
+	dd := Delta(Delta(ramp, 2), 2)
+	for i := 4; i < 6; i++ {
+		check.Eq(t, dd[i], []float64{0, 0})
+	}
+	check.Eq(t, len(Delta(nil, 2)), 0)
+}
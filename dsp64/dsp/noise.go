@@ -0,0 +1,145 @@
+package dsp
+
+import (
+	"math"
+	"math/rand"
+)
+
+// NoiseColor selects the spectrum of a NoiseGenerator. The power spectral
+// density of colored noise is proportional to a power of the frequency f.
+type NoiseColor int
+
+const (
+	// WhiteNoise is Gaussian noise with a flat spectrum.
+	WhiteNoise NoiseColor = iota
+	// UniformNoise is white noise with values that are uniformly distributed
+	// in [-amplitude, amplitude).
+	UniformNoise
+	// PinkNoise has a spectrum proportional to 1/f, it falls by 10 dB per
+	// decade. It is generated with the Voss-McCartney algorithm.
+	PinkNoise
+	// BrownNoise, also called red noise, has a spectrum proportional to
+	// 1/f^2, it falls by 20 dB per decade. It is integrated white noise with a
+	// slight leak that keeps it from drifting away, which flattens the
+	// spectrum below about 1.6e-4 times the sample rate.
+	BrownNoise
+	// BlueNoise has a spectrum proportional to f, it rises by 10 dB per
+	// decade. It is differentiated pink noise.
+	BlueNoise
+	// VioletNoise has a spectrum proportional to f^2, it rises by 20 dB per
+	// decade. It is differentiated white noise.
+	VioletNoise
+)
+
+// pinkRows is the number of random values that are summed for pink noise.
+// They cover as many octaves below the sample rate.
+const pinkRows = 16
+
+// brownLeak is the feedback factor of the leaky integrator for brown noise.
+const brownLeak = 0.999
+
+var (
+	pinkNorm  = math.Sqrt(pinkRows + 1)
+	brownNorm = math.Sqrt(1 - brownLeak*brownLeak)
+)
+
+// NoiseGenerator generates noise of a given color in blocks of arbitrary size.
+// For all colors but UniformNoise, the noise has zero mean and a standard
+// deviation of Amplitude.
+type NoiseGenerator struct {
+	Color     NoiseColor
+	Amplitude float64
+
+	rand *rand.Rand
+	// rows and sum hold the state of the Voss-McCartney algorithm.
+	rows    [pinkRows]float64
+	sum     float64
+	counter uint64
+	// last is the previous pink or white value for blue and violet noise or
+	// the integrator state for brown noise.
+	last float64
+}
+
+// NewNoiseGenerator returns a NoiseGenerator that draws its random numbers
+// from rnd. Use rand.New(rand.NewSource(seed)) for reproducible noise.
+func NewNoiseGenerator(color NoiseColor, amplitude float64, rnd *rand.Rand) *NoiseGenerator {
+	g := &NoiseGenerator{Color: color, Amplitude: amplitude, rand: rnd}
+	for i := range g.rows {
+		g.rows[i] = rnd.NormFloat64()
+		g.sum += g.rows[i]
+	}
+	switch color {
+	case BrownNoise:
+		// Start in the steady state to avoid a slow fade in.
+		g.last = rnd.NormFloat64()
+	case BlueNoise:
+		g.last = g.pink()
+	case VioletNoise:
+		g.last = rnd.NormFloat64()
+	}
+	return g
+}
+
+// pink returns the next pink noise value with a variance of pinkRows+1.
+func (g *NoiseGenerator) pink() float64 {
+	// Row i changes every 2^(i+1) samples, at different times for each row,
+	// so at most one row changes per sample.
+	g.counter++
+	c := g.counter
+	i := 0
+	for c&1 == 0 && i < pinkRows {
+		c >>= 1
+		i++
+	}
+	if i < pinkRows {
+		x := g.rand.NormFloat64()
+		g.sum += x - g.rows[i]
+		g.rows[i] = x
+	}
+	return g.sum + g.rand.NormFloat64()
+}
+
+// Read fills out with the next noise samples.
+func (g *NoiseGenerator) Read(out []float64) {
+	a := float64(g.Amplitude)
+	for i := range out {
+		var x float64
+		switch g.Color {
+		case UniformNoise:
+			x = 2*g.rand.Float64() - 1
+		case PinkNoise:
+			x = g.pink() / pinkNorm
+		case BrownNoise:
+			// The variance of the integrator output is 1 if the white
+			// noise has a variance of 1-brownLeak^2.
+			g.last = brownLeak*g.last + brownNorm*g.rand.NormFloat64()
+			x = g.last
+		case BlueNoise:
+			// Almost always one row and the white part change per sample,
+			// each difference has a variance of 2.
+			p := g.pink()
+			x = (p - g.last) / 2
+			g.last = p
+		case VioletNoise:
+			w := g.rand.NormFloat64()
+			x = (w - g.last) / math.Sqrt2
+			g.last = w
+		default:
+			x = g.rand.NormFloat64()
+		}
+		out[i] = float64(a * x)
+	}
+}
+
+// Generate returns the next n noise samples.
+func (g *NoiseGenerator) Generate(n int) []float64 {
+	a := make([]float64, max0(n))
+	g.Read(a)
+	return a
+}
+
+// Noise returns n samples of noise with the given color. The same seed always
+// produces the same noise. See NoiseGenerator.
+func Noise(n int, color NoiseColor, amplitude float64, seed int64) []float64 {
+	return NewNoiseGenerator(color, amplitude, rand.New(rand.NewSource(seed))).Generate(n)
+}
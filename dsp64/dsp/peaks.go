@@ -0,0 +1,275 @@
+package dsp
+
+import (
+	"math"
+	"sort"
+)
+
+// PeakOptions configures FindPeaks. The zero value finds all local maxima.
+type PeakOptions struct {
+	// MinHeight and MaxHeight limit the values of peaks. MaxHeight is only
+	// used if it is greater than MinHeight. MinHeight is used if it is not 0
+	// or if MaxHeight is used, so a lower limit of 0 needs a MaxHeight, e.g.
+	// infinity.
+	MinHeight, MaxHeight float64
+	// MinThreshold is the smallest difference between a peak and both of its
+	// direct neighbors.
+	MinThreshold float64
+	// MinDistance is the smallest number of samples between two peaks. Of two
+	// peaks that are too close, the smaller one is removed.
+	MinDistance int
+	// MinProminence is the smallest prominence of a peak, see Peak.
+	MinProminence float64
+	// MinWidth and MaxWidth limit the width of peaks in samples, see Peak.
+	// MaxWidth is only used if it is greater than 0.
+	MinWidth, MaxWidth float64
+	// RelativeHeight selects the height at which the width of a peak is
+	// measured, relative to its prominence. 0 means 0.5 which is the full
+	// width at half prominence, 1 measures the width at the lowest contour
+	// line.
+	RelativeHeight float64
+	// Window limits the search for the bases of a peak to this many samples
+	// around it, which speeds up the search in long signals and makes
+	// prominences local. 0 means no limit.
+	Window int
+	// MinPlateau is the smallest number of samples with the same value at the
+	// top of a peak.
+	MinPlateau int
+}
+
+// Peak is a local maximum found by FindPeaks.
+type Peak struct {
+	// Index is the position of the peak. For flat peaks it is the middle of
+	// the plateau, rounded down.
+	Index int
+	// Value is the value at Index.
+	Value float64
+	// LeftEdge and RightEdge are the first and last index of the plateau at
+	// the top of the peak. They are both Index for peaks that are not flat.
+	LeftEdge, RightEdge int
+	// LeftThreshold and RightThreshold are the differences between the peak
+	// and its neighbors on each side of the plateau.
+	LeftThreshold, RightThreshold float64
+	// Prominence is how far the peak stands out from the signal around it,
+	// the height of the peak above the higher of its two bases.
+	Prominence float64
+	// LeftBase and RightBase are the lowest points on each side of the peak
+	// before the signal rises above the peak or ends.
+	LeftBase, RightBase int
+	// Width is the distance between the points left and right of the peak
+	// where the signal falls below WidthHeight, see
+	// PeakOptions.RelativeHeight.
+	Width float64
+	// WidthHeight is the height at which Width was measured.
+	WidthHeight float64
+	// LeftPosition and RightPosition are the linearly interpolated positions
+	// where the signal crosses WidthHeight.
+	LeftPosition, RightPosition float64
+}
+
+// FindPeaks returns all local maxima in a that satisfy the given options,
+// sorted by index. A peak is a sample, or a run of equal samples, with smaller
+// neighbors on both sides. The first and last samples are never peaks. The
+// definitions of prominence and width are those of SciPy's find_peaks.
+func FindPeaks(a []float64, options PeakOptions) []Peak {
+	var peaks []Peak
+	for i := 1; i < len(a)-1; i++ {
+		if a[i-1] >= a[i] {
+			continue
+		}
+		right := i
+		for right+1 < len(a)-1 && a[right+1] == a[i] {
+			right++
+		}
+		if a[right+1] < a[i] {
+			peaks = append(peaks, Peak{
+				Index:          (i + right) / 2,
+				Value:          a[i],
+				LeftEdge:       i,
+				RightEdge:      right,
+				LeftThreshold:  a[i] - a[i-1],
+				RightThreshold: a[i] - a[right+1],
+			})
+		}
+		i = right
+	}
+
+	useMax := options.MaxHeight > options.MinHeight
+	useMin := options.MinHeight != 0 || useMax
+	peaks = filterPeaks(peaks, func(p *Peak) bool {
+		return p.RightEdge-p.LeftEdge+1 >= options.MinPlateau &&
+			(!useMin || p.Value >= options.MinHeight) &&
+			(!useMax || p.Value <= options.MaxHeight) &&
+			p.LeftThreshold >= options.MinThreshold &&
+			p.RightThreshold >= options.MinThreshold
+	})
+	if options.MinDistance > 1 {
+		peaks = peaksWithDistance(peaks, options.MinDistance)
+	}
+
+	relHeight := float64(options.RelativeHeight)
+	if relHeight <= 0 {
+		relHeight = 0.5
+	}
+	for i := range peaks {
+		peakProminence(a, &peaks[i], options.Window)
+	}
+	peaks = filterPeaks(peaks, func(p *Peak) bool {
+		return p.Prominence >= options.MinProminence
+	})
+	for i := range peaks {
+		peakWidth(a, &peaks[i], relHeight)
+	}
+	return filterPeaks(peaks, func(p *Peak) bool {
+		return p.Width >= options.MinWidth &&
+			(options.MaxWidth <= 0 || p.Width <= options.MaxWidth)
+	})
+}
+
+func filterPeaks(peaks []Peak, keep func(p *Peak) bool) []Peak {
+	n := 0
+	for i := range peaks {
+		if keep(&peaks[i]) {
+			peaks[n] = peaks[i]
+			n++
+		}
+	}
+	return peaks[:n]
+}
+
+// peaksWithDistance removes peaks that are closer than distance to a higher
+// peak. Higher peaks are kept first, equal peaks from left to right.
+func peaksWithDistance(peaks []Peak, distance int) []Peak {
+	order := make([]int, len(peaks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return peaks[order[i]].Value > peaks[order[j]].Value
+	})
+	removed := make([]bool, len(peaks))
+	for _, i := range order {
+		if removed[i] {
+			continue
+		}
+		for j := i - 1; j >= 0 && peaks[i].Index-peaks[j].Index < distance; j-- {
+			removed[j] = true
+		}
+		for j := i + 1; j < len(peaks) && peaks[j].Index-peaks[i].Index < distance; j++ {
+			removed[j] = true
+		}
+	}
+	n := 0
+	for i := range peaks {
+		if !removed[i] {
+			peaks[n] = peaks[i]
+			n++
+		}
+	}
+	return peaks[:n]
+}
+
+// peakProminence sets the prominence and bases of p.
+func peakProminence(a []float64, p *Peak, window int) {
+	lo, hi := 0, len(a)-1
+	if window >= 2 {
+		if p.Index-window/2 > lo {
+			lo = p.Index - window/2
+		}
+		if p.Index+window/2 < hi {
+			hi = p.Index + window/2
+		}
+	}
+
+	p.LeftBase = p.Index
+	leftMin := p.Value
+	for i := p.Index; i >= lo && a[i] <= p.Value; i-- {
+		if a[i] < leftMin {
+			leftMin = a[i]
+			p.LeftBase = i
+		}
+	}
+	p.RightBase = p.Index
+	rightMin := p.Value
+	for i := p.Index; i <= hi && a[i] <= p.Value; i++ {
+		if a[i] < rightMin {
+			rightMin = a[i]
+			p.RightBase = i
+		}
+	}
+	if leftMin > rightMin {
+		p.Prominence = p.Value - leftMin
+	} else {
+		p.Prominence = p.Value - rightMin
+	}
+}
+
+// peakWidth sets the width of p, measured between its bases.
+func peakWidth(a []float64, p *Peak, relHeight float64) {
+	height := float64(p.Value) - float64(p.Prominence)*relHeight
+	p.WidthHeight = float64(height)
+
+	i := p.Index
+	for i > p.LeftBase && height < float64(a[i]) {
+		i--
+	}
+	left := float64(i)
+	if float64(a[i]) < height {
+		left += (height - float64(a[i])) / float64(a[i+1]-a[i])
+	}
+
+	i = p.Index
+	for i < p.RightBase && height < float64(a[i]) {
+		i++
+	}
+	right := float64(i)
+	if float64(a[i]) < height {
+		right -= (height - float64(a[i])) / float64(a[i-1]-a[i])
+	}
+
+	p.LeftPosition, p.RightPosition = float64(left), float64(right)
+	p.Width = float64(right - left)
+}
+
+// ParabolicPeak fits a parabola through a[i] and its two neighbors and
+// returns the position and value of its vertex. This estimates the location
+// of a peak between samples, e.g. the frequency of a sine wave from the bins
+// of a magnitude spectrum. At the ends of a, i and a[i] are returned.
+func ParabolicPeak(a []float64, i int) (position, value float64) {
+	if i <= 0 || i >= len(a)-1 {
+		if i >= 0 && i < len(a) {
+			value = a[i]
+		}
+		return float64(i), value
+	}
+	p, v := parabolicVertex(float64(a[i-1]), float64(a[i]), float64(a[i+1]))
+	return float64(float64(i) + p), float64(v)
+}
+
+// GaussianPeak is like ParabolicPeak but fits a Gaussian, a parabola through
+// the logarithms of the values. It is exact for Gaussian shaped peaks and
+// more accurate than ParabolicPeak for the peaks in magnitude spectra with a
+// Gaussian window. If any of the three values is not positive, it falls back
+// to ParabolicPeak.
+func GaussianPeak(a []float64, i int) (position, value float64) {
+	if i <= 0 || i >= len(a)-1 || a[i-1] <= 0 || a[i] <= 0 || a[i+1] <= 0 {
+		return ParabolicPeak(a, i)
+	}
+	p, v := parabolicVertex(
+		math.Log(float64(a[i-1])),
+		math.Log(float64(a[i])),
+		math.Log(float64(a[i+1])),
+	)
+	return float64(float64(i) + p), float64(math.Exp(v))
+}
+
+// parabolicVertex returns the offset and value of the vertex of the parabola
+// through (-1, left), (0, center) and (1, right).
+func parabolicVertex(left, center, right float64) (offset, value float64) {
+	d := left - 2*center + right
+	if d == 0 {
+		return 0, center
+	}
+	offset = 0.5 * (left - right) / d
+	return offset, center - 0.25*(left-right)*offset
+}
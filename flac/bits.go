@@ -0,0 +1,174 @@
+package flac
+
+import (
+	"io"
+	"math/bits"
+)
+
+// bitReader reads big-endian bit fields from a byte stream and keeps running
+// CRC-8 and CRC-16 checksums over all bytes read. The first error that occurs
+// is kept in err, after that all reads return 0.
+type bitReader struct {
+	r io.ByteReader
+	// cache holds the n least significant bits that were read from r but not
+	// yet consumed. Bytes are only read on demand, so n is always less than 8
+	// after a call returns.
+	cache uint64
+	n     uint
+	crc8  byte
+	crc16 uint16
+	err   error
+}
+
+func (b *bitReader) resetCRC() {
+	b.crc8 = 0
+	b.crc16 = 0
+}
+
+func (b *bitReader) fill() bool {
+	if b.err != nil {
+		return false
+	}
+	c, err := b.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		b.err = err
+		return false
+	}
+	b.crc8 = crc8Table[b.crc8^c]
+	b.crc16 = b.crc16<<8 ^ crc16Table[byte(b.crc16>>8)^c]
+	b.cache = b.cache<<8 | uint64(c)
+	b.n += 8
+	return true
+}
+
+// read returns the next n bits, n must be at most 56.
+func (b *bitReader) read(n uint) uint64 {
+	for b.n < n {
+		if !b.fill() {
+			return 0
+		}
+	}
+	b.n -= n
+	v := b.cache >> b.n & (1<<n - 1)
+	b.cache &= 1<<b.n - 1
+	return v
+}
+
+// readSigned returns the next n bits as a two's complement number.
+func (b *bitReader) readSigned(n uint) int64 {
+	if n == 0 {
+		return 0
+	}
+	shift := 64 - n
+	return int64(b.read(n)<<shift) >> shift
+}
+
+// readUnary counts and consumes zero bits up to and including the next one
+// bit.
+func (b *bitReader) readUnary() uint64 {
+	var count uint64
+	for b.cache == 0 {
+		count += uint64(b.n)
+		b.n = 0
+		if !b.fill() {
+			return 0
+		}
+	}
+	zeros := uint(bits.LeadingZeros64(b.cache)) - (64 - b.n)
+	b.n -= zeros + 1
+	b.cache &= 1<<b.n - 1
+	return count + uint64(zeros)
+}
+
+// align skips the bits up to the next byte boundary.
+func (b *bitReader) align() {
+	b.n = 0
+	b.cache = 0
+}
+
+// bitWriter writes big-endian bit fields into a byte slice.
+type bitWriter struct {
+	buf   []byte
+	cache uint64
+	n     uint
+}
+
+// write appends the n least significant bits of v, n must be at most 56.
+func (b *bitWriter) write(v uint64, n uint) {
+	if n == 0 {
+		return
+	}
+	b.cache = b.cache<<n | v&(1<<n-1)
+	b.n += n
+	for b.n >= 8 {
+		b.n -= 8
+		b.buf = append(b.buf, byte(b.cache>>b.n))
+	}
+	b.cache &= 1<<b.n - 1
+}
+
+func (b *bitWriter) writeSigned(v int64, n uint) {
+	b.write(uint64(v), n)
+}
+
+// writeUnary writes n zero bits followed by a one bit.
+func (b *bitWriter) writeUnary(n uint64) {
+	for n >= 32 {
+		b.write(0, 32)
+		n -= 32
+	}
+	b.write(1, uint(n)+1)
+}
+
+// align pads with zero bits up to the next byte boundary.
+func (b *bitWriter) align() {
+	if b.n > 0 {
+		b.write(0, 8-b.n)
+	}
+}
+
+var crc8Table, crc16Table = makeCRCTables()
+
+// makeCRCTables creates the tables for the CRC-8 with polynomial
+// x^8+x^2+x+1 and the CRC-16 with polynomial x^16+x^15+x^2+1 that protect
+// FLAC frames.
+func makeCRCTables() (t8 [256]byte, t16 [256]uint16) {
+	for i := range t8 {
+		c8 := byte(i)
+		c16 := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if c8&0x80 != 0 {
+				c8 = c8<<1 ^ 0x07
+			} else {
+				c8 <<= 1
+			}
+			if c16&0x8000 != 0 {
+				c16 = c16<<1 ^ 0x8005
+			} else {
+				c16 <<= 1
+			}
+		}
+		t8[i] = c8
+		t16[i] = c16
+	}
+	return
+}
+
+func crc8(data []byte) byte {
+	var c byte
+	for _, b := range data {
+		c = crc8Table[c^b]
+	}
+	return c
+}
+
+func crc16(data []byte) uint16 {
+	var c uint16
+	for _, b := range data {
+		c = c<<8 ^ crc16Table[byte(c>>8)^b]
+	}
+	return c
+}
@@ -0,0 +1,52 @@
+package flac
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestCRCsMatchCheckValues(t *testing.T) {
+	check.Eq(t, crc8([]byte("123456789")), byte(0xF4))
+	check.Eq(t, crc16([]byte("123456789")), uint16(0xFEE8))
+}
+
+func TestBitReaderReadsWhatBitWriterWrote(t *testing.T) {
+	var w bitWriter
+	w.write(5, 3)
+	w.writeSigned(-3, 4)
+	w.writeUnary(0)
+	w.writeUnary(70)
+	w.write(1<<56-1, 56)
+	w.writeSigned(-1, 33)
+	w.writeUnary(9)
+	w.align()
+	check.Eq(t, len(w.buf), 23)
+
+	r := bitReader{r: bytes.NewReader(w.buf)}
+	check.Eq(t, r.read(3), uint64(5))
+	check.Eq(t, r.readSigned(4), int64(-3))
+	check.Eq(t, r.readUnary(), uint64(0))
+	check.Eq(t, r.readUnary(), uint64(70))
+	check.Eq(t, r.read(56), uint64(1<<56-1))
+	check.Eq(t, r.readSigned(33), int64(-1))
+	check.Eq(t, r.readUnary(), uint64(9))
+	r.align()
+	check.Eq(t, r.err, nil)
+	check.Eq(t, r.crc16, crc16(w.buf))
+	check.Eq(t, r.read(1), uint64(0))
+	check.Eq(t, r.err, io.ErrUnexpectedEOF)
+}
+
+func TestUTF8NumbersRoundTrip(t *testing.T) {
+	for _, v := range []uint64{0, 0x7F, 0x80, 0x7FF, 0x800, 0xFFFF, 1<<36 - 1} {
+		var w bitWriter
+		writeUTF8(&w, v)
+		r := bitReader{r: bytes.NewReader(w.buf)}
+		check.Eq(t, readUTF8(&r), true, v)
+		check.Eq(t, r.read(1), uint64(0), v)
+		check.Eq(t, r.err, io.ErrUnexpectedEOF, v)
+	}
+}
@@ -0,0 +1,510 @@
+package flac
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/bits"
+)
+
+// Reader decodes a FLAC stream frame by frame, without loading the whole
+// stream into memory.
+type Reader struct {
+	// Format is the format of the samples.
+	Format Format
+	// Metadata holds all metadata blocks except STREAMINFO.
+	Metadata []MetadataBlock
+
+	info  streamInfo
+	in    *bufio.Reader
+	bits  bitReader
+	md5   hash.Hash
+	md5b  []byte
+	frame [][]int64
+	// pos is the number of samples of frame that were already returned and
+	// frameLen is the number of samples in frame.
+	pos      int
+	frameLen int
+	scale    float64
+	buf64    [][]float64
+	err      error
+}
+
+// NewReader reads the stream marker and all metadata blocks from r, up to the
+// first audio frame. An ID3v2 tag in front of the stream is skipped.
+func NewReader(r io.Reader) (*Reader, error) {
+	in := bufio.NewReader(r)
+	if err := skipID3(in); err != nil {
+		return nil, err
+	}
+	var marker [4]byte
+	if _, err := io.ReadFull(in, marker[:]); err != nil {
+		return nil, errors.New("flac: stream is too short")
+	}
+	if string(marker[:]) != "fLaC" {
+		return nil, errors.New("flac: not a FLAC stream")
+	}
+
+	fr := &Reader{in: in}
+	fr.bits.r = in
+	haveInfo := false
+	for last := false; !last; {
+		var header [4]byte
+		if _, err := io.ReadFull(in, header[:]); err != nil {
+			return nil, errors.New("flac: metadata is truncated")
+		}
+		last = header[0]&0x80 != 0
+		typ := BlockType(header[0] & 0x7F)
+		size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		if typ == 127 {
+			return nil, errors.New("flac: invalid metadata block type")
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(in, data); err != nil {
+			return nil, errors.New("flac: metadata is truncated")
+		}
+		if !haveInfo {
+			if typ != StreamInfo {
+				return nil, errors.New("flac: first metadata block is not STREAMINFO")
+			}
+			info, err := decodeStreamInfo(data)
+			if err != nil {
+				return nil, err
+			}
+			fr.info = info
+			fr.Format = info.format
+			haveInfo = true
+			continue
+		}
+		if typ == StreamInfo {
+			return nil, errors.New("flac: more than one STREAMINFO block")
+		}
+		fr.Metadata = append(fr.Metadata, MetadataBlock{Type: typ, Data: data})
+	}
+
+	if fr.info.md5 != [16]byte{} {
+		fr.md5 = md5.New()
+	}
+	fr.frame = make([][]int64, fr.Format.ChannelCount)
+	fr.scale = math.Ldexp(1, 1-fr.Format.BitsPerSample)
+	return fr, nil
+}
+
+// skipID3 skips an ID3v2 tag if there is one.
+func skipID3(in *bufio.Reader) error {
+	header, err := in.Peek(10)
+	if err != nil || string(header[:3]) != "ID3" {
+		return nil
+	}
+	size := int64(header[6]&0x7F)<<21 | int64(header[7]&0x7F)<<14 |
+		int64(header[8]&0x7F)<<7 | int64(header[9]&0x7F)
+	if header[5]&0x10 != 0 {
+		size += 10 // footer
+	}
+	n, err := io.CopyN(ioutil.Discard, in, 10+size)
+	if n < 10+size {
+		return errors.New("flac: ID3 tag is truncated")
+	}
+	return err
+}
+
+// FrameCount returns the number of samples per channel in the stream or -1 if
+// the stream header does not say.
+func (r *Reader) FrameCount() int {
+	if r.info.sampleCount == 0 {
+		return -1
+	}
+	return int(r.info.sampleCount)
+}
+
+// Read decodes the next samples into dst which must contain one slice per
+// channel. It reads at most as many samples as the shortest slice in dst can
+// hold and returns the number of samples read per channel. At the end of the
+// stream it returns 0 and io.EOF, or ErrMD5Mismatch if the decoded audio does
+// not match the MD5 signature of the stream.
+func (r *Reader) Read(dst [][]float64) (int, error) {
+	if len(dst) != r.Format.ChannelCount {
+		return 0, errors.New("flac: Read needs one buffer per channel")
+	}
+	n := -1
+	for _, c := range dst {
+		if n == -1 || len(c) < n {
+			n = len(c)
+		}
+	}
+	done := 0
+	for done < n {
+		if r.pos == r.frameLen {
+			if r.err == nil {
+				r.err = r.readFrame()
+			}
+			if r.err != nil {
+				break
+			}
+		}
+		m := r.frameLen - r.pos
+		if m > n-done {
+			m = n - done
+		}
+		for i, c := range dst {
+			src := r.frame[i][r.pos : r.pos+m]
+			for j, v := range src {
+				c[done+j] = float64(v) * r.scale
+			}
+		}
+		r.pos += m
+		done += m
+	}
+	if done > 0 {
+		return done, nil
+	}
+	return 0, r.err
+}
+
+// ReadFloat32 is like Read but converts the samples to float32.
+func (r *Reader) ReadFloat32(dst [][]float32) (int, error) {
+	if len(dst) != r.Format.ChannelCount {
+		return 0, errors.New("flac: Read needs one buffer per channel")
+	}
+	if r.buf64 == nil {
+		r.buf64 = make([][]float64, len(dst))
+		for i := range r.buf64 {
+			r.buf64[i] = make([]float64, 4096)
+		}
+	}
+	buf := make([][]float64, len(dst))
+	for i := range buf {
+		buf[i] = r.buf64[i]
+		if len(dst[i]) < len(buf[i]) {
+			buf[i] = buf[i][:len(dst[i])]
+		}
+	}
+	n, err := r.Read(buf)
+	for i := range buf {
+		for j := 0; j < n; j++ {
+			dst[i][j] = float32(buf[i][j])
+		}
+	}
+	return n, err
+}
+
+// Channel assignments of stereo frames that store a side channel.
+const (
+	leftSide  = 8
+	sideRight = 9
+	midSide   = 10
+)
+
+var sampleSizes = [8]int{0, 8, 12, -1, 16, 20, 24, 32}
+
+// readFrame decodes the next frame into r.frame. At the end of the stream it
+// returns io.EOF or ErrMD5Mismatch.
+func (r *Reader) readFrame() error {
+	if _, err := r.in.Peek(1); err == io.EOF {
+		if r.md5 != nil && !bytes.Equal(r.md5.Sum(nil), r.info.md5[:]) {
+			return ErrMD5Mismatch
+		}
+		return io.EOF
+	}
+
+	b := &r.bits
+	b.resetCRC()
+	if b.read(15) != 0x3FFE<<1 {
+		if b.err != nil {
+			return b.err
+		}
+		return errors.New("flac: lost frame sync")
+	}
+	b.read(1) // blocking strategy
+	blockSizeCode := b.read(4)
+	sampleRateCode := b.read(4)
+	channelCode := int(b.read(4))
+	sampleSizeCode := b.read(3)
+	reserved := b.read(1)
+	if !readUTF8(b) {
+		return errors.New("flac: invalid frame number")
+	}
+
+	blockSize := 0
+	switch {
+	case blockSizeCode == 1:
+		blockSize = 192
+	case blockSizeCode >= 2 && blockSizeCode <= 5:
+		blockSize = 576 << (blockSizeCode - 2)
+	case blockSizeCode == 6:
+		blockSize = int(b.read(8)) + 1
+	case blockSizeCode == 7:
+		blockSize = int(b.read(16)) + 1
+	case blockSizeCode >= 8:
+		blockSize = 256 << (blockSizeCode - 8)
+	}
+	switch sampleRateCode {
+	case 12:
+		b.read(8)
+	case 13, 14:
+		b.read(16)
+	}
+	headerCRC := b.crc8
+	if b.read(8) != uint64(headerCRC) && b.err == nil {
+		return errors.New("flac: frame header CRC mismatch")
+	}
+	if b.err != nil {
+		return b.err
+	}
+
+	if blockSize == 0 || sampleRateCode == 15 || reserved != 0 {
+		return errors.New("flac: invalid frame header")
+	}
+	channels := channelCode + 1
+	if channelCode >= leftSide {
+		if channelCode > midSide {
+			return errors.New("flac: invalid channel assignment")
+		}
+		channels = 2
+	}
+	if channels != r.Format.ChannelCount {
+		return errors.New("flac: frame channel count does not match STREAMINFO")
+	}
+	bps := r.Format.BitsPerSample
+	if sampleSizeCode != 0 {
+		bps = sampleSizes[sampleSizeCode]
+		if bps != r.Format.BitsPerSample {
+			return errors.New("flac: frame sample size does not match STREAMINFO")
+		}
+	}
+
+	for i := range r.frame {
+		if cap(r.frame[i]) < blockSize {
+			r.frame[i] = make([]int64, blockSize)
+		}
+		r.frame[i] = r.frame[i][:blockSize]
+		subframeBits := uint(bps)
+		if channelCode == leftSide && i == 1 ||
+			channelCode == sideRight && i == 0 ||
+			channelCode == midSide && i == 1 {
+			subframeBits++
+		}
+		if err := r.readSubframe(r.frame[i], subframeBits); err != nil {
+			return err
+		}
+	}
+	b.align()
+	frameCRC := b.crc16
+	if b.read(16) != uint64(frameCRC) && b.err == nil {
+		return errors.New("flac: frame CRC mismatch")
+	}
+	if b.err != nil {
+		return b.err
+	}
+
+	decorrelate(r.frame, channelCode)
+	if r.md5 != nil {
+		r.hashFrame(blockSize, bps)
+	}
+	r.pos = 0
+	r.frameLen = blockSize
+	return nil
+}
+
+// readUTF8 skips the frame or sample number, which is coded like an extended
+// UTF-8 character of up to 7 bytes.
+func readUTF8(b *bitReader) bool {
+	x := byte(b.read(8))
+	n := bits.LeadingZeros8(^x)
+	if n == 0 {
+		return true
+	}
+	if n == 1 || n == 8 {
+		return false
+	}
+	for i := 1; i < n; i++ {
+		if b.read(8)&0xC0 != 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+func decorrelate(frame [][]int64, channelCode int) {
+	switch channelCode {
+	case leftSide:
+		left, side := frame[0], frame[1]
+		for i := range side {
+			side[i] = left[i] - side[i]
+		}
+	case sideRight:
+		side, right := frame[0], frame[1]
+		for i := range side {
+			side[i] += right[i]
+		}
+	case midSide:
+		mid, side := frame[0], frame[1]
+		for i := range mid {
+			m := mid[i]<<1 | side[i]&1
+			mid[i] = (m + side[i]) >> 1
+			side[i] = (m - side[i]) >> 1
+		}
+	}
+}
+
+// hashFrame adds the samples of the current frame to the MD5 signature. The
+// signature is computed over interleaved little-endian samples.
+func (r *Reader) hashFrame(blockSize, bps int) {
+	size := (bps + 7) / 8
+	r.md5b = r.md5b[:0]
+	for i := 0; i < blockSize; i++ {
+		for _, c := range r.frame {
+			v := c[i]
+			for j := 0; j < size; j++ {
+				r.md5b = append(r.md5b, byte(v>>(8*uint(j))))
+			}
+		}
+	}
+	r.md5.Write(r.md5b)
+}
+
+// Subframe types. The fixed and LPC types are offset by their order.
+const (
+	subframeConstant = 0
+	subframeVerbatim = 1
+	subframeFixed    = 8
+	subframeLPC      = 32
+)
+
+// fixedCoefficients are the predictor coefficients of the fixed predictors of
+// orders 0 to 4, starting with the most recent sample.
+var fixedCoefficients = [5][]int64{
+	{},
+	{1},
+	{2, -1},
+	{3, -3, 1},
+	{4, -6, 4, -1},
+}
+
+func (r *Reader) readSubframe(dst []int64, bps uint) error {
+	b := &r.bits
+	if b.read(1) != 0 {
+		return errors.New("flac: invalid subframe header")
+	}
+	typ := int(b.read(6))
+	wasted := uint(0)
+	if b.read(1) == 1 {
+		wasted = uint(b.readUnary()) + 1
+		if wasted >= bps {
+			return errors.New("flac: invalid wasted bits in subframe")
+		}
+		bps -= wasted
+	}
+
+	switch {
+	case typ == subframeConstant:
+		v := b.readSigned(bps)
+		for i := range dst {
+			dst[i] = v
+		}
+	case typ == subframeVerbatim:
+		for i := range dst {
+			dst[i] = b.readSigned(bps)
+		}
+	case typ >= subframeFixed && typ <= subframeFixed+4:
+		order := typ - subframeFixed
+		if order > len(dst) {
+			return errors.New("flac: predictor order is larger than the block")
+		}
+		for i := 0; i < order; i++ {
+			dst[i] = b.readSigned(bps)
+		}
+		if err := r.readResidual(dst, order); err != nil {
+			return err
+		}
+		predict(dst, fixedCoefficients[order], 0)
+	case typ >= subframeLPC:
+		order := typ - subframeLPC + 1
+		if order > len(dst) {
+			return errors.New("flac: predictor order is larger than the block")
+		}
+		for i := 0; i < order; i++ {
+			dst[i] = b.readSigned(bps)
+		}
+		precision := uint(b.read(4)) + 1
+		shift := b.readSigned(5)
+		if precision == 16 || shift < 0 {
+			return errors.New("flac: invalid LPC coefficients")
+		}
+		coefficients := make([]int64, order)
+		for i := range coefficients {
+			coefficients[i] = b.readSigned(precision)
+		}
+		if err := r.readResidual(dst, order); err != nil {
+			return err
+		}
+		predict(dst, coefficients, uint(shift))
+	default:
+		return errors.New("flac: reserved subframe type")
+	}
+
+	if wasted > 0 {
+		for i := range dst {
+			dst[i] <<= wasted
+		}
+	}
+	return b.err
+}
+
+// predict adds the prediction to the residual in data[len(coefficients):].
+// The first coefficient is applied to the most recent sample.
+func predict(data []int64, coefficients []int64, shift uint) {
+	order := len(coefficients)
+	for i := order; i < len(data); i++ {
+		var sum int64
+		for j, c := range coefficients {
+			sum += c * data[i-1-j]
+		}
+		data[i] += sum >> shift
+	}
+}
+
+// readResidual reads the Rice coded prediction residual into dst[order:].
+func (r *Reader) readResidual(dst []int64, order int) error {
+	b := &r.bits
+	method := b.read(2)
+	if method > 1 {
+		return errors.New("flac: reserved residual coding method")
+	}
+	paramBits, escape := uint(4), uint64(15)
+	if method == 1 {
+		paramBits, escape = 5, 31
+	}
+	partitionOrder := uint(b.read(4))
+	partitions := 1 << partitionOrder
+	partitionSize := len(dst) >> partitionOrder
+	if partitionSize<<partitionOrder != len(dst) || partitionSize < order {
+		return errors.New("flac: invalid residual partition order")
+	}
+
+	i := order
+	for p := 0; p < partitions; p++ {
+		end := (p + 1) * partitionSize
+		k := b.read(paramBits)
+		if k == escape {
+			width := uint(b.read(5))
+			for ; i < end; i++ {
+				dst[i] = b.readSigned(width)
+			}
+		} else {
+			for ; i < end; i++ {
+				v := b.readUnary()<<k | b.read(uint(k))
+				dst[i] = int64(v>>1) ^ -int64(v&1)
+			}
+		}
+		if b.err != nil {
+			return b.err
+		}
+	}
+	return nil
+}
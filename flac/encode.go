@@ -0,0 +1,363 @@
+package flac
+
+import (
+	"crypto/md5"
+	"errors"
+	"hash"
+	"io"
+)
+
+// encoder turns blocks of samples into frames and keeps track of the values
+// for the STREAMINFO block.
+type encoder struct {
+	w      io.Writer
+	format Format
+	opt    Options
+	// block holds the samples of the next frame, one slice per channel.
+	block       [][]int64
+	frameNumber uint64
+	info        streamInfo
+	md5         hash.Hash
+	md5b        []byte
+	side, mid   []int64
+	buf         bitWriter
+}
+
+func newEncoder(w io.Writer, f Format, o *Options) (*encoder, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	opt, err := o.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	e := &encoder{
+		w:      w,
+		format: f,
+		opt:    opt,
+		block:  make([][]int64, f.ChannelCount),
+		md5:    md5.New(),
+	}
+	for i := range e.block {
+		e.block[i] = make([]int64, 0, opt.BlockSize)
+	}
+	e.info.format = f
+	return e, nil
+}
+
+// write converts the samples to integers and writes all complete frames.
+func (e *encoder) write(samples [][]float64) error {
+	n := len(samples[0])
+	for done := 0; done < n; {
+		m := e.opt.BlockSize - len(e.block[0])
+		if m > n-done {
+			m = n - done
+		}
+		for i, c := range samples {
+			for _, x := range c[done : done+m] {
+				e.block[i] = append(e.block[i], toInt(x, e.format.BitsPerSample))
+			}
+		}
+		done += m
+		if len(e.block[0]) == e.opt.BlockSize {
+			if err := e.writeFrame(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush writes the remaining samples as a shorter, last frame.
+func (e *encoder) flush() error {
+	if len(e.block[0]) == 0 {
+		return nil
+	}
+	return e.writeFrame()
+}
+
+func (e *encoder) writeFrame() error {
+	n := len(e.block[0])
+	e.hashBlock()
+	frame := e.encodeFrame()
+	if _, err := e.w.Write(frame); err != nil {
+		return err
+	}
+
+	if e.frameNumber == 0 || n > e.info.maxBlockSize {
+		e.info.maxBlockSize = n
+	}
+	if e.frameNumber == 0 || len(frame) < e.info.minFrameSize {
+		e.info.minFrameSize = len(frame)
+	}
+	if len(frame) > e.info.maxFrameSize {
+		e.info.maxFrameSize = len(frame)
+	}
+	e.info.sampleCount += int64(n)
+	e.frameNumber++
+	for i := range e.block {
+		e.block[i] = e.block[i][:0]
+	}
+	return nil
+}
+
+func (e *encoder) hashBlock() {
+	size := (e.format.BitsPerSample + 7) / 8
+	e.md5b = e.md5b[:0]
+	for i := range e.block[0] {
+		for _, c := range e.block {
+			v := c[i]
+			for j := 0; j < size; j++ {
+				e.md5b = append(e.md5b, byte(v>>(8*uint(j))))
+			}
+		}
+	}
+	e.md5.Write(e.md5b)
+}
+
+// streamInfo returns the STREAMINFO block for all frames written so far.
+func (e *encoder) streamInfo() streamInfo {
+	info := e.info
+	// All frames but the last have the full block size. A stream with a
+	// single, shorter frame states that frame's size, but at least the
+	// minimum of 16.
+	if e.frameNumber == 1 && info.maxBlockSize < e.opt.BlockSize {
+		if info.maxBlockSize < 16 {
+			info.maxBlockSize = 16
+		}
+	} else {
+		info.maxBlockSize = e.opt.BlockSize
+	}
+	info.minBlockSize = info.maxBlockSize
+	copy(info.md5[:], e.md5.Sum(nil))
+	return info
+}
+
+// sampleRateCodes are the sample rates that can be stored in the frame header
+// directly.
+var sampleRateCodes = map[int]uint64{
+	88200:  1,
+	176400: 2,
+	192000: 3,
+	8000:   4,
+	16000:  5,
+	22050:  6,
+	24000:  7,
+	32000:  8,
+	44100:  9,
+	48000:  10,
+	96000:  11,
+}
+
+var sampleSizeCodes = map[int]uint64{8: 1, 12: 2, 16: 4, 20: 5, 24: 6, 32: 7}
+
+func (e *encoder) encodeFrame() []byte {
+	n := len(e.block[0])
+	bps := uint(e.format.BitsPerSample)
+
+	var subframes []*subframe
+	channelCode := uint64(len(e.block) - 1)
+	if len(e.block) == 2 {
+		channelCode, subframes = e.analyzeStereo(bps)
+	} else {
+		for _, c := range e.block {
+			subframes = append(subframes, analyze(c, bps, e.opt.MaxLPCOrder))
+		}
+	}
+
+	b := &e.buf
+	b.buf = b.buf[:0]
+	b.write(0x3FFE, 14)
+	b.write(0, 1) // reserved
+	b.write(0, 1) // fixed block size
+
+	var blockSizeCode, blockSizeExtra uint64
+	var blockSizeExtraBits uint
+	switch {
+	case n == 192:
+		blockSizeCode = 1
+	case n == 576 || n == 1152 || n == 2304 || n == 4608:
+		blockSizeCode = 2 + uint64(log2(n/576))
+	case n&(n-1) == 0 && n >= 256 && n <= 32768:
+		blockSizeCode = 8 + uint64(log2(n/256))
+	case n <= 256:
+		blockSizeCode, blockSizeExtra, blockSizeExtraBits = 6, uint64(n-1), 8
+	default:
+		blockSizeCode, blockSizeExtra, blockSizeExtraBits = 7, uint64(n-1), 16
+	}
+	b.write(blockSizeCode, 4)
+
+	rate := e.format.SampleRate
+	var rateExtra uint64
+	var rateExtraBits uint
+	rateCode, ok := sampleRateCodes[rate]
+	if !ok {
+		switch {
+		case rate%1000 == 0 && rate/1000 < 256:
+			rateCode, rateExtra, rateExtraBits = 12, uint64(rate/1000), 8
+		case rate < 65536:
+			rateCode, rateExtra, rateExtraBits = 13, uint64(rate), 16
+		case rate%10 == 0 && rate/10 < 65536:
+			rateCode, rateExtra, rateExtraBits = 14, uint64(rate/10), 16
+		}
+	}
+	b.write(rateCode, 4)
+	b.write(channelCode, 4)
+	b.write(sampleSizeCodes[e.format.BitsPerSample], 3)
+	b.write(0, 1) // reserved
+	writeUTF8(b, e.frameNumber)
+	b.write(blockSizeExtra, blockSizeExtraBits)
+	b.write(rateExtra, rateExtraBits)
+	b.write(uint64(crc8(b.buf)), 8)
+
+	for _, s := range subframes {
+		s.write(b)
+	}
+	b.align()
+	b.write(uint64(crc16(b.buf)), 16)
+	return b.buf
+}
+
+// analyzeStereo finds the cheapest of the four ways to store two channels.
+func (e *encoder) analyzeStereo(bps uint) (uint64, []*subframe) {
+	left, right := e.block[0], e.block[1]
+	n := len(left)
+	if cap(e.side) < n {
+		e.side = make([]int64, n)
+		e.mid = make([]int64, n)
+	}
+	side, mid := e.side[:n], e.mid[:n]
+	for i := range left {
+		side[i] = left[i] - right[i]
+		mid[i] = (left[i] + right[i]) >> 1
+	}
+	l := analyze(left, bps, e.opt.MaxLPCOrder)
+	r := analyze(right, bps, e.opt.MaxLPCOrder)
+	s := analyze(side, bps+1, e.opt.MaxLPCOrder)
+	m := analyze(mid, bps, e.opt.MaxLPCOrder)
+
+	code, best := uint64(1), []*subframe{l, r}
+	size := l.bits + r.bits
+	if l.bits+s.bits < size {
+		code, best, size = leftSide, []*subframe{l, s}, l.bits+s.bits
+	}
+	if s.bits+r.bits < size {
+		code, best, size = sideRight, []*subframe{s, r}, s.bits+r.bits
+	}
+	if m.bits+s.bits < size {
+		code, best = midSide, []*subframe{m, s}
+	}
+	return code, best
+}
+
+// writeUTF8 writes the frame number coded like an extended UTF-8 character.
+func writeUTF8(b *bitWriter, v uint64) {
+	if v < 0x80 {
+		b.write(v, 8)
+		return
+	}
+	// An n byte code holds 5*n+1 bits.
+	n := uint(2)
+	for v >= 1<<(5*n+1) {
+		n++
+	}
+	b.write(0xFF<<(8-n)|v>>(6*(n-1)), 8)
+	for i := int(n) - 2; i >= 0; i-- {
+		b.write(0x80|v>>(6*uint(i))&0x3F, 8)
+	}
+}
+
+func log2(n int) int {
+	k := 0
+	for n > 1 {
+		n >>= 1
+		k++
+	}
+	return k
+}
+
+// Writer writes a FLAC stream in blocks of samples. The STREAMINFO block is
+// updated with the final sample count and MD5 signature in Close.
+type Writer struct {
+	w        io.WriteSeeker
+	metadata []MetadataBlock
+	start    int64
+	enc      *encoder
+	closed   bool
+}
+
+// NewWriter writes the stream header with the given format and metadata to w.
+// Samples are written with Write and Close must be called at the end. A nil
+// *Options selects the default encoder options.
+func NewWriter(w io.WriteSeeker, f Format, metadata []MetadataBlock, o *Options) (*Writer, error) {
+	if err := validateMetadata(metadata); err != nil {
+		return nil, err
+	}
+	enc, err := newEncoder(w, f, o)
+	if err != nil {
+		return nil, err
+	}
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(encodeHeader(enc.streamInfo(), metadata)); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, metadata: metadata, start: start, enc: enc}, nil
+}
+
+// Write appends samples to the stream. src must contain one slice per channel,
+// all of the same length. Samples are buffered until a whole frame is
+// complete.
+func (w *Writer) Write(src [][]float64) error {
+	if w.closed {
+		return errors.New("flac: Write after Close")
+	}
+	if len(src) != w.enc.format.ChannelCount {
+		return errors.New("flac: Write needs one buffer per channel")
+	}
+	for _, c := range src {
+		if len(c) != len(src[0]) {
+			return errors.New("flac: channels have different lengths")
+		}
+	}
+	return w.enc.write(src)
+}
+
+// WriteFloat32 is like Write but takes float32 samples.
+func (w *Writer) WriteFloat32(src [][]float32) error {
+	src64 := make([][]float64, len(src))
+	for i, c := range src {
+		src64[i] = make([]float64, len(c))
+		for j, x := range c {
+			src64[i][j] = float64(x)
+		}
+	}
+	return w.Write(src64)
+}
+
+// Close writes the last frame and updates the STREAMINFO block. It does not
+// close the underlying io.WriteSeeker.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.enc.flush(); err != nil {
+		return err
+	}
+	end, err := w.w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Seek(w.start, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(encodeHeader(w.enc.streamInfo(), w.metadata)); err != nil {
+		return err
+	}
+	_, err = w.w.Seek(end, io.SeekStart)
+	return err
+}
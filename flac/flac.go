@@ -0,0 +1,270 @@
+// Package flac decodes and encodes FLAC (Free Lossless Audio Codec) streams.
+// Samples are deinterleaved into one slice per channel and normalized to the
+// range [-1,1].
+//
+// The decoder supports all block sizes, sample rates, bit depths from 4 to 32
+// bits, all channel decorrelation modes and verifies frame CRCs and the MD5
+// signature of the decoded audio. The encoder uses fixed and LPC predictors
+// with Rice coded residuals and chooses the best stereo decorrelation for each
+// frame.
+package flac
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/gonutz/dsp/pcm"
+)
+
+// ErrMD5Mismatch is returned at the end of a stream if the MD5 signature of
+// the decoded samples does not match the one stored in the stream.
+var ErrMD5Mismatch = errors.New("flac: MD5 signature of decoded audio does not match")
+
+// Format describes the samples in a stream.
+type Format struct {
+	SampleRate    int
+	ChannelCount  int
+	BitsPerSample int
+}
+
+func (f Format) validate() error {
+	if f.SampleRate <= 0 || f.SampleRate >= 1<<20 {
+		return errors.New("flac: invalid sample rate")
+	}
+	if f.ChannelCount < 1 || f.ChannelCount > 8 {
+		return errors.New("flac: invalid channel count")
+	}
+	if f.BitsPerSample < 4 || f.BitsPerSample > 32 {
+		return errors.New("flac: invalid bits per sample")
+	}
+	return nil
+}
+
+// BlockType is the type of a metadata block.
+type BlockType byte
+
+// These are the metadata block types defined by the FLAC format.
+const (
+	StreamInfo    BlockType = 0
+	Padding       BlockType = 1
+	Application   BlockType = 2
+	SeekTable     BlockType = 3
+	VorbisComment BlockType = 4
+	CueSheet      BlockType = 5
+	Picture       BlockType = 6
+)
+
+// MetadataBlock is a metadata block other than STREAMINFO. Its contents are
+// not parsed.
+type MetadataBlock struct {
+	Type BlockType
+	Data []byte
+}
+
+// File is a complete FLAC stream in memory.
+type File struct {
+	Format
+	// Metadata holds all metadata blocks except the STREAMINFO block, which
+	// is generated from Format and the samples.
+	Metadata []MetadataBlock
+	// Samples contains one slice per channel, all of the same length.
+	Samples [][]float64
+}
+
+// Float32 returns the samples converted to float32.
+func (f *File) Float32() [][]float32 {
+	return pcm.Float32(f.Samples)
+}
+
+// Decode reads a whole FLAC stream from r.
+func Decode(r io.Reader) (*File, error) {
+	fr, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([][]float64, fr.Format.ChannelCount)
+	block := make([][]float64, fr.Format.ChannelCount)
+	for i := range block {
+		block[i] = make([]float64, 4096)
+		if n := fr.FrameCount(); n > 0 && n < 1<<28 {
+			samples[i] = make([]float64, 0, n)
+		}
+	}
+	for {
+		n, err := fr.Read(block)
+		for i := range samples {
+			samples[i] = append(samples[i], block[i][:n]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &File{
+		Format:   fr.Format,
+		Metadata: fr.Metadata,
+		Samples:  samples,
+	}, nil
+}
+
+// Options control the encoder. A nil *Options selects the defaults.
+type Options struct {
+	// BlockSize is the number of samples per channel in one frame. It must be
+	// in the range 16 to 65535, 0 selects the default of 4096.
+	BlockSize int
+	// MaxLPCOrder is the highest order of linear predictors that the encoder
+	// tries, at most 32. 0 selects the default of 8, a negative value turns
+	// off LPC and only uses fixed predictors.
+	MaxLPCOrder int
+}
+
+func (o *Options) withDefaults() (Options, error) {
+	var opt Options
+	if o != nil {
+		opt = *o
+	}
+	if opt.BlockSize == 0 {
+		opt.BlockSize = 4096
+	}
+	if opt.BlockSize < 16 || opt.BlockSize > 65535 {
+		return opt, errors.New("flac: block size must be in the range 16 to 65535")
+	}
+	if opt.MaxLPCOrder == 0 {
+		opt.MaxLPCOrder = 8
+	}
+	if opt.MaxLPCOrder < 0 {
+		opt.MaxLPCOrder = 0
+	}
+	if opt.MaxLPCOrder > 32 {
+		return opt, errors.New("flac: LPC order must be at most 32")
+	}
+	return opt, nil
+}
+
+// Encode writes f as a FLAC stream to w. All channels in f.Samples must have
+// the same length and there must be f.ChannelCount of them. Samples are
+// rounded to f.BitsPerSample bits and clamped to [-1,1).
+func Encode(w io.Writer, f *File, o *Options) error {
+	if len(f.Samples) != f.ChannelCount {
+		return errors.New("flac: number of sample channels does not match format")
+	}
+	for _, c := range f.Samples {
+		if len(c) != len(f.Samples[0]) {
+			return errors.New("flac: channels have different lengths")
+		}
+	}
+	if err := validateMetadata(f.Metadata); err != nil {
+		return err
+	}
+	var frames bytes.Buffer
+	e, err := newEncoder(&frames, f.Format, o)
+	if err != nil {
+		return err
+	}
+	if err := e.write(f.Samples); err != nil {
+		return err
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	if _, err := w.Write(encodeHeader(e.streamInfo(), f.Metadata)); err != nil {
+		return err
+	}
+	_, err = frames.WriteTo(w)
+	return err
+}
+
+// streamInfo is the contents of the STREAMINFO metadata block.
+type streamInfo struct {
+	minBlockSize int
+	maxBlockSize int
+	minFrameSize int
+	maxFrameSize int
+	format       Format
+	sampleCount  int64
+	md5          [16]byte
+}
+
+const streamInfoSize = 34
+
+func (s *streamInfo) encode() []byte {
+	var b bitWriter
+	b.write(uint64(s.minBlockSize), 16)
+	b.write(uint64(s.maxBlockSize), 16)
+	b.write(uint64(s.minFrameSize), 24)
+	b.write(uint64(s.maxFrameSize), 24)
+	b.write(uint64(s.format.SampleRate), 20)
+	b.write(uint64(s.format.ChannelCount-1), 3)
+	b.write(uint64(s.format.BitsPerSample-1), 5)
+	b.write(uint64(s.sampleCount), 36)
+	return append(b.buf, s.md5[:]...)
+}
+
+func decodeStreamInfo(data []byte) (streamInfo, error) {
+	var s streamInfo
+	if len(data) < streamInfoSize {
+		return s, errors.New("flac: STREAMINFO block is too short")
+	}
+	b := bitReader{r: bytes.NewReader(data)}
+	s.minBlockSize = int(b.read(16))
+	s.maxBlockSize = int(b.read(16))
+	s.minFrameSize = int(b.read(24))
+	s.maxFrameSize = int(b.read(24))
+	s.format.SampleRate = int(b.read(20))
+	s.format.ChannelCount = int(b.read(3)) + 1
+	s.format.BitsPerSample = int(b.read(5)) + 1
+	s.sampleCount = int64(b.read(36))
+	copy(s.md5[:], data[18:34])
+	if s.format.SampleRate == 0 {
+		return s, errors.New("flac: invalid sample rate")
+	}
+	if s.format.BitsPerSample < 4 {
+		return s, errors.New("flac: invalid bits per sample")
+	}
+	return s, nil
+}
+
+// encodeHeader returns the stream marker and all metadata blocks.
+func encodeHeader(info streamInfo, metadata []MetadataBlock) []byte {
+	b := []byte("fLaC")
+	blocks := append([]MetadataBlock{{Type: StreamInfo, Data: info.encode()}}, metadata...)
+	for i, block := range blocks {
+		header := byte(block.Type) & 0x7F
+		if i == len(blocks)-1 {
+			header |= 0x80
+		}
+		n := len(block.Data)
+		b = append(b, header, byte(n>>16), byte(n>>8), byte(n))
+		b = append(b, block.Data...)
+	}
+	return b
+}
+
+func validateMetadata(metadata []MetadataBlock) error {
+	for _, block := range metadata {
+		if block.Type == StreamInfo || block.Type >= 127 {
+			return errors.New("flac: invalid metadata block type")
+		}
+		if len(block.Data) >= 1<<24 {
+			return errors.New("flac: metadata block is too large")
+		}
+	}
+	return nil
+}
+
+// toInt converts a normalized sample to an integer of the given bit depth.
+func toInt(x float64, bits int) int64 {
+	scale := math.Ldexp(1, bits-1)
+	v := math.Floor(x*scale + 0.5)
+	if v < -scale || math.IsNaN(v) {
+		v = -scale
+	}
+	if v > scale-1 {
+		v = scale - 1
+	}
+	return int64(v)
+}
@@ -0,0 +1,561 @@
+package flac
+
+import (
+	"bytes"
+	"crypto/md5"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+// stream builds a FLAC stream from a STREAMINFO block and the given frames.
+func stream(info streamInfo, frames ...[]byte) []byte {
+	b := encodeHeader(info, nil)
+	for _, f := range frames {
+		b = append(b, f...)
+	}
+	return b
+}
+
+// frame builds a frame from the header bytes without CRC and the subframes
+// that body writes. It adds both CRCs.
+func frame(header []byte, body func(b *bitWriter)) []byte {
+	b := bitWriter{buf: append([]byte(nil), header...)}
+	b.write(uint64(crc8(header)), 8)
+	body(&b)
+	b.align()
+	b.write(uint64(crc16(b.buf)), 16)
+	return b.buf
+}
+
+// handBuiltStream has two stereo frames of 4 samples each. The first uses
+// left/side coding with a constant and a verbatim subframe. The second uses
+// independent channels with a fixed and an LPC subframe, the latter with
+// wasted bits and an escaped residual partition.
+func handBuiltStream(md5sum [16]byte) []byte {
+	info := streamInfo{
+		minBlockSize: 4,
+		maxBlockSize: 4,
+		format:       Format{SampleRate: 44100, ChannelCount: 2, BitsPerSample: 16},
+		sampleCount:  8,
+		md5:          md5sum,
+	}
+	frame1 := frame([]byte{0xFF, 0xF8, 0x69, 0x88, 0x00, 0x03}, func(b *bitWriter) {
+		// left: constant 1000
+		b.write(0x00, 8)
+		b.write(1000, 16)
+		// side: verbatim with 17 bits
+		b.write(0x02, 8)
+		for _, v := range []int64{0, 1, -1, 2000} {
+			b.writeSigned(v, 17)
+		}
+	})
+	frame2 := frame([]byte{0xFF, 0xF8, 0x69, 0x18, 0x01, 0x03}, func(b *bitWriter) {
+		// left: fixed order 1, warm-up 10, residual 2, -1, 0 with Rice
+		// parameter 1
+		b.write(0x12, 8)
+		b.write(10, 16)
+		b.write(0, 2)   // method
+		b.write(0, 4)   // partition order
+		b.write(1, 4)   // parameter
+		b.write(0x2, 4) // 4 = 001 0
+		b.write(0x3, 2) // 1 = 1 1
+		b.write(0x2, 2) // 0 = 1 0
+		// right: LPC order 1 with one wasted bit, predicting the previous
+		// sample with coefficient 2 and shift 1
+		b.write(0x41, 8)
+		b.write(1, 1) // wasted bits - 1 in unary
+		b.writeSigned(-2, 15)
+		b.write(3, 4) // precision - 1
+		b.write(1, 5) // shift
+		b.writeSigned(2, 4)
+		b.write(0, 2)  // method
+		b.write(0, 4)  // partition order
+		b.write(15, 4) // escape
+		b.write(3, 5)  // bits per residual
+		b.writeSigned(0, 3)
+		b.writeSigned(1, 3)
+		b.writeSigned(1, 3)
+	})
+	return stream(info, frame1, frame2)
+}
+
+var handBuiltSamples = [][]int64{
+	{1000, 1000, 1000, 1000, 10, 12, 11, 11},
+	{1000, 999, 1001, -1000, -4, -4, -2, 0},
+}
+
+// md5Of computes the MD5 signature of 16 bit samples.
+func md5Of(channels [][]int64) [16]byte {
+	var b []byte
+	for i := range channels[0] {
+		for _, c := range channels {
+			b = append(b, byte(c[i]), byte(c[i]>>8))
+		}
+	}
+	return md5.Sum(b)
+}
+
+func TestDecodeHandBuiltStream(t *testing.T) {
+	f, err := Decode(bytes.NewReader(handBuiltStream(md5Of(handBuiltSamples))))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Format, Format{SampleRate: 44100, ChannelCount: 2, BitsPerSample: 16})
+	check.Eq(t, len(f.Metadata), 0)
+	want := make([][]float64, 2)
+	for i, c := range handBuiltSamples {
+		for _, v := range c {
+			want[i] = append(want[i], float64(v)/32768)
+		}
+	}
+	check.Eq(t, f.Samples, want)
+}
+
+// referenceFiles are the decoding examples D.1 and D.3 of RFC 9639, which
+// were encoded by libFLAC. The first has two verbatim subframes with wasted
+// bits, the second an LPC subframe of order 3.
+var referenceFiles = []struct {
+	name    string
+	format  Format
+	samples [][]int64
+}{
+	{
+		name:    "rfc9639-d1.flac",
+		format:  Format{SampleRate: 44100, ChannelCount: 2, BitsPerSample: 16},
+		samples: [][]int64{{25588}, {10416}},
+	},
+	{
+		name:   "rfc9639-d3.flac",
+		format: Format{SampleRate: 32000, ChannelCount: 1, BitsPerSample: 8},
+		samples: [][]int64{{
+			0, 79, 111, 78, 8, -61, -90, -68, -13, 42, 67, 53,
+			13, -27, -46, -38, -12, 14, 24, 19, 6, -4, -5, 0,
+		}},
+	},
+}
+
+// pcmMD5 computes the MD5 signature of samples with the given number of bits,
+// as stored in STREAMINFO.
+func pcmMD5(channels [][]int64, bits int) [16]byte {
+	var b []byte
+	for i := range channels[0] {
+		for _, c := range channels {
+			for shift := 0; shift < bits; shift += 8 {
+				b = append(b, byte(c[i]>>uint(shift)))
+			}
+		}
+	}
+	return md5.Sum(b)
+}
+
+func TestDecodeReferenceFiles(t *testing.T) {
+	for _, ref := range referenceFiles {
+		data, err := ioutil.ReadFile("testdata/" + ref.name)
+		check.Eq(t, err, nil, ref.name)
+		// The expected samples are the ones that the reference encoder
+		// signed, independent of the decoder.
+		var signature [16]byte
+		copy(signature[:], data[26:42])
+		check.Eq(t, pcmMD5(ref.samples, ref.format.BitsPerSample), signature, ref.name)
+
+		f, err := Decode(bytes.NewReader(data))
+		check.Eq(t, err, nil, ref.name)
+		check.Eq(t, f.Format, ref.format, ref.name)
+		scale := math.Ldexp(1, ref.format.BitsPerSample-1)
+		want := make([][]float64, len(ref.samples))
+		for i, c := range ref.samples {
+			for _, v := range c {
+				want[i] = append(want[i], float64(v)/scale)
+			}
+		}
+		check.Eq(t, f.Samples, want, ref.name)
+	}
+}
+
+func TestEncoderSignsReferenceSamples(t *testing.T) {
+	for _, ref := range referenceFiles {
+		scale := math.Ldexp(1, ref.format.BitsPerSample-1)
+		in := &File{Format: ref.format, Samples: make([][]float64, len(ref.samples))}
+		for i, c := range ref.samples {
+			for _, v := range c {
+				in.Samples[i] = append(in.Samples[i], float64(v)/scale)
+			}
+		}
+		var b bytes.Buffer
+		check.Eq(t, Encode(&b, in, &Options{BlockSize: 16}), nil, ref.name)
+		var signature [16]byte
+		copy(signature[:], b.Bytes()[26:42])
+		check.Eq(t, signature, pcmMD5(ref.samples, ref.format.BitsPerSample), ref.name)
+		out, err := Decode(&b)
+		check.Eq(t, err, nil, ref.name)
+		check.Eq(t, out.Samples, in.Samples, ref.name)
+	}
+}
+
+// libFLACStereoSignals returns 16 bit stereo signals that favor left/side,
+// right/side and mid/side coding in libFLAC's exhaustive stereo mode: one
+// silent channel makes the other one equal to the side channel, and two
+// channels that differ by noise make the mid channel the smoothest.
+func libFLACStereoSignals() map[string][][]float64 {
+	music := quantized(2, 8192, 16, 7)
+	noise := quantized(3, 8192, 12, 8)[2]
+	silence := make([]float64, 8192)
+	mid, side := make([]float64, 8192), make([]float64, 8192)
+	for i := range mid {
+		mid[i] = music[0][i] + noise[i]/16
+		side[i] = music[0][i] - noise[i]/16
+	}
+	return map[string][][]float64{
+		"left-side":  {silence, music[1]},
+		"right-side": {music[1], silence},
+		"mid-side":   {mid, side},
+	}
+}
+
+// TestLibFLACInterop checks that libFLAC's flac command line tool accepts the
+// encoder's output and that files encoded by it decode correctly. It is
+// skipped if flac is not installed.
+func TestLibFLACInterop(t *testing.T) {
+	tool, err := exec.LookPath("flac")
+	if err != nil {
+		t.Skip("the flac command line tool is not installed")
+	}
+	version, err := exec.Command(tool, "--version").Output()
+	check.Eq(t, err, nil)
+	t.Logf("testing against %s", bytes.TrimSpace(version))
+
+	dir, err := ioutil.TempDir("", "flac")
+	check.Eq(t, err, nil)
+	defer os.RemoveAll(dir)
+	format := Format{SampleRate: 44100, ChannelCount: 2, BitsPerSample: 16}
+
+	for name, samples := range libFLACStereoSignals() {
+		var b bytes.Buffer
+		check.Eq(t, Encode(&b, &File{Format: format, Samples: samples}, nil), nil, name)
+		path := filepath.Join(dir, name+".flac")
+		check.Eq(t, ioutil.WriteFile(path, b.Bytes(), 0666), nil, name)
+		out, err := exec.Command(tool, "--test", "--silent", path).CombinedOutput()
+		check.Eq(t, err, nil, name, string(out))
+
+		var raw []byte
+		for i := range samples[0] {
+			for _, c := range samples {
+				v := int16(c[i] * 32768)
+				raw = append(raw, byte(v), byte(v>>8))
+			}
+		}
+		rawPath := filepath.Join(dir, name+".raw")
+		check.Eq(t, ioutil.WriteFile(rawPath, raw, 0666), nil, name)
+		encoded := filepath.Join(dir, name+"-libflac.flac")
+		out, err = exec.Command(tool, "--silent", "--force-raw-format",
+			"--endian=little", "--sign=signed", "--channels=2", "--bps=16",
+			"--sample-rate=44100", "--mid-side", "-o", encoded, rawPath,
+		).CombinedOutput()
+		check.Eq(t, err, nil, name, string(out))
+		data, err := ioutil.ReadFile(encoded)
+		check.Eq(t, err, nil, name)
+		f, err := Decode(bytes.NewReader(data))
+		check.Eq(t, err, nil, name)
+		check.Eq(t, f.Format, format, name)
+		check.Eq(t, f.Samples, samples, name)
+	}
+}
+
+func TestDecodeVerifiesMD5(t *testing.T) {
+	_, err := Decode(bytes.NewReader(handBuiltStream([16]byte{1})))
+	check.Eq(t, err, ErrMD5Mismatch)
+
+	// An all-zero signature means that it was not computed.
+	f, err := Decode(bytes.NewReader(handBuiltStream([16]byte{})))
+	check.Eq(t, err, nil)
+	check.Eq(t, len(f.Samples[0]), 8)
+}
+
+func TestCorruptFramesAreReported(t *testing.T) {
+	valid := handBuiltStream(md5Of(handBuiltSamples))
+	headerSize := 4 + 4 + streamInfoSize
+	for i := headerSize; i < len(valid); i++ {
+		corrupt := append([]byte(nil), valid...)
+		corrupt[i] ^= 0x10
+		_, err := Decode(bytes.NewReader(corrupt))
+		check.Neq(t, err, nil, i)
+	}
+	_, err := Decode(bytes.NewReader(valid[:len(valid)-1]))
+	check.Eq(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestInvalidStreamsAreReported(t *testing.T) {
+	info := streamInfo{format: Format{SampleRate: 8000, ChannelCount: 1, BitsPerSample: 8}}
+	header := encodeHeader(info, nil)
+	streams := [][]byte{
+		nil,
+		[]byte("fLaX"),
+		header[:20],
+		append([]byte("fLaC\x81\x00\x00\x00"), header[4:]...),
+	}
+	for i, s := range streams {
+		_, err := Decode(bytes.NewReader(s))
+		check.Neq(t, err, nil, i)
+	}
+}
+
+func TestID3TagIsSkipped(t *testing.T) {
+	tag := []byte("ID3\x03\x00\x00\x00\x00\x00\x05hello")
+	s := append(tag, handBuiltStream([16]byte{})...)
+	f, err := Decode(bytes.NewReader(s))
+	check.Eq(t, err, nil)
+	check.Eq(t, len(f.Samples[1]), 8)
+}
+
+func TestStereoDecorrelationModes(t *testing.T) {
+	left := []int64{5, -3, 0, 100}
+	right := []int64{2, -4, 7, -100}
+	side := []int64{3, 1, -7, 200}
+	mid := []int64{3, -4, 3, 0}
+
+	f := [][]int64{append([]int64(nil), left...), append([]int64(nil), side...)}
+	decorrelate(f, leftSide)
+	check.Eq(t, f, [][]int64{left, right})
+
+	f = [][]int64{append([]int64(nil), side...), append([]int64(nil), right...)}
+	decorrelate(f, sideRight)
+	check.Eq(t, f, [][]int64{left, right})
+
+	f = [][]int64{append([]int64(nil), mid...), append([]int64(nil), side...)}
+	decorrelate(f, midSide)
+	check.Eq(t, f, [][]int64{left, right})
+}
+
+// quantized returns channels of test signals that are exactly representable
+// with the given number of bits.
+func quantized(channels, n, bits int, seed int64) [][]float64 {
+	rnd := rand.New(rand.NewSource(seed))
+	scale := math.Ldexp(1, bits-1)
+	samples := make([][]float64, channels)
+	for c := range samples {
+		samples[c] = make([]float64, n)
+		for i := range samples[c] {
+			var x float64
+			switch c % 4 {
+			case 0:
+				x = 0.7*math.Sin(float64(i)*0.01) + 0.01*rnd.Float64()
+			case 1:
+				x = 0.6*math.Sin(float64(i)*0.0103+1) + 0.2*math.Sin(float64(i)*0.3)
+			case 2:
+				x = 2*rnd.Float64() - 1
+			case 3:
+				if i/50%2 == 0 {
+					x = 0.5
+				} else {
+					x = -0.5
+				}
+			}
+			v := math.Floor(x * scale)
+			samples[c][i] = math.Max(-scale, math.Min(scale-1, v)) / scale
+		}
+	}
+	return samples
+}
+
+func TestEncodeDecodeIsLossless(t *testing.T) {
+	for _, bits := range []int{4, 8, 10, 12, 16, 20, 24, 32} {
+		for _, channels := range []int{1, 2, 3} {
+			for _, opt := range []*Options{
+				nil,
+				{BlockSize: 1000, MaxLPCOrder: 32},
+				{BlockSize: 192, MaxLPCOrder: -1},
+				{BlockSize: 16},
+			} {
+				in := &File{
+					Format:  Format{SampleRate: 48000, ChannelCount: channels, BitsPerSample: bits},
+					Samples: quantized(channels, 5000, bits, int64(bits*channels)),
+				}
+				var b bytes.Buffer
+				check.Eq(t, Encode(&b, in, opt), nil, bits, channels, opt)
+				out, err := Decode(&b)
+				check.Eq(t, err, nil, bits, channels, opt)
+				check.Eq(t, out.Format, in.Format)
+				check.Eq(t, out.Samples, in.Samples, bits, channels, opt)
+			}
+		}
+	}
+}
+
+func TestStereoSignalsAreDecorrelated(t *testing.T) {
+	mono := quantized(1, 20000, 16, 1)[0]
+	var b bytes.Buffer
+	in := &File{
+		Format:  Format{SampleRate: 22050, ChannelCount: 2, BitsPerSample: 16},
+		Samples: [][]float64{mono, mono},
+	}
+	check.Eq(t, Encode(&b, in, nil), nil)
+	stereoSize := b.Len()
+
+	b.Reset()
+	in.ChannelCount = 1
+	in.Samples = [][]float64{mono}
+	check.Eq(t, Encode(&b, in, nil), nil)
+	monoSize := b.Len()
+	// The identical second channel is coded as a constant side channel.
+	check.Eq(t, stereoSize < monoSize+monoSize/10, true)
+}
+
+func TestPredictionCompressesSmoothSignals(t *testing.T) {
+	n := 44100
+	samples := make([]float64, n)
+	for i := range samples {
+		x := 0.5*math.Sin(float64(i)*0.05) + 0.3*math.Sin(float64(i)*0.011)
+		samples[i] = math.Floor(x*32768) / 32768
+	}
+	in := &File{
+		Format:  Format{SampleRate: 44100, ChannelCount: 1, BitsPerSample: 16},
+		Samples: [][]float64{samples},
+	}
+	var lpc, fixed bytes.Buffer
+	check.Eq(t, Encode(&lpc, in, nil), nil)
+	check.Eq(t, Encode(&fixed, in, &Options{MaxLPCOrder: -1}), nil)
+	check.Eq(t, lpc.Len() < n*2/4, true)
+	check.Eq(t, lpc.Len() < fixed.Len(), true)
+}
+
+func TestSilenceAndSpecialSampleRates(t *testing.T) {
+	for _, rate := range []int{8000, 11025, 37000, 100000, 655350, 1000001} {
+		in := &File{
+			Format:  Format{SampleRate: rate, ChannelCount: 1, BitsPerSample: 16},
+			Samples: [][]float64{make([]float64, 10)},
+		}
+		var b bytes.Buffer
+		err := Encode(&b, in, nil)
+		if rate >= 1<<20 {
+			check.Neq(t, err, nil)
+			continue
+		}
+		check.Eq(t, err, nil, rate)
+		out, err := Decode(&b)
+		check.Eq(t, err, nil, rate)
+		check.Eq(t, out.Format, in.Format, rate)
+		check.Eq(t, out.Samples, in.Samples, rate)
+	}
+}
+
+func TestSamplesAreRoundedAndClamped(t *testing.T) {
+	in := &File{
+		Format:  Format{SampleRate: 8000, ChannelCount: 1, BitsPerSample: 8},
+		Samples: [][]float64{{2, -2, 0.3 / 128, 0.6 / 128, math.NaN()}},
+	}
+	var b bytes.Buffer
+	check.Eq(t, Encode(&b, in, nil), nil)
+	out, err := Decode(&b)
+	check.Eq(t, err, nil)
+	check.Eq(t, out.Samples, [][]float64{{127.0 / 128, -1, 0, 1.0 / 128, -1}})
+}
+
+func TestMetadataRoundTrips(t *testing.T) {
+	in := &File{
+		Format: Format{SampleRate: 44100, ChannelCount: 1, BitsPerSample: 16},
+		Metadata: []MetadataBlock{
+			{Type: VorbisComment, Data: []byte("comments")},
+			{Type: Padding, Data: make([]byte, 100)},
+		},
+		Samples: [][]float64{{0, 0.5}},
+	}
+	var b bytes.Buffer
+	check.Eq(t, Encode(&b, in, nil), nil)
+	out, err := Decode(&b)
+	check.Eq(t, err, nil)
+	check.Eq(t, out, in)
+}
+
+// seekBuffer is an in-memory io.WriteSeeker.
+type seekBuffer struct {
+	data []byte
+	pos  int
+}
+
+func (b *seekBuffer) Write(p []byte) (int, error) {
+	if b.pos+len(p) > len(b.data) {
+		b.data = append(b.data, make([]byte, b.pos+len(p)-len(b.data))...)
+	}
+	copy(b.data[b.pos:], p)
+	b.pos += len(p)
+	return len(p), nil
+}
+
+func (b *seekBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		b.pos = int(offset)
+	case io.SeekCurrent:
+		b.pos += int(offset)
+	case io.SeekEnd:
+		b.pos = len(b.data) + int(offset)
+	}
+	return int64(b.pos), nil
+}
+
+func TestStreamingWriteAndReadMatchesBatchFunctions(t *testing.T) {
+	format := Format{SampleRate: 8000, ChannelCount: 2, BitsPerSample: 16}
+	meta := []MetadataBlock{{Type: Application, Data: []byte("test")}}
+	samples := quantized(2, 10000, 16, 5)
+	opt := &Options{BlockSize: 1024}
+
+	var buf seekBuffer
+	w, err := NewWriter(&buf, format, meta, opt)
+	check.Eq(t, err, nil)
+	for i := 0; i < len(samples[0]); i += 3000 {
+		end := i + 3000
+		if end > len(samples[0]) {
+			end = len(samples[0])
+		}
+		check.Eq(t, w.Write([][]float64{samples[0][i:end], samples[1][i:end]}), nil)
+	}
+	check.Eq(t, w.Close(), nil)
+
+	var batch bytes.Buffer
+	check.Eq(t, Encode(&batch, &File{Format: format, Metadata: meta, Samples: samples}, opt), nil)
+	check.Eq(t, buf.data, batch.Bytes())
+
+	r, err := NewReader(bytes.NewReader(buf.data))
+	check.Eq(t, err, nil)
+	check.Eq(t, r.FrameCount(), 10000)
+	check.Eq(t, r.Metadata, meta)
+	var left, right []float32
+	block := [][]float32{make([]float32, 777), make([]float32, 777)}
+	for {
+		n, err := r.ReadFloat32(block)
+		left = append(left, block[0][:n]...)
+		right = append(right, block[1][:n]...)
+		if err == io.EOF {
+			break
+		}
+		check.Eq(t, err, nil)
+	}
+	check.Eq(t, len(left), 10000)
+	for i := range left {
+		check.Eq(t, left[i], float32(samples[0][i]))
+		check.Eq(t, right[i], float32(samples[1][i]))
+	}
+}
+
+func TestEncodeChecksInput(t *testing.T) {
+	format := Format{SampleRate: 8000, ChannelCount: 2, BitsPerSample: 16}
+	var b bytes.Buffer
+	check.Neq(t, Encode(&b, &File{Format: format, Samples: [][]float64{{1}}}, nil), nil)
+	check.Neq(t, Encode(&b, &File{Format: format, Samples: [][]float64{{1}, {1, 2}}}, nil), nil)
+	check.Neq(t, Encode(&b, &File{
+		Format:   format,
+		Metadata: []MetadataBlock{{Type: StreamInfo}},
+		Samples:  [][]float64{{1}, {1}},
+	}, nil), nil)
+	samples := [][]float64{{1}, {1}}
+	check.Neq(t, Encode(&b, &File{Format: format, Samples: samples}, &Options{BlockSize: 15}), nil)
+	check.Neq(t, Encode(&b, &File{Format: format, Samples: samples}, &Options{MaxLPCOrder: 33}), nil)
+	format.BitsPerSample = 33
+	check.Neq(t, Encode(&b, &File{Format: format, Samples: samples}, nil), nil)
+}
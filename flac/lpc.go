@@ -0,0 +1,377 @@
+package flac
+
+import (
+	"math"
+	"math/bits"
+)
+
+// subframe is the encoding that was chosen for one channel of a frame.
+type subframe struct {
+	typ int
+	// samples are the channel's samples shifted right by wasted bits, bps is
+	// the number of bits per sample after that shift.
+	samples []int64
+	bps     uint
+	wasted  uint
+	// order, coefficients, precision and shift describe the predictor of
+	// fixed and LPC subframes.
+	order        int
+	coefficients []int64
+	precision    uint
+	shift        uint
+	residual     []int64
+	rice         riceCoding
+	// bits is the size of the subframe.
+	bits int
+}
+
+// analyze finds the smallest encoding of the samples x with bps bits each.
+func analyze(x []int64, bps uint, maxLPCOrder int) *subframe {
+	constant := true
+	var or int64
+	for _, v := range x {
+		constant = constant && v == x[0]
+		or |= v
+	}
+	if constant {
+		return &subframe{typ: subframeConstant, samples: x, bps: bps, bits: 8 + int(bps)}
+	}
+
+	s := &subframe{typ: subframeVerbatim, samples: x, bps: bps}
+	header := 8
+	if wasted := uint(bits.TrailingZeros64(uint64(or))); wasted > 0 {
+		if wasted >= bps {
+			wasted = bps - 1
+		}
+		s.wasted = wasted
+		s.bps -= wasted
+		s.samples = make([]int64, len(x))
+		for i, v := range x {
+			s.samples[i] = v >> wasted
+		}
+		header += int(wasted)
+	}
+	s.bits = header + len(x)*int(s.bps)
+
+	n := len(x)
+	residual := make([]int64, n)
+	for order := 0; order <= 4 && order < n; order++ {
+		if !computeResidual(residual, s.samples, fixedCoefficients[order], 0) {
+			continue
+		}
+		rice, ok := bestRice(residual, order)
+		size := header + order*int(s.bps) + rice.bits
+		if ok && size < s.bits {
+			s.typ = subframeFixed + order
+			s.order = order
+			s.coefficients = fixedCoefficients[order]
+			s.shift = 0
+			s.residual = append(s.residual[:0], residual...)
+			s.rice = rice
+			s.bits = size
+		}
+	}
+
+	if maxLPCOrder >= n {
+		maxLPCOrder = n - 1
+	}
+	if maxLPCOrder <= 0 {
+		return s
+	}
+	predictors := lpcPredictors(s.samples, maxLPCOrder)
+	for order := 1; order <= len(predictors); order++ {
+		precision := lpcPrecision(n, s.bps, order)
+		coefficients, shift, ok := quantize(predictors[order-1], precision)
+		if !ok || !computeResidual(residual, s.samples, coefficients, shift) {
+			continue
+		}
+		rice, ok := bestRice(residual, order)
+		size := header + order*int(s.bps) + 4 + 5 + order*int(precision) + rice.bits
+		if ok && size < s.bits {
+			s.typ = subframeLPC + order - 1
+			s.order = order
+			s.coefficients = coefficients
+			s.precision = precision
+			s.shift = shift
+			s.residual = append(s.residual[:0], residual...)
+			s.rice = rice
+			s.bits = size
+		}
+	}
+	return s
+}
+
+// computeResidual computes the prediction error of x into residual, starting
+// at the predictor order. It returns false if a residual does not fit in 32
+// bits, which decoders do not have to support.
+func computeResidual(residual, x, coefficients []int64, shift uint) bool {
+	order := len(coefficients)
+	for i := order; i < len(x); i++ {
+		var sum int64
+		for j, c := range coefficients {
+			sum += c * x[i-1-j]
+		}
+		r := x[i] - sum>>shift
+		if r < math.MinInt32 || r > math.MaxInt32 {
+			return false
+		}
+		residual[i] = r
+	}
+	return true
+}
+
+// lpcPredictors returns the linear predictors of orders 1 to maxOrder for the
+// samples x. The first coefficient of each is applied to the most recent
+// sample. Fewer predictors are returned if a higher order does not improve
+// the prediction.
+func lpcPredictors(x []int64, maxOrder int) [][]float64 {
+	// Apply a Tukey window with half of the block tapered to reduce the
+	// spectral leakage of the block's edges.
+	n := len(x)
+	windowed := make([]float64, n)
+	taper := n / 4
+	for i, v := range x {
+		w := 1.0
+		if i < taper {
+			w = 0.5 - 0.5*math.Cos(math.Pi*float64(i)/float64(taper))
+		} else if i >= n-taper {
+			w = 0.5 - 0.5*math.Cos(math.Pi*float64(n-1-i)/float64(taper))
+		}
+		windowed[i] = float64(v) * w
+	}
+	r := make([]float64, maxOrder+1)
+	for lag := range r {
+		var sum float64
+		for i := lag; i < n; i++ {
+			sum += windowed[i] * windowed[i-lag]
+		}
+		r[lag] = sum
+	}
+
+	// Levinson-Durbin recursion.
+	var predictors [][]float64
+	a := make([]float64, maxOrder)
+	prev := make([]float64, maxOrder)
+	err := r[0]
+	for i := 0; i < maxOrder && err > 0; i++ {
+		acc := r[i+1]
+		for j := 0; j < i; j++ {
+			acc -= a[j] * r[i-j]
+		}
+		k := acc / err
+		copy(prev, a[:i])
+		a[i] = k
+		for j := 0; j < i; j++ {
+			a[j] = prev[j] - k*prev[i-1-j]
+		}
+		err *= 1 - k*k
+		predictors = append(predictors, append([]float64(nil), a[:i+1]...))
+	}
+	return predictors
+}
+
+// lpcPrecision returns the number of bits for quantized LPC coefficients. Short
+// blocks use less precision because the coefficients make up a larger part of
+// the frame.
+func lpcPrecision(blockSize int, bps uint, order int) uint {
+	precision := uint(13)
+	switch {
+	case blockSize <= 192:
+		precision = 7
+	case blockSize <= 384:
+		precision = 8
+	case blockSize <= 576:
+		precision = 9
+	case blockSize <= 1152:
+		precision = 10
+	case blockSize <= 2304:
+		precision = 11
+	case blockSize <= 4608:
+		precision = 12
+	}
+	// Keep the prediction sums within 32 bits where possible.
+	limit := 32 - int(bps) - bits.Len(uint(order))
+	if int(precision) > limit {
+		precision = uint(limit)
+	}
+	if precision < 5 {
+		precision = 5
+	}
+	return precision
+}
+
+// quantize converts the predictor coefficients to integers with the given
+// number of bits, scaled by 2^shift. The rounding error of each coefficient is
+// carried over to the next one.
+func quantize(predictor []float64, precision uint) ([]int64, uint, bool) {
+	var max float64
+	for _, c := range predictor {
+		max = math.Max(max, math.Abs(c))
+	}
+	if max == 0 || math.IsNaN(max) || math.IsInf(max, 0) {
+		return nil, 0, false
+	}
+	_, exp := math.Frexp(max)
+	shift := int(precision) - 1 - exp
+	if shift < 0 {
+		return nil, 0, false
+	}
+	if shift > 15 {
+		shift = 15
+	}
+	limit := int64(1)<<(precision-1) - 1
+	coefficients := make([]int64, len(predictor))
+	var e float64
+	for i, c := range predictor {
+		e += math.Ldexp(c, shift)
+		q := int64(math.Floor(e + 0.5))
+		if q > limit {
+			q = limit
+		}
+		if q < -limit-1 {
+			q = -limit - 1
+		}
+		coefficients[i] = q
+		e -= float64(q)
+	}
+	return coefficients, uint(shift), true
+}
+
+// riceCoding describes how a residual is split into partitions and the Rice
+// parameter of each partition.
+type riceCoding struct {
+	method         uint
+	partitionOrder uint
+	parameters     []uint
+	bits           int
+}
+
+// maxPartitionOrder limits the search for the best partitioning.
+const maxPartitionOrder = 8
+
+// maxRiceParameter is the largest parameter of the 5 bit Rice coding method;
+// 31 is the escape code.
+const maxRiceParameter = 30
+
+// bestRice finds the partition order and Rice parameters that code the
+// residual[order:] with the fewest bits. The sizes are estimates.
+func bestRice(residual []int64, order int) (riceCoding, bool) {
+	n := len(residual)
+	maxOrder := uint(0)
+	for maxOrder < maxPartitionOrder && n%(2<<maxOrder) == 0 && n>>(maxOrder+1) >= order {
+		maxOrder++
+	}
+
+	// Sum up the zig-zag coded residuals for the finest partitioning, coarser
+	// partitions are combined from these.
+	partitionSize := n >> maxOrder
+	sums := make([]uint64, 1<<maxOrder)
+	for i := order; i < n; i++ {
+		r := residual[i]
+		sums[i/partitionSize] += uint64(r<<1 ^ r>>63)
+	}
+
+	best := riceCoding{bits: -1}
+	for p := int(maxOrder); p >= 0; p-- {
+		coding := riceCoding{partitionOrder: uint(p), bits: 6}
+		size := n >> uint(p)
+		for i, sum := range sums {
+			count := size
+			if i == 0 {
+				count -= order
+			}
+			k, bits := riceParameter(sum, count)
+			coding.parameters = append(coding.parameters, k)
+			coding.bits += bits
+			if k > 14 {
+				coding.method = 1
+			}
+		}
+		paramBits := 4
+		if coding.method == 1 {
+			paramBits = 5
+		}
+		coding.bits += paramBits * len(sums)
+		if best.bits < 0 || coding.bits < best.bits {
+			best = coding
+		}
+		// Merge neighboring partitions for the next coarser order.
+		for i := range sums[:len(sums)/2] {
+			sums[i] = sums[2*i] + sums[2*i+1]
+		}
+		sums = sums[:len(sums)/2]
+	}
+	return best, best.bits < 1<<30
+}
+
+// riceParameter returns the Rice parameter that codes count values summing up
+// to sum with the fewest bits and the estimated size.
+func riceParameter(sum uint64, count int) (uint, int) {
+	bestK, bestBits := uint(0), uint64(math.MaxUint64)
+	for k := uint(0); k <= maxRiceParameter; k++ {
+		bits := uint64(count)*uint64(k+1) + sum>>k
+		if bits < bestBits {
+			bestK, bestBits = k, bits
+		}
+		if sum>>k == 0 {
+			break
+		}
+	}
+	if bestBits > 1<<40 {
+		bestBits = 1 << 40
+	}
+	return bestK, int(bestBits)
+}
+
+func (s *subframe) write(b *bitWriter) {
+	b.write(0, 1)
+	b.write(uint64(s.typ), 6)
+	if s.wasted > 0 {
+		b.write(1, 1)
+		b.writeUnary(uint64(s.wasted - 1))
+	} else {
+		b.write(0, 1)
+	}
+
+	switch {
+	case s.typ == subframeConstant:
+		b.writeSigned(s.samples[0], s.bps)
+	case s.typ == subframeVerbatim:
+		for _, v := range s.samples {
+			b.writeSigned(v, s.bps)
+		}
+	default:
+		for _, v := range s.samples[:s.order] {
+			b.writeSigned(v, s.bps)
+		}
+		if s.typ >= subframeLPC {
+			b.write(uint64(s.precision-1), 4)
+			b.write(uint64(s.shift), 5)
+			for _, c := range s.coefficients {
+				b.writeSigned(c, s.precision)
+			}
+		}
+		s.writeResidual(b)
+	}
+}
+
+func (s *subframe) writeResidual(b *bitWriter) {
+	rice := s.rice
+	b.write(uint64(rice.method), 2)
+	b.write(uint64(rice.partitionOrder), 4)
+	paramBits := uint(4)
+	if rice.method == 1 {
+		paramBits = 5
+	}
+	size := len(s.residual) >> rice.partitionOrder
+	i := s.order
+	for p, k := range rice.parameters {
+		b.write(uint64(k), paramBits)
+		for end := (p + 1) * size; i < end; i++ {
+			r := s.residual[i]
+			u := uint64(r<<1 ^ r>>63)
+			b.writeUnary(u >> k)
+			b.write(u, k)
+		}
+	}
+}
@@ -5,6 +5,7 @@ package main
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -12,19 +13,21 @@ func main() {
 	os.MkdirAll("dsp32/dsp", 0666)
 	os.MkdirAll("dsp64/dsp", 0666)
 
-	dsp, err := ioutil.ReadFile("dsp.go")
+	files, err := filepath.Glob("*.go")
 	check(err)
-	dsp32 := strings.Replace(string(dsp), "FLOAT", "float32", -1)
-	dsp64 := strings.Replace(string(dsp), "FLOAT", "float64", -1)
-	check(ioutil.WriteFile("dsp32/dsp/dsp.go", []byte(dsp32), 0666))
-	check(ioutil.WriteFile("dsp64/dsp/dsp.go", []byte(dsp64), 0666))
-
-	dspTest, err := ioutil.ReadFile("dsp_test.go")
-	check(err)
-	dspTest32 := strings.Replace(string(dspTest), "FLOAT", "float32", -1)
-	dspTest64 := strings.Replace(string(dspTest), "FLOAT", "float64", -1)
-	check(ioutil.WriteFile("dsp32/dsp/dsp_test.go", []byte(dspTest32), 0666))
-	check(ioutil.WriteFile("dsp64/dsp/dsp_test.go", []byte(dspTest64), 0666))
+	for _, file := range files {
+		if file == "gen.go" || file == "float.go" {
+			continue
+		}
+		code, err := ioutil.ReadFile(file)
+		check(err)
+		code32 := strings.Replace(string(code), "COMPLEX", "complex64", -1)
+		code32 = strings.Replace(code32, "FLOAT", "float32", -1)
+		code64 := strings.Replace(string(code), "COMPLEX", "complex128", -1)
+		code64 = strings.Replace(code64, "FLOAT", "float64", -1)
+		check(ioutil.WriteFile(filepath.Join("dsp32/dsp", file), []byte(code32), 0666))
+		check(ioutil.WriteFile(filepath.Join("dsp64/dsp", file), []byte(code64), 0666))
+	}
 }
 
 func check(err error) {
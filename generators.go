@@ -0,0 +1,296 @@
+package dsp
+
+import "math"
+
+// Waveform is the shape of the signal that an Oscillator generates.
+type Waveform int
+
+const (
+	// SineWave starts at 0 and rises.
+	SineWave Waveform = iota
+	// SquareWave is +1 for the first half of each cycle and -1 for the
+	// second half.
+	SquareWave
+	// SawtoothWave starts at 0, rises to +1 at half the cycle, jumps down to
+	// -1 and rises to 0 again.
+	SawtoothWave
+	// TriangleWave starts at 0, rises to +1 at a quarter of the cycle, falls
+	// to -1 at three quarters and rises to 0 again.
+	TriangleWave
+	// PulseWave is +1 for the first DutyCycle of each cycle and -1 for the
+	// rest.
+	PulseWave
+)
+
+// Oscillator generates a periodic waveform in blocks of arbitrary size. The
+// phase continues from one block to the next, even if the fields change in
+// between, e.g. to sweep the frequency.
+//
+// The square, sawtooth, triangle and pulse waves are band-limited with
+// polynomial corrections around their discontinuities (PolyBLEP and
+// PolyBLAMP), which removes most of the aliasing of the naive waveforms.
+type Oscillator struct {
+	Waveform  Waveform
+	Amplitude FLOAT
+	// Frequency is in Hz, or cycles per sample if SampleRate is 1.
+	Frequency  FLOAT
+	SampleRate FLOAT
+	// DutyCycle is the fraction of each cycle for which a PulseWave is high,
+	// from 0 to 1.
+	DutyCycle FLOAT
+
+	// phase is the current position in the cycle from 0 to 1, startPhase is
+	// the phase that Reset goes back to.
+	phase, startPhase float64
+}
+
+// NewOscillator returns an Oscillator with the given starting phase in
+// radians. The DutyCycle is 0.5.
+func NewOscillator(waveform Waveform, amplitude, frequency, phase, sampleRate FLOAT) *Oscillator {
+	p := float64(phase) / (2 * math.Pi)
+	p -= math.Floor(p)
+	return &Oscillator{
+		Waveform:   waveform,
+		Amplitude:  amplitude,
+		Frequency:  frequency,
+		SampleRate: sampleRate,
+		DutyCycle:  0.5,
+		phase:      p,
+		startPhase: p,
+	}
+}
+
+// Phase returns the phase of the next sample in radians, from 0 to 2*pi.
+func (o *Oscillator) Phase() FLOAT {
+	return FLOAT(2 * math.Pi * o.phase)
+}
+
+// Reset sets the phase back to the start.
+func (o *Oscillator) Reset() {
+	o.phase = o.startPhase
+}
+
+// Read fills out with the next samples.
+func (o *Oscillator) Read(out []FLOAT) {
+	step := float64(o.Frequency) / float64(o.SampleRate)
+	if math.IsNaN(step) || math.IsInf(step, 0) {
+		step = 0
+	}
+	dt := math.Abs(step)
+	duty := math.Max(0, math.Min(1, float64(o.DutyCycle)))
+	a := float64(o.Amplitude)
+	for i := range out {
+		p := o.phase
+		var v float64
+		switch o.Waveform {
+		case SquareWave:
+			v = pulse(p, 0.5, dt)
+		case SawtoothWave:
+			t := frac(p + 0.5)
+			v = 2*t - 1 - polyBLEP(t, dt)
+		case TriangleWave:
+			v = 1 - 4*math.Abs(frac(p+0.25)-0.5)
+			// The slope changes by -8 at the top and +8 at the bottom.
+			v += 4 * dt * (polyBLAMP(frac(p+0.25), dt) - polyBLAMP(frac(p+0.75), dt))
+		case PulseWave:
+			v = pulse(p, duty, dt)
+		default:
+			v = math.Sin(2 * math.Pi * p)
+		}
+		out[i] = FLOAT(a * v)
+		o.phase = frac(p + step)
+	}
+}
+
+// Generate returns the next n samples.
+func (o *Oscillator) Generate(n int) []FLOAT {
+	if n < 0 {
+		n = 0
+	}
+	a := make([]FLOAT, n)
+	o.Read(a)
+	return a
+}
+
+// pulse returns the band-limited pulse wave at phase p which is +1 for the
+// first duty part of the cycle and -1 for the rest.
+func pulse(p, duty, dt float64) float64 {
+	if duty <= 0 {
+		return -1
+	}
+	if duty >= 1 {
+		return 1
+	}
+	v := -1.0
+	if p < duty {
+		v = 1
+	}
+	return v + polyBLEP(p, dt) - polyBLEP(frac(p-duty), dt)
+}
+
+// polyBLEP returns the correction for a step from -1 to +1 at phase 0. t is
+// the phase from 0 to 1 and dt the phase increment per sample.
+func polyBLEP(t, dt float64) float64 {
+	if dt <= 0 {
+		return 0
+	}
+	if t < dt {
+		t /= dt
+		return 2*t - t*t - 1
+	}
+	if t > 1-dt {
+		t = (t - 1) / dt
+		return t*t + 2*t + 1
+	}
+	return 0
+}
+
+// polyBLAMP returns the correction for a corner at phase 0 where the slope
+// increases by 2 per sample. It is the integral of polyBLEP.
+func polyBLAMP(t, dt float64) float64 {
+	if dt <= 0 {
+		return 0
+	}
+	if t < dt {
+		t = 1 - t/dt
+		return t * t * t / 3
+	}
+	if t > 1-dt {
+		t = 1 + (t-1)/dt
+		return t * t * t / 3
+	}
+	return 0
+}
+
+func frac(x float64) float64 {
+	return x - math.Floor(x)
+}
+
+// Sine returns n samples of amplitude*sin(2*pi*frequency*t + phase) where t is
+// the time of each sample at the given sample rate, starting at 0.
+func Sine(n int, amplitude, frequency, phase, sampleRate FLOAT) []FLOAT {
+	return NewOscillator(SineWave, amplitude, frequency, phase, sampleRate).Generate(n)
+}
+
+// Cosine returns n samples of amplitude*cos(2*pi*frequency*t + phase) where t
+// is the time of each sample at the given sample rate, starting at 0.
+func Cosine(n int, amplitude, frequency, phase, sampleRate FLOAT) []FLOAT {
+	return Sine(n, amplitude, frequency, phase+math.Pi/2, sampleRate)
+}
+
+// Square returns n samples of a band-limited square wave, see SquareWave.
+func Square(n int, amplitude, frequency, phase, sampleRate FLOAT) []FLOAT {
+	return NewOscillator(SquareWave, amplitude, frequency, phase, sampleRate).Generate(n)
+}
+
+// Sawtooth returns n samples of a band-limited sawtooth wave, see
+// SawtoothWave.
+func Sawtooth(n int, amplitude, frequency, phase, sampleRate FLOAT) []FLOAT {
+	return NewOscillator(SawtoothWave, amplitude, frequency, phase, sampleRate).Generate(n)
+}
+
+// Triangle returns n samples of a band-limited triangle wave, see
+// TriangleWave.
+func Triangle(n int, amplitude, frequency, phase, sampleRate FLOAT) []FLOAT {
+	return NewOscillator(TriangleWave, amplitude, frequency, phase, sampleRate).Generate(n)
+}
+
+// Pulse returns n samples of a band-limited pulse train that is high for the
+// fraction dutyCycle of each cycle, see PulseWave.
+func Pulse(n int, amplitude, frequency, dutyCycle, phase, sampleRate FLOAT) []FLOAT {
+	o := NewOscillator(PulseWave, amplitude, frequency, phase, sampleRate)
+	o.DutyCycle = dutyCycle
+	return o.Generate(n)
+}
+
+// ChirpMethod selects how the frequency of a Chirp changes over time.
+type ChirpMethod int
+
+const (
+	// LinearChirp changes the frequency at a constant rate.
+	LinearChirp ChirpMethod = iota
+	// QuadraticChirp changes the frequency with the square of the time, so
+	// it changes slowly at first and fast at the end.
+	QuadraticChirp
+	// LogarithmicChirp multiplies the frequency by a constant factor per time,
+	// so it spends the same time in each octave. Both frequencies must be
+	// positive, otherwise the chirp is linear.
+	LogarithmicChirp
+)
+
+// Chirp returns n samples of a sine wave with a frequency that goes from
+// startFrequency at the first sample to endFrequency at the last sample. The
+// phase is the starting phase in radians.
+func Chirp(n int, amplitude, startFrequency, endFrequency, phase, sampleRate FLOAT, method ChirpMethod) []FLOAT {
+	if n < 0 {
+		n = 0
+	}
+	a := make([]FLOAT, n)
+	f0, f1 := float64(startFrequency), float64(endFrequency)
+	// duration is the time of the last sample.
+	duration := float64(n-1) / float64(sampleRate)
+	if method == LogarithmicChirp && !(f0 > 0 && f1 > 0) {
+		method = LinearChirp
+	}
+	for i := range a {
+		t := float64(i) / float64(sampleRate)
+		// cycles is the integral of the frequency from 0 to t.
+		cycles := f0 * t
+		if duration > 0 {
+			switch method {
+			case QuadraticChirp:
+				cycles += (f1 - f0) * t * t * t / (3 * duration * duration)
+			case LogarithmicChirp:
+				if f0 != f1 {
+					k := math.Log(f1 / f0)
+					cycles = f0 * duration / k * (math.Exp(k*t/duration) - 1)
+				}
+			default:
+				cycles += (f1 - f0) * t * t / (2 * duration)
+			}
+		}
+		a[i] = FLOAT(float64(amplitude) * math.Sin(2*math.Pi*cycles+float64(phase)))
+	}
+	return a
+}
+
+// Impulse returns n samples that are 0 except for a 1 at the given position.
+// If position is outside of the signal, all samples are 0.
+func Impulse(n, position int) []FLOAT {
+	a := make([]FLOAT, max0(n))
+	if 0 <= position && position < len(a) {
+		a[position] = 1
+	}
+	return a
+}
+
+// Step returns n samples that are 0 before the given position and 1 from the
+// position on.
+func Step(n, position int) []FLOAT {
+	a := make([]FLOAT, max0(n))
+	for i := range a {
+		if i >= position {
+			a[i] = 1
+		}
+	}
+	return a
+}
+
+// Ramp returns n samples that are 0 before the given position and then rise by
+// 1 per sample, starting at 0 at the position.
+func Ramp(n, position int) []FLOAT {
+	a := make([]FLOAT, max0(n))
+	for i := range a {
+		if i >= position {
+			a[i] = FLOAT(i - position)
+		}
+	}
+	return a
+}
+
+func max0(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,304 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Goertzel returns the discrete Fourier transform of a at a single frequency
+// in Hz, which is the sum of a[n] * exp(-2*pi*i*n*frequency/sampleRate). The
+// frequency does not have to fall on an FFT bin. For a few frequencies this
+// is faster than an FFT. See GoertzelFilter for the streaming version.
+func Goertzel(a []FLOAT, frequency, sampleRate FLOAT) COMPLEX {
+	g := NewGoertzelFilter(frequency, sampleRate)
+	g.Add(a)
+	return g.Result()
+}
+
+// GoertzelFilter computes the discrete Fourier transform at a single
+// frequency over all samples added since it was created or Reset. It uses
+// the generalized Goertzel algorithm by Sysel and Rajmic which is exact for
+// any frequency.
+type GoertzelFilter struct {
+	omega float64
+	coeff float64
+	// s1 and s2 are the last two values of the resonator.
+	s1, s2 float64
+	count  int
+}
+
+// NewGoertzelFilter returns a GoertzelFilter for the given frequency in Hz.
+func NewGoertzelFilter(frequency, sampleRate FLOAT) *GoertzelFilter {
+	omega := 2 * math.Pi * float64(frequency) / float64(sampleRate)
+	return &GoertzelFilter{omega: omega, coeff: 2 * math.Cos(omega)}
+}
+
+// Add feeds the next samples into the filter.
+func (g *GoertzelFilter) Add(a []FLOAT) {
+	s1, s2 := g.s1, g.s2
+	for _, x := range a {
+		s1, s2 = float64(x)+g.coeff*s1-s2, s1
+	}
+	g.s1, g.s2 = s1, s2
+	g.count += len(a)
+}
+
+// Count returns the number of samples added since the last Reset.
+func (g *GoertzelFilter) Count() int {
+	return g.count
+}
+
+// Result returns the discrete Fourier transform at the filter frequency over
+// all samples added since the last Reset.
+func (g *GoertzelFilter) Result() COMPLEX {
+	if g.count == 0 {
+		return 0
+	}
+	y := complex(g.s1, 0) - cmplx.Exp(complex(0, -g.omega))*complex(g.s2, 0)
+	// y is the transform with the phase referenced to the last sample.
+	return COMPLEX(y * cmplx.Exp(complex(0, -g.omega*float64(g.count-1))))
+}
+
+// Power returns the squared magnitude of Result. It is cheaper to compute.
+func (g *GoertzelFilter) Power() FLOAT {
+	return FLOAT(g.power())
+}
+
+func (g *GoertzelFilter) power() float64 {
+	return g.s1*g.s1 + g.s2*g.s2 - g.coeff*g.s1*g.s2
+}
+
+// Amplitude returns the amplitude of a sine wave at the filter frequency that
+// produces the current Result, 2*|Result|/Count. It is exact if the samples
+// hold a whole number of cycles.
+func (g *GoertzelFilter) Amplitude() FLOAT {
+	if g.count == 0 {
+		return 0
+	}
+	return FLOAT(2 * math.Sqrt(g.power()) / float64(g.count))
+}
+
+// Reset clears the filter so it can be used for the next block of samples.
+func (g *GoertzelFilter) Reset() {
+	g.s1, g.s2 = 0, 0
+	g.count = 0
+}
+
+var (
+	dtmfRows    = [4]FLOAT{697, 770, 852, 941}
+	dtmfColumns = [4]FLOAT{1209, 1336, 1477, 1633}
+	dtmfKeys    = [4]string{"123A", "456B", "789C", "*0#D"}
+)
+
+// DTMFTone returns n samples of the dual tone multi frequency signal for the
+// given key, one of 0-9, A-D, * and #. Each of the two tones has the given
+// amplitude. An unknown key produces silence.
+func DTMFTone(key byte, n int, amplitude, sampleRate FLOAT) []FLOAT {
+	for row, keys := range dtmfKeys {
+		for col := range keys {
+			if keys[col] == key {
+				return Add(
+					Sine(n, amplitude, dtmfRows[row], 0, sampleRate),
+					Sine(n, amplitude, dtmfColumns[col], 0, sampleRate),
+				)
+			}
+		}
+	}
+	return make([]FLOAT, max0(n))
+}
+
+// DTMFOptions configures a DTMFDecoder. Zero values select the defaults,
+// which follow ITU-T Q.24.
+type DTMFOptions struct {
+	// MinDuration is the shortest tone in seconds that is detected, 0 means
+	// 0.04.
+	MinDuration FLOAT
+	// MinAmplitude is the smallest amplitude of each of the two tones, 0
+	// means 0.005, which is about -46 dB relative to full scale.
+	MinAmplitude FLOAT
+	// MaxNormalTwist is the largest ratio in dB by which the high frequency
+	// tone may be weaker than the low frequency tone, 0 means 8.
+	MaxNormalTwist FLOAT
+	// MaxReverseTwist is the largest ratio in dB by which the low frequency
+	// tone may be weaker than the high frequency tone, 0 means 4.
+	MaxReverseTwist FLOAT
+	// MinRelativePower is the smallest fraction of the signal power that must
+	// be in the two tones, 0 means 0.5. This rejects speech and music.
+	MinRelativePower FLOAT
+}
+
+// DTMFDigit is a key detected by a DTMFDecoder.
+type DTMFDigit struct {
+	// Key is one of 0-9, A-D, * and #.
+	Key byte
+	// Start and End are the times of the tone in seconds.
+	Start, End FLOAT
+}
+
+// DTMFDecoder detects dual tone multi frequency signals, the tones of
+// telephone keys. The signal is analyzed in blocks of 12.75 ms, 102 samples at
+// 8 kHz. A key is detected if its tones are the strongest in enough
+// consecutive blocks to last at least MinDuration, allowing for one block that
+// is only partially covered by the tone.
+type DTMFDecoder struct {
+	options    DTMFOptions
+	sampleRate FLOAT
+	blockSize  int
+	rows       [4]*GoertzelFilter
+	columns    [4]*GoertzelFilter
+	energy     float64
+	// blocks is the number of whole blocks analyzed.
+	blocks int
+	// key is the key in the current run of blocks and runStart the block in
+	// which the run started. key is 0 for no key.
+	key      byte
+	runStart int
+}
+
+// NewDTMFDecoder returns a DTMFDecoder for signals at the given sample rate.
+// If the sample rate is not positive, the decoder detects no digits.
+func NewDTMFDecoder(sampleRate FLOAT, options DTMFOptions) *DTMFDecoder {
+	if options.MinDuration <= 0 {
+		options.MinDuration = 0.04
+	}
+	if options.MinAmplitude <= 0 {
+		options.MinAmplitude = 0.005
+	}
+	if options.MaxNormalTwist <= 0 {
+		options.MaxNormalTwist = 8
+	}
+	if options.MaxReverseTwist <= 0 {
+		options.MaxReverseTwist = 4
+	}
+	if options.MinRelativePower <= 0 {
+		options.MinRelativePower = 0.5
+	}
+	d := &DTMFDecoder{
+		options:    options,
+		sampleRate: sampleRate,
+		blockSize:  int(math.Floor(float64(sampleRate)*102/8000 + 0.5)),
+	}
+	if d.blockSize < 1 {
+		d.blockSize = 1
+	}
+	for i := range d.rows {
+		d.rows[i] = NewGoertzelFilter(dtmfRows[i], sampleRate)
+		d.columns[i] = NewGoertzelFilter(dtmfColumns[i], sampleRate)
+	}
+	return d
+}
+
+// Process analyzes the next samples and returns all digits that ended in
+// them. A digit ends when its tone stops, call Flush at the end of the signal
+// for a tone that is still on.
+func (d *DTMFDecoder) Process(a []FLOAT) []DTMFDigit {
+	if !(d.sampleRate > 0) {
+		return nil
+	}
+	var digits []DTMFDigit
+	for len(a) > 0 {
+		n := d.blockSize - d.rows[0].Count()
+		if n > len(a) {
+			n = len(a)
+		}
+		block := a[:n]
+		a = a[n:]
+		for i := range d.rows {
+			d.rows[i].Add(block)
+			d.columns[i].Add(block)
+		}
+		for _, x := range block {
+			d.energy += float64(x) * float64(x)
+		}
+		if d.rows[0].Count() == d.blockSize {
+			key := d.blockKey()
+			d.resetBlock()
+			if key != d.key {
+				digits = d.endRun(digits)
+				d.key = key
+				d.runStart = d.blocks
+			}
+			d.blocks++
+		}
+	}
+	return digits
+}
+
+// Flush returns the digit that is still on at the end of the signal, if it is
+// long enough, and resets the decoder.
+func (d *DTMFDecoder) Flush() []DTMFDigit {
+	digits := d.endRun(nil)
+	d.Reset()
+	return digits
+}
+
+// Reset puts the decoder back into its initial state.
+func (d *DTMFDecoder) Reset() {
+	d.resetBlock()
+	d.blocks = 0
+	d.key = 0
+	d.runStart = 0
+}
+
+func (d *DTMFDecoder) resetBlock() {
+	for i := range d.rows {
+		d.rows[i].Reset()
+		d.columns[i].Reset()
+	}
+	d.energy = 0
+}
+
+// endRun appends the key of the current run to digits if it lasted long
+// enough.
+func (d *DTMFDecoder) endRun(digits []DTMFDigit) []DTMFDigit {
+	if d.key == 0 {
+		return digits
+	}
+	blockTime := float64(d.blockSize) / float64(d.sampleRate)
+	start := float64(d.runStart) * blockTime
+	end := float64(d.blocks) * blockTime
+	// The blocks at both ends of a tone are only partially covered and are
+	// detected if they are covered by more than about half.
+	if end-start+blockTime < float64(d.options.MinDuration) {
+		return digits
+	}
+	return append(digits, DTMFDigit{Key: d.key, Start: FLOAT(start), End: FLOAT(end)})
+}
+
+// blockKey returns the key detected in the current block or 0.
+func (d *DTMFDecoder) blockKey() byte {
+	strongest := func(f [4]*GoertzelFilter) (int, float64) {
+		best, power := 0, f[0].power()
+		for i := 1; i < 4; i++ {
+			if p := f[i].power(); p > power {
+				best, power = i, p
+			}
+		}
+		return best, power
+	}
+	row, rowPower := strongest(d.rows)
+	col, colPower := strongest(d.columns)
+
+	// A sine wave with amplitude A has a Goertzel power of (A*N/2)^2 and an
+	// energy of A^2*N/2 over N samples.
+	n := float64(d.blockSize)
+	minPower := float64(d.options.MinAmplitude) * n / 2
+	minPower *= minPower
+	if rowPower < minPower || colPower < minPower {
+		return 0
+	}
+	if 2*(rowPower+colPower)/n < float64(d.options.MinRelativePower)*d.energy {
+		return 0
+	}
+	twist := 10 * math.Log10(rowPower/colPower)
+	if twist > float64(d.options.MaxNormalTwist) || -twist > float64(d.options.MaxReverseTwist) {
+		return 0
+	}
+	return dtmfKeys[row][col]
+}
+
+// DecodeDTMF returns all DTMF digits in a, see DTMFDecoder.
+func DecodeDTMF(a []FLOAT, sampleRate FLOAT, options DTMFOptions) []DTMFDigit {
+	d := NewDTMFDecoder(sampleRate, options)
+	return append(d.Process(a), d.Flush()...)
+}
@@ -0,0 +1,123 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestGoertzelMatchesDFT(t *testing.T) {
+	a := randomFloats(100, 1)
+	for _, f := range []FLOAT{0, 7, 12.3, 49.5} {
+		want := complex128(0)
+		for n, x := range a {
+			want += complex(float64(x), 0) * cmplx.Exp(complex(0, -2*math.Pi*float64(f)*float64(n)/100))
+		}
+		got := Goertzel(a, f, 100)
+		check.EqEps(t, cmplx.Abs(complex128(got)-want), 0, 1e-3, f)
+
+		g := NewGoertzelFilter(f, 100)
+		g.Add(a[:33])
+		g.Add(nil)
+		g.Add(a[33:])
+		check.Eq(t, g.Count(), 100)
+		check.Eq(t, g.Result(), got)
+		check.EqEps(t, g.Power(), cmplx.Abs(want)*cmplx.Abs(want), 1e-2, f)
+	}
+	check.Eq(t, Goertzel(nil, 1, 1), COMPLEX(0))
+}
+
+func TestGoertzelAmplitudeOfSine(t *testing.T) {
+	g := NewGoertzelFilter(440, 8000)
+	g.Add(Sine(800, 0.3, 440, 1, 8000))
+	check.EqEps(t, g.Amplitude(), 0.3, 1e-5)
+	g.Reset()
+	check.Eq(t, g.Count(), 0)
+	check.Eq(t, g.Amplitude(), 0)
+	g.Add(Sine(800, 0.3, 600, 0, 8000))
+	check.EqEps(t, g.Amplitude(), 0, 1e-5)
+}
+
+// dtmfSequence returns tones for all keys, each followed by a pause, and the
+// start and end times of the tones.
+func dtmfSequence(keys string, tone, pause FLOAT, sampleRate FLOAT) (a []FLOAT, times [][2]FLOAT) {
+	for i := range keys {
+		start := FLOAT(len(a)) / sampleRate
+		a = append(a, DTMFTone(keys[i], int(tone*sampleRate), 0.2, sampleRate)...)
+		times = append(times, [2]FLOAT{start, FLOAT(len(a)) / sampleRate})
+		a = append(a, make([]FLOAT, int(pause*sampleRate))...)
+	}
+	return
+}
+
+func TestDTMFDecoderFindsAllKeys(t *testing.T) {
+	const keys = "0123456789ABCD*#"
+	for _, sampleRate := range []FLOAT{8000, 44100} {
+		a, times := dtmfSequence(keys, 0.045, 0.04, sampleRate)
+		a = Add(a, Noise(len(a), WhiteNoise, 0.02, 1))
+		digits := DecodeDTMF(a, sampleRate, DTMFOptions{})
+		check.Eq(t, len(digits), len(keys), sampleRate)
+		for i, d := range digits {
+			check.Eq(t, string(d.Key), keys[i:i+1], sampleRate)
+			check.EqEps(t, d.Start, times[i][0], 0.013, sampleRate, i)
+			check.EqEps(t, d.End, times[i][1], 0.013, sampleRate, i)
+		}
+	}
+}
+
+func TestDTMFDecoderWorksInBlocks(t *testing.T) {
+	a, _ := dtmfSequence("159#", 0.1, 0.05, 8000)
+	// The last tone lasts until the end of the signal.
+	a = a[:len(a)-400]
+	whole := DecodeDTMF(a, 8000, DTMFOptions{})
+	check.Eq(t, len(whole), 4)
+
+	d := NewDTMFDecoder(8000, DTMFOptions{})
+	var blocks []DTMFDigit
+	for i := 0; i < len(a); i += 77 {
+		end := i + 77
+		if end > len(a) {
+			end = len(a)
+		}
+		blocks = append(blocks, d.Process(a[i:end])...)
+	}
+	blocks = append(blocks, d.Flush()...)
+	check.Eq(t, blocks, whole)
+}
+
+func TestDTMFDecoderRejectsInvalidTones(t *testing.T) {
+	// A tone that is too short.
+	check.Eq(t, len(DecodeDTMF(DTMFTone('5', 160, 0.2, 8000), 8000, DTMFOptions{})), 0)
+	// A tone that is long enough.
+	check.Eq(t, len(DecodeDTMF(DTMFTone('5', 320, 0.2, 8000), 8000, DTMFOptions{})), 1)
+
+	// The high tone is 10 dB weaker than the low tone.
+	twisted := Add(
+		Sine(800, 0.3, 770, 0, 8000),
+		Sine(800, 0.3/FLOAT(math.Sqrt(10)), 1336, 0, 8000),
+	)
+	check.Eq(t, len(DecodeDTMF(twisted, 8000, DTMFOptions{})), 0)
+	check.Eq(t, len(DecodeDTMF(twisted, 8000, DTMFOptions{MaxNormalTwist: 12})), 1)
+	// The low tone is 6 dB weaker than the high tone.
+	twisted = Add(
+		Sine(800, 0.15, 770, 0, 8000),
+		Sine(800, 0.3, 1336, 0, 8000),
+	)
+	check.Eq(t, len(DecodeDTMF(twisted, 8000, DTMFOptions{})), 0)
+
+	// Most of the power is in another tone.
+	loud := Add(DTMFTone('5', 800, 0.2, 8000), Sine(800, 0.5, 400, 0, 8000))
+	check.Eq(t, len(DecodeDTMF(loud, 8000, DTMFOptions{})), 0)
+
+	// Silence and quiet tones.
+	check.Eq(t, len(DecodeDTMF(make([]FLOAT, 800), 8000, DTMFOptions{})), 0)
+	check.Eq(t, len(DecodeDTMF(DTMFTone('5', 800, 0.001, 8000), 8000, DTMFOptions{})), 0)
+	check.Eq(t, DTMFTone('x', 3, 1, 8000), []FLOAT{0, 0, 0})
+}
+
+func TestDTMFDecoderWithoutSampleRateFindsNothing(t *testing.T) {
+	check.Eq(t, len(DecodeDTMF(make([]FLOAT, 10), 0, DTMFOptions{})), 0)
+	check.Eq(t, len(DecodeDTMF(DTMFTone('5', 800, 0.2, 8000), -8000, DTMFOptions{})), 0)
+}
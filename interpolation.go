@@ -0,0 +1,388 @@
+package dsp
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// InterpolationMethod selects how an Interpolator computes values between the
+// given points.
+type InterpolationMethod int
+
+const (
+	// LinearInterpolation connects the points with straight lines.
+	LinearInterpolation InterpolationMethod = iota
+	// NearestInterpolation uses the value of the closest point. Halfway
+	// between two points, the later one is used.
+	NearestInterpolation
+	// CubicHermiteInterpolation uses cubic pieces with the slope at each
+	// point being the average of the slopes of the lines to its neighbors.
+	CubicHermiteInterpolation
+	// CatmullRomInterpolation uses cubic pieces with the slope at each point
+	// being the slope of the line between its two neighbors.
+	CatmullRomInterpolation
+	// AkimaInterpolation uses cubic pieces with slopes that follow the local
+	// trend and avoid overshooting near outliers.
+	AkimaInterpolation
+	// PCHIPInterpolation uses a piecewise cubic Hermite interpolating
+	// polynomial that preserves the shape of the data, it does not overshoot
+	// and is monotonic where the data is monotonic.
+	PCHIPInterpolation
+	// NaturalSplineInterpolation is a cubic spline with zero second
+	// derivative at both ends.
+	NaturalSplineInterpolation
+	// ClampedSplineInterpolation is a cubic spline with the slopes at the ends
+	// given by Interpolation.StartSlope and Interpolation.EndSlope.
+	ClampedSplineInterpolation
+	// NotAKnotSplineInterpolation is a cubic spline where the first two and
+	// the last two pieces are the same cubic polynomial.
+	NotAKnotSplineInterpolation
+)
+
+// Extrapolation selects what an Interpolator returns outside of the range of
+// the given points.
+type Extrapolation int
+
+const (
+	// ExtrapolateHold returns the value of the first or last point.
+	ExtrapolateHold Extrapolation = iota
+	// ExtrapolateExtend continues the first or last piece of the
+	// interpolation, e.g. the straight line through the last two points for
+	// LinearInterpolation.
+	ExtrapolateExtend
+	// ExtrapolateZero returns 0.
+	ExtrapolateZero
+	// ExtrapolateNaN returns NaN.
+	ExtrapolateNaN
+)
+
+// Interpolation configures an Interpolator. The zero value is linear
+// interpolation that holds the end values.
+type Interpolation struct {
+	Method        InterpolationMethod
+	Extrapolation Extrapolation
+	// StartSlope and EndSlope are the first derivatives at the first and last
+	// point for ClampedSplineInterpolation.
+	StartSlope, EndSlope FLOAT
+}
+
+// Interpolator evaluates a function that is given at a number of points
+// anywhere in between them. It is built once and can then be evaluated any
+// number of times.
+type Interpolator struct {
+	options Interpolation
+	x, y    []float64
+	// For uniform points, x[i] = x0 + i*step.
+	uniform  bool
+	x0, step float64
+	// Piece i is c[i][0] + c[i][1]*t + c[i][2]*t^2 + c[i][3]*t^3 with
+	// t = x - x[i].
+	c [][4]float64
+}
+
+// NewInterpolator returns an Interpolator for the values y at the positions
+// 0, 1, 2, ..., e.g. At(2.5) lies halfway between y[2] and y[3].
+func NewInterpolator(y []FLOAT, options Interpolation) *Interpolator {
+	x := make([]float64, len(y))
+	for i := range x {
+		x[i] = float64(i)
+	}
+	p := newInterpolator(x, y, options)
+	p.uniform, p.x0, p.step = true, 0, 1
+	return p
+}
+
+// NewInterpolatorXY returns an Interpolator for the points (x[i], y[i]). The x
+// values must be strictly increasing but need not be evenly spaced. x and y
+// must have the same length.
+func NewInterpolatorXY(x, y []FLOAT, options Interpolation) (*Interpolator, error) {
+	if len(x) != len(y) {
+		return nil, errors.New("dsp: interpolation x and y have different lengths")
+	}
+	xs := make([]float64, len(x))
+	for i := range x {
+		xs[i] = float64(x[i])
+		if math.IsNaN(xs[i]) || math.IsInf(xs[i], 0) {
+			return nil, errors.New("dsp: interpolation x values must be finite")
+		}
+		if i > 0 && !(xs[i] > xs[i-1]) {
+			return nil, errors.New("dsp: interpolation x values must be strictly increasing")
+		}
+	}
+	return newInterpolator(xs, y, options), nil
+}
+
+func newInterpolator(x []float64, y []FLOAT, options Interpolation) *Interpolator {
+	p := &Interpolator{
+		options: options,
+		x:       x,
+		y:       make([]float64, len(y)),
+	}
+	for i := range y {
+		p.y[i] = float64(y[i])
+	}
+	if len(x) < 2 {
+		return p
+	}
+
+	n := len(x)
+	h := make([]float64, n-1)
+	d := make([]float64, n-1)
+	for i := range h {
+		h[i] = x[i+1] - x[i]
+		d[i] = (p.y[i+1] - p.y[i]) / h[i]
+	}
+	p.c = make([][4]float64, n-1)
+	switch options.Method {
+	case NearestInterpolation:
+		// Evaluated directly from the points.
+	case CubicHermiteInterpolation, CatmullRomInterpolation, AkimaInterpolation,
+		PCHIPInterpolation, NaturalSplineInterpolation,
+		ClampedSplineInterpolation, NotAKnotSplineInterpolation:
+		p.hermite(h, d, slopes(options, x, p.y, h, d))
+	default:
+		for i := range p.c {
+			p.c[i] = [4]float64{p.y[i], d[i], 0, 0}
+		}
+	}
+	return p
+}
+
+// hermite computes the cubic pieces that go through the points with the
+// slopes m.
+func (p *Interpolator) hermite(h, d, m []float64) {
+	for i := range p.c {
+		p.c[i] = [4]float64{
+			p.y[i],
+			m[i],
+			(3*d[i] - 2*m[i] - m[i+1]) / h[i],
+			(m[i] + m[i+1] - 2*d[i]) / (h[i] * h[i]),
+		}
+	}
+}
+
+// slopes returns the first derivative at each point for the cubic methods. h
+// holds the widths of the intervals and d the slopes of the lines connecting
+// the points.
+func slopes(options Interpolation, x, y, h, d []float64) []float64 {
+	n := len(x)
+	m := make([]float64, n)
+	if n == 2 && options.Method != ClampedSplineInterpolation {
+		// Two points only determine a straight line.
+		m[0], m[1] = d[0], d[0]
+		return m
+	}
+	m[0], m[n-1] = d[0], d[n-2]
+
+	switch options.Method {
+	case CubicHermiteInterpolation:
+		for i := 1; i < n-1; i++ {
+			m[i] = (d[i-1] + d[i]) / 2
+		}
+	case CatmullRomInterpolation:
+		for i := 1; i < n-1; i++ {
+			m[i] = (y[i+1] - y[i-1]) / (x[i+1] - x[i-1])
+		}
+	case AkimaInterpolation:
+		// Extend the slopes by two on each side.
+		e := make([]float64, n+3)
+		copy(e[2:], d)
+		e[1] = 2*e[2] - e[3]
+		e[0] = 2*e[1] - e[2]
+		e[n+1] = 2*e[n] - e[n-1]
+		e[n+2] = 2*e[n+1] - e[n]
+		for i := range m {
+			w1 := math.Abs(e[i+3] - e[i+2])
+			w2 := math.Abs(e[i+1] - e[i])
+			if w1+w2 == 0 {
+				m[i] = (e[i+1] + e[i+2]) / 2
+			} else {
+				m[i] = (w1*e[i+1] + w2*e[i+2]) / (w1 + w2)
+			}
+		}
+	case PCHIPInterpolation:
+		for i := 1; i < n-1; i++ {
+			if d[i-1]*d[i] <= 0 {
+				m[i] = 0
+			} else {
+				// Weighted harmonic mean of the neighboring slopes.
+				w1 := 2*h[i] + h[i-1]
+				w2 := h[i] + 2*h[i-1]
+				m[i] = (w1 + w2) / (w1/d[i-1] + w2/d[i])
+			}
+		}
+		m[0] = pchipEndSlope(h[0], h[1], d[0], d[1])
+		m[n-1] = pchipEndSlope(h[n-2], h[n-3], d[n-2], d[n-3])
+	case NaturalSplineInterpolation, ClampedSplineInterpolation, NotAKnotSplineInterpolation:
+		m = splineSlopes(options, h, d)
+	}
+	return m
+}
+
+// pchipEndSlope returns the slope at an end point from a three point formula,
+// limited so that the data's shape is preserved. h0 and d0 belong to the
+// interval at the end, h1 and d1 to its neighbor.
+func pchipEndSlope(h0, h1, d0, d1 float64) float64 {
+	m := ((2*h0+h1)*d0 - h0*d1) / (h0 + h1)
+	if m*d0 <= 0 {
+		return 0
+	}
+	if d0*d1 <= 0 && math.Abs(m) > math.Abs(3*d0) {
+		return 3 * d0
+	}
+	return m
+}
+
+// splineSlopes solves the tridiagonal system for the first derivatives of a
+// cubic spline at every point.
+func splineSlopes(options Interpolation, h, d []float64) []float64 {
+	n := len(h) + 1
+	if options.Method == NotAKnotSplineInterpolation && n == 3 {
+		// The only not-a-knot spline through three points is the parabola.
+		c := (d[1] - d[0]) / (h[0] + h[1])
+		return []float64{d[0] - c*h[0], d[0] + c*h[0], d[0] + c*(h[0]+2*h[1])}
+	}
+
+	// Row i reads lower[i]*m[i-1] + diag[i]*m[i] + upper[i]*m[i+1] = b[i].
+	lower := make([]float64, n)
+	diag := make([]float64, n)
+	upper := make([]float64, n)
+	b := make([]float64, n)
+	for i := 1; i < n-1; i++ {
+		lower[i] = h[i]
+		diag[i] = 2 * (h[i-1] + h[i])
+		upper[i] = h[i-1]
+		b[i] = 3 * (h[i]*d[i-1] + h[i-1]*d[i])
+	}
+	switch options.Method {
+	case ClampedSplineInterpolation:
+		diag[0], b[0] = 1, float64(options.StartSlope)
+		diag[n-1], b[n-1] = 1, float64(options.EndSlope)
+	case NotAKnotSplineInterpolation:
+		w := h[0] + h[1]
+		diag[0] = h[1]
+		upper[0] = w
+		b[0] = ((h[0]+2*w)*h[1]*d[0] + h[0]*h[0]*d[1]) / w
+		w = h[n-2] + h[n-3]
+		diag[n-1] = h[n-3]
+		lower[n-1] = w
+		b[n-1] = (h[n-2]*h[n-2]*d[n-3] + (2*w+h[n-2])*h[n-3]*d[n-2]) / w
+	default:
+		diag[0], upper[0], b[0] = 2, 1, 3*d[0]
+		diag[n-1], lower[n-1], b[n-1] = 2, 1, 3*d[n-2]
+	}
+
+	// Thomas algorithm.
+	for i := 1; i < n; i++ {
+		f := lower[i] / diag[i-1]
+		diag[i] -= f * upper[i-1]
+		b[i] -= f * b[i-1]
+	}
+	m := make([]float64, n)
+	m[n-1] = b[n-1] / diag[n-1]
+	for i := n - 2; i >= 0; i-- {
+		m[i] = (b[i] - upper[i]*m[i+1]) / diag[i]
+	}
+	return m
+}
+
+// At returns the interpolated value at x.
+func (p *Interpolator) At(x FLOAT) FLOAT {
+	return FLOAT(p.at(float64(x)))
+}
+
+func (p *Interpolator) at(x float64) float64 {
+	n := len(p.x)
+	if n == 0 {
+		return 0
+	}
+	first, last := p.x[0], p.x[n-1]
+	if math.IsNaN(x) {
+		return x
+	}
+	if x < first || x > last {
+		switch p.options.Extrapolation {
+		case ExtrapolateZero:
+			return 0
+		case ExtrapolateNaN:
+			return math.NaN()
+		case ExtrapolateExtend:
+			if n > 1 && p.options.Method != NearestInterpolation {
+				break
+			}
+			fallthrough
+		default:
+			if x < first {
+				return p.y[0]
+			}
+			return p.y[n-1]
+		}
+	}
+	if n == 1 {
+		return p.y[0]
+	}
+
+	i := p.piece(x)
+	t := x - p.x[i]
+	if p.options.Method == NearestInterpolation {
+		if 2*t < p.x[i+1]-p.x[i] {
+			return p.y[i]
+		}
+		return p.y[i+1]
+	}
+	c := p.c[i]
+	return c[0] + t*(c[1]+t*(c[2]+t*c[3]))
+}
+
+// piece returns the index of the interval that x lies in, clamped to the first
+// and last interval.
+func (p *Interpolator) piece(x float64) int {
+	var i int
+	if p.uniform {
+		f := math.Floor((x - p.x0) / p.step)
+		if f < 0 {
+			return 0
+		}
+		if f > float64(len(p.c)-1) {
+			return len(p.c) - 1
+		}
+		i = int(f)
+	} else {
+		// Find the first point after x.
+		i = sort.SearchFloat64s(p.x, x)
+		if i < len(p.x) && p.x[i] == x {
+			i++
+		}
+		i--
+	}
+	if i < 0 {
+		i = 0
+	}
+	if i > len(p.c)-1 {
+		i = len(p.c) - 1
+	}
+	return i
+}
+
+// AtEach returns the interpolated values at all positions in x.
+func (p *Interpolator) AtEach(x []FLOAT) []FLOAT {
+	y := make([]FLOAT, len(x))
+	for i := range x {
+		y[i] = p.At(x[i])
+	}
+	return y
+}
+
+// Grid returns count interpolated values at the evenly spaced positions start,
+// start+step, start+2*step, ....
+func (p *Interpolator) Grid(start, step FLOAT, count int) []FLOAT {
+	if count < 0 {
+		count = 0
+	}
+	y := make([]FLOAT, count)
+	for i := range y {
+		y[i] = FLOAT(p.at(float64(start) + float64(i)*float64(step)))
+	}
+	return y
+}
@@ -0,0 +1,216 @@
+// Package iq reads and writes recordings of complex baseband (IQ) samples as
+// produced by software defined radios. Raw files store the in-phase (I) and
+// quadrature (Q) parts of each sample interleaved, without a header. SigMF
+// datasets add a JSON metadata file with the sample format, sample rate,
+// center frequency and annotations.
+//
+// Samples are complex128 values, normalized like in package pcm: signed
+// integers are divided by 2^(Bits-1), unsigned integers are centered around
+// 2^(Bits-1) first.
+package iq
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gonutz/dsp/pcm"
+)
+
+// These are the common raw IQ formats. The names are those used by tools like
+// GNU Radio, SoapySDR and SigMF.
+var (
+	// CU8 is unsigned 8 bit, the output of rtl_sdr.
+	CU8 = pcm.SampleFormat{Encoding: pcm.Unsigned, Bits: 8}
+	// CS8 is signed 8 bit, e.g. from HackRF.
+	CS8 = pcm.SampleFormat{Encoding: pcm.Signed, Bits: 8}
+	// CS16 is signed 16 bit little endian.
+	CS16 = pcm.SampleFormat{Encoding: pcm.Signed, Bits: 16}
+	// CF32 is 32 bit float little endian, GNU Radio's complex type.
+	CF32 = pcm.SampleFormat{Encoding: pcm.Float, Bits: 32}
+)
+
+// Reader reads raw interleaved IQ samples in blocks, which makes it possible
+// to process recordings that do not fit into memory.
+type Reader struct {
+	r      io.Reader
+	format pcm.SampleFormat
+	pcm    *pcm.Reader
+	buf    [][]float64
+	// start is the offset of the first sample in r if r is an io.Seeker.
+	start    int64
+	seekable bool
+}
+
+// readBlock is the number of samples that Reader converts at once.
+const readBlock = 4096
+
+// NewReader returns a Reader that decodes IQ samples in the given format from
+// r.
+func NewReader(r io.Reader, format pcm.SampleFormat) (*Reader, error) {
+	p, err := pcm.NewReader(r, format, 2)
+	if err != nil {
+		return nil, err
+	}
+	ir := &Reader{
+		r:      r,
+		format: format,
+		pcm:    p,
+		buf:    [][]float64{make([]float64, readBlock), make([]float64, readBlock)},
+	}
+	if s, ok := r.(io.Seeker); ok {
+		if start, err := s.Seek(0, io.SeekCurrent); err == nil {
+			ir.start, ir.seekable = start, true
+		}
+	}
+	return ir, nil
+}
+
+// Format returns the sample format.
+func (r *Reader) Format() pcm.SampleFormat {
+	return r.format
+}
+
+// Read reads the next samples into dst and returns the number of samples
+// read. At the end of the data it returns 0 and io.EOF. If the data ends in
+// the middle of a sample, io.ErrUnexpectedEOF is returned.
+func (r *Reader) Read(dst []complex128) (int, error) {
+	n := len(dst)
+	if n > readBlock {
+		n = readBlock
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	n, err := r.pcm.Read([][]float64{r.buf[0][:n], r.buf[1][:n]})
+	for k := 0; k < n; k++ {
+		dst[k] = complex(r.buf[0][k], r.buf[1][k])
+	}
+	return n, err
+}
+
+// ReadComplex64 is like Read but converts the samples to complex64.
+func (r *Reader) ReadComplex64(dst []complex64) (int, error) {
+	n := len(dst)
+	if n > readBlock {
+		n = readBlock
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	n, err := r.pcm.Read([][]float64{r.buf[0][:n], r.buf[1][:n]})
+	for k := 0; k < n; k++ {
+		dst[k] = complex(float32(r.buf[0][k]), float32(r.buf[1][k]))
+	}
+	return n, err
+}
+
+// SeekSample moves to the sample with the given index, counted from the start of
+// the data at the position where the underlying reader was when NewReader was
+// called. The underlying reader must be an io.Seeker.
+func (r *Reader) SeekSample(sample int64) error {
+	if !r.seekable {
+		return errors.New("iq: reader does not support seeking")
+	}
+	if sample < 0 {
+		return errors.New("iq: negative sample index")
+	}
+	s := r.r.(io.Seeker)
+	if _, err := s.Seek(r.start+sample*int64(2*r.format.Size()), io.SeekStart); err != nil {
+		return err
+	}
+	p, err := pcm.NewReader(r.r, r.format, 2)
+	if err != nil {
+		return err
+	}
+	r.pcm = p
+	return nil
+}
+
+// Decode reads all IQ samples from r.
+func Decode(r io.Reader, format pcm.SampleFormat) ([]complex128, error) {
+	ir, err := NewReader(r, format)
+	if err != nil {
+		return nil, err
+	}
+	var samples []complex128
+	block := make([]complex128, readBlock)
+	for {
+		n, err := ir.Read(block)
+		samples = append(samples, block[:n]...)
+		if err == io.EOF {
+			return samples, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Writer writes raw interleaved IQ samples in blocks.
+type Writer struct {
+	w      io.Writer
+	format pcm.SampleFormat
+	buf    [][]float64
+}
+
+// NewWriter returns a Writer that encodes IQ samples in the given format to
+// w. Values outside of the range of integer formats are clipped.
+func NewWriter(w io.Writer, format pcm.SampleFormat) (*Writer, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	return &Writer{
+		w:      w,
+		format: format,
+		buf:    [][]float64{make([]float64, readBlock), make([]float64, readBlock)},
+	}, nil
+}
+
+// Write writes all samples.
+func (w *Writer) Write(samples []complex128) error {
+	for len(samples) > 0 {
+		n := len(samples)
+		if n > readBlock {
+			n = readBlock
+		}
+		for k := 0; k < n; k++ {
+			w.buf[0][k], w.buf[1][k] = real(samples[k]), imag(samples[k])
+		}
+		if err := w.flush(n); err != nil {
+			return err
+		}
+		samples = samples[n:]
+	}
+	return nil
+}
+
+// WriteComplex64 is like Write for complex64 samples.
+func (w *Writer) WriteComplex64(samples []complex64) error {
+	for len(samples) > 0 {
+		n := len(samples)
+		if n > readBlock {
+			n = readBlock
+		}
+		for k := 0; k < n; k++ {
+			w.buf[0][k], w.buf[1][k] = float64(real(samples[k])), float64(imag(samples[k]))
+		}
+		if err := w.flush(n); err != nil {
+			return err
+		}
+		samples = samples[n:]
+	}
+	return nil
+}
+
+func (w *Writer) flush(n int) error {
+	return pcm.Encode(w.w, w.format, [][]float64{w.buf[0][:n], w.buf[1][:n]})
+}
+
+// Encode writes all samples to w in the given format.
+func Encode(w io.Writer, format pcm.SampleFormat, samples []complex128) error {
+	iw, err := NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+	return iw.Write(samples)
+}
@@ -0,0 +1,83 @@
+package iq
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gonutz/check"
+	"github.com/gonutz/dsp/pcm"
+)
+
+func TestRTLSDRSamplesAreCentered(t *testing.T) {
+	samples, err := Decode(bytes.NewReader([]byte{128, 0, 255, 192}), CU8)
+	check.Eq(t, err, nil)
+	check.Eq(t, samples, []complex128{complex(0, -1), complex(127.0/128, 0.5)})
+}
+
+func TestSignedSamplesAreInterleaved(t *testing.T) {
+	data := []byte{0x00, 0x40, 0x00, 0xC0, 0x00, 0x80, 0x00, 0x00}
+	samples, err := Decode(bytes.NewReader(data), CS16)
+	check.Eq(t, err, nil)
+	check.Eq(t, samples, []complex128{complex(0.5, -0.5), complex(-1, 0)})
+}
+
+func TestIncompleteSampleIsUnexpectedEOF(t *testing.T) {
+	_, err := Decode(bytes.NewReader(make([]byte, 3)), CS8)
+	check.Eq(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestSamplesRoundTripInBlocks(t *testing.T) {
+	in := make([]complex128, 3*readBlock+17)
+	for i := range in {
+		in[i] = complex(float64(i%100)/128, -float64(i%64)/64)
+	}
+	for _, f := range []pcm.SampleFormat{CU8, CS8, CS16, CF32} {
+		var b bytes.Buffer
+		check.Eq(t, Encode(&b, f, in), nil)
+		check.Eq(t, b.Len(), len(in)*2*f.Size())
+		r, err := NewReader(&b, f)
+		check.Eq(t, err, nil)
+		var out []complex128
+		block := make([]complex64, 1000)
+		for {
+			n, err := r.ReadComplex64(block)
+			for _, c := range block[:n] {
+				out = append(out, complex128(c))
+			}
+			if err == io.EOF {
+				break
+			}
+			check.Eq(t, err, nil)
+		}
+		check.Eq(t, out, in, f)
+	}
+}
+
+func TestWriterClipsIntegerSamples(t *testing.T) {
+	var b bytes.Buffer
+	check.Eq(t, Encode(&b, CS8, []complex128{complex(2, -3)}), nil)
+	check.Eq(t, b.Bytes(), []byte{127, 128})
+}
+
+func TestReaderSeeksToSample(t *testing.T) {
+	in := []complex128{complex(0, 0.5), complex(0.25, -0.25), complex(-1, 0.75)}
+	var b bytes.Buffer
+	b.WriteString("header")
+	check.Eq(t, Encode(&b, CS16, in), nil)
+	data := bytes.NewReader(b.Bytes())
+	data.Seek(6, io.SeekStart)
+
+	r, err := NewReader(data, CS16)
+	check.Eq(t, err, nil)
+	check.Eq(t, r.SeekSample(2), nil)
+	out := make([]complex128, 5)
+	n, _ := r.Read(out)
+	check.Eq(t, out[:n], in[2:])
+	check.Eq(t, r.SeekSample(0), nil)
+	n, _ = r.Read(out)
+	check.Eq(t, out[:n], in)
+
+	r, _ = NewReader(&b, CS16)
+	check.Neq(t, r.SeekSample(0), nil)
+}
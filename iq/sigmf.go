@@ -0,0 +1,178 @@
+package iq
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gonutz/dsp/pcm"
+)
+
+// SigMFVersion is the version of the SigMF specification that WriteMetadata
+// writes if the metadata does not specify one.
+const SigMFVersion = "1.0.0"
+
+// Metadata is the content of a SigMF metadata file (.sigmf-meta). The samples
+// are stored in a separate data file (.sigmf-data) as raw IQ in the format
+// given by Global.DataType, see Metadata.Format. Only the fields of the SigMF
+// core namespace are kept, extensions are dropped when reading.
+type Metadata struct {
+	Global      Global       `json:"global"`
+	Captures    []Capture    `json:"captures"`
+	Annotations []Annotation `json:"annotations"`
+}
+
+// Global describes the whole dataset.
+type Global struct {
+	// DataType is the sample format, e.g. "cf32_le" or "cu8".
+	DataType    string  `json:"core:datatype"`
+	SampleRate  float64 `json:"core:sample_rate,omitempty"`
+	Version     string  `json:"core:version"`
+	Description string  `json:"core:description,omitempty"`
+	Author      string  `json:"core:author,omitempty"`
+	Hardware    string  `json:"core:hw,omitempty"`
+	Recorder    string  `json:"core:recorder,omitempty"`
+	License     string  `json:"core:license,omitempty"`
+}
+
+// Capture describes the recording parameters starting at a sample.
+type Capture struct {
+	SampleStart int64 `json:"core:sample_start"`
+	// Frequency is the center frequency in Hz.
+	Frequency float64 `json:"core:frequency,omitempty"`
+	// DateTime is the ISO 8601 time of the first sample of the capture.
+	DateTime string `json:"core:datetime,omitempty"`
+}
+
+// Annotation marks a range of samples and optionally a frequency range.
+type Annotation struct {
+	SampleStart   int64   `json:"core:sample_start"`
+	SampleCount   int64   `json:"core:sample_count,omitempty"`
+	FreqLowerEdge float64 `json:"core:freq_lower_edge,omitempty"`
+	FreqUpperEdge float64 `json:"core:freq_upper_edge,omitempty"`
+	Label         string  `json:"core:label,omitempty"`
+	Comment       string  `json:"core:comment,omitempty"`
+}
+
+// ReadMetadata parses SigMF metadata in JSON format.
+func ReadMetadata(r io.Reader) (*Metadata, error) {
+	var m Metadata
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, errors.New("iq: invalid SigMF metadata: " + err.Error())
+	}
+	return &m, nil
+}
+
+// WriteMetadata writes m as SigMF JSON. If m.Global.Version is empty,
+// SigMFVersion is written.
+func WriteMetadata(w io.Writer, m Metadata) error {
+	if m.Global.Version == "" {
+		m.Global.Version = SigMFVersion
+	}
+	// The specification requires the lists to be present.
+	if m.Captures == nil {
+		m.Captures = []Capture{}
+	}
+	if m.Annotations == nil {
+		m.Annotations = []Annotation{}
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Format returns the sample format of the data file.
+func (m *Metadata) Format() (pcm.SampleFormat, error) {
+	return ParseDataType(m.Global.DataType)
+}
+
+// CenterFrequency returns the center frequency of the capture that contains
+// the given sample, or 0 if there is none.
+func (m *Metadata) CenterFrequency(sample int64) float64 {
+	var f float64
+	for _, c := range m.Captures {
+		if c.SampleStart <= sample {
+			f = c.Frequency
+		}
+	}
+	return f
+}
+
+// ParseDataType returns the sample format for a SigMF data type, e.g.
+// "ci16_le". Only complex types are supported, real types like "rf32_le" are
+// an error.
+func ParseDataType(dataType string) (pcm.SampleFormat, error) {
+	invalid := errors.New("iq: unsupported SigMF data type " + strconv.Quote(dataType))
+	s := dataType
+	if !strings.HasPrefix(s, "c") {
+		return pcm.SampleFormat{}, invalid
+	}
+	s = s[1:]
+	var f pcm.SampleFormat
+	if strings.HasSuffix(s, "_be") {
+		f.BigEndian = true
+		s = strings.TrimSuffix(s, "_be")
+	} else {
+		s = strings.TrimSuffix(s, "_le")
+	}
+	if s == "" {
+		return pcm.SampleFormat{}, invalid
+	}
+	switch s[0] {
+	case 'i':
+		f.Encoding = pcm.Signed
+	case 'u':
+		f.Encoding = pcm.Unsigned
+	case 'f':
+		f.Encoding = pcm.Float
+	default:
+		return pcm.SampleFormat{}, invalid
+	}
+	bits, err := strconv.Atoi(s[1:])
+	if err != nil {
+		return pcm.SampleFormat{}, invalid
+	}
+	f.Bits = bits
+	// Multi-byte types must specify the byte order, 8 bit types must not.
+	hasOrder := len(s) != len(dataType)-1
+	if (bits == 8) == hasOrder || bits == 24 || f.Validate() != nil {
+		return pcm.SampleFormat{}, invalid
+	}
+	return f, nil
+}
+
+// DataType returns the SigMF data type for the given sample format, e.g.
+// "cf32_le" for CF32.
+func DataType(format pcm.SampleFormat) (string, error) {
+	if err := format.Validate(); err != nil {
+		return "", err
+	}
+	var s string
+	switch format.Encoding {
+	case pcm.Signed:
+		s = "ci"
+	case pcm.Unsigned:
+		s = "cu"
+	case pcm.Float:
+		s = "cf"
+	default:
+		return "", errors.New("iq: sample format has no SigMF data type")
+	}
+	s += strconv.Itoa(format.Bits)
+	if format.Bits > 8 {
+		if format.BigEndian {
+			s += "_be"
+		} else {
+			s += "_le"
+		}
+	}
+	if _, err := ParseDataType(s); err != nil {
+		return "", errors.New("iq: sample format has no SigMF data type")
+	}
+	return s, nil
+}
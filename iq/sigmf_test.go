@@ -0,0 +1,93 @@
+package iq
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gonutz/check"
+	"github.com/gonutz/dsp/pcm"
+)
+
+func TestSigMFMetadataIsParsed(t *testing.T) {
+	m, err := ReadMetadata(strings.NewReader(`{
+		"global": {
+			"core:datatype": "ci16_le",
+			"core:sample_rate": 2048000,
+			"core:version": "1.0.0",
+			"core:hw": "RTL-SDR",
+			"ext:unknown": 5
+		},
+		"captures": [
+			{"core:sample_start": 0, "core:frequency": 100e6},
+			{"core:sample_start": 1000, "core:frequency": 101e6}
+		],
+		"annotations": [
+			{
+				"core:sample_start": 200,
+				"core:sample_count": 50,
+				"core:freq_lower_edge": 99.9e6,
+				"core:freq_upper_edge": 100.1e6,
+				"core:label": "FM"
+			}
+		]
+	}`))
+	check.Eq(t, err, nil)
+	check.Eq(t, m.Global.SampleRate, 2048000.0)
+	check.Eq(t, m.Global.Hardware, "RTL-SDR")
+	check.Eq(t, m.CenterFrequency(999), 100e6)
+	check.Eq(t, m.CenterFrequency(1000), 101e6)
+	check.Eq(t, m.Annotations, []Annotation{{
+		SampleStart:   200,
+		SampleCount:   50,
+		FreqLowerEdge: 99.9e6,
+		FreqUpperEdge: 100.1e6,
+		Label:         "FM",
+	}})
+	f, err := m.Format()
+	check.Eq(t, err, nil)
+	check.Eq(t, f, CS16)
+}
+
+func TestSigMFMetadataRoundTrips(t *testing.T) {
+	m := Metadata{
+		Global:   Global{DataType: "cf32_le", SampleRate: 1e6, Author: "me"},
+		Captures: []Capture{{SampleStart: 0, Frequency: 433.92e6}},
+	}
+	var b bytes.Buffer
+	check.Eq(t, WriteMetadata(&b, m), nil)
+	check.Eq(t, strings.Contains(b.String(), `"annotations": []`), true)
+	back, err := ReadMetadata(&b)
+	check.Eq(t, err, nil)
+	m.Global.Version = SigMFVersion
+	m.Annotations = []Annotation{}
+	check.Eq(t, *back, m)
+}
+
+func TestSigMFDataTypes(t *testing.T) {
+	for _, test := range []struct {
+		dataType string
+		format   pcm.SampleFormat
+	}{
+		{"cu8", CU8},
+		{"ci8", CS8},
+		{"ci16_le", CS16},
+		{"cf32_le", CF32},
+		{"cf64_be", pcm.SampleFormat{Encoding: pcm.Float, Bits: 64, BigEndian: true}},
+		{"cu16_be", pcm.SampleFormat{Encoding: pcm.Unsigned, Bits: 16, BigEndian: true}},
+		{"ci32_le", pcm.SampleFormat{Encoding: pcm.Signed, Bits: 32}},
+	} {
+		f, err := ParseDataType(test.dataType)
+		check.Eq(t, err, nil, test.dataType)
+		check.Eq(t, f, test.format, test.dataType)
+		s, err := DataType(test.format)
+		check.Eq(t, err, nil, test.dataType)
+		check.Eq(t, s, test.dataType)
+	}
+	for _, invalid := range []string{"", "c", "rf32_le", "ci16", "cu8_le", "ci24_le", "cf16_le", "cx8"} {
+		_, err := ParseDataType(invalid)
+		check.Neq(t, err, nil, invalid)
+	}
+	_, err := DataType(pcm.SampleFormat{Encoding: pcm.MuLaw, Bits: 8})
+	check.Neq(t, err, nil)
+}
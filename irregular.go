@@ -0,0 +1,302 @@
+package dsp
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// GapFill selects the values of uniformly resampled data inside of gaps in
+// the irregular input.
+type GapFill int
+
+const (
+	// GapIsInterpolated interpolates across gaps just like between any other
+	// input samples.
+	GapIsInterpolated GapFill = iota
+	// GapRepeatsPrevious repeats the last sample before the gap.
+	GapRepeatsPrevious
+	// GapIsZero fills gaps with zeros.
+	GapIsZero
+	// GapIsNaN fills gaps with NaNs.
+	GapIsNaN
+)
+
+// IrregularResampling configures ResampleIrregular.
+type IrregularResampling struct {
+	// Step is the time between two output samples. If it is not positive,
+	// the median time between input samples is used.
+	Step FLOAT
+	// Start and End are the times of the first and last output sample. If End
+	// is not greater than Start, the output covers the input times.
+	Start, End FLOAT
+	// BinAverage averages all input samples within Step/2 of an output time
+	// instead of interpolating. Bins without input samples are filled by
+	// linear interpolation between their neighbors and are marked as filled.
+	BinAverage bool
+	// Interpolation is used if BinAverage is false.
+	Interpolation Interpolation
+	// MaxGap is the longest time between two input samples that is not a gap.
+	// 0 means there are no gaps.
+	MaxGap FLOAT
+	// GapFill selects the values inside of gaps and outside of the input
+	// times.
+	GapFill GapFill
+}
+
+// UniformSignal is a uniformly sampled signal, sample i is at time
+// Start + i*Step.
+type UniformSignal struct {
+	Samples []FLOAT
+	Start   FLOAT
+	Step    FLOAT
+	// Filled is true for all samples that are not based on input data, which
+	// are samples in gaps, before the first and after the last input sample
+	// and in empty bins.
+	Filled []bool
+}
+
+// ResampleIrregular resamples the values that were measured at the given
+// times onto a uniform time grid. The times do not have to be sorted, values
+// with equal times are averaged. time and values must have the same length.
+func ResampleIrregular(time, values []FLOAT, options IrregularResampling) (*UniformSignal, error) {
+	if len(time) != len(values) {
+		return nil, errors.New("dsp: time and values have different lengths")
+	}
+	if len(time) == 0 {
+		return nil, errors.New("dsp: no samples to resample")
+	}
+	t, v, err := sortedSamples(time, values)
+	if err != nil {
+		return nil, err
+	}
+
+	step := float64(options.Step)
+	if !(step > 0) {
+		step = medianStep(t)
+	}
+	start, end := float64(options.Start), float64(options.End)
+	if !(end > start) {
+		start, end = t[0], t[len(t)-1]
+	}
+	n := int(math.Floor((end-start)/step+1e-9)) + 1
+	times := make([]float64, n)
+	for i := range times {
+		times[i] = start + float64(i)*step
+	}
+
+	out := &UniformSignal{
+		Samples: make([]FLOAT, n),
+		Start:   FLOAT(start),
+		Step:    FLOAT(step),
+		Filled:  make([]bool, n),
+	}
+	if options.BinAverage {
+		binAverage(t, v, times, step, out)
+	} else {
+		x, y := make([]FLOAT, len(t)), make([]FLOAT, len(t))
+		for i := range t {
+			x[i], y[i] = FLOAT(t[i]), FLOAT(v[i])
+		}
+		p, err := NewInterpolatorXY(x, y, options.Interpolation)
+		if err != nil {
+			return nil, err
+		}
+		for i, at := range times {
+			out.Samples[i] = FLOAT(p.at(at))
+		}
+	}
+
+	maxGap := float64(options.MaxGap)
+	previous := FLOAT(v[0])
+	for i, at := range times {
+		// The output time lies between the input samples j-1 and j.
+		j := sort.SearchFloat64s(t, at)
+		exact := j < len(t) && t[j] == at
+		outside := at < t[0] || at > t[len(t)-1]
+		gap := !outside && !exact && maxGap > 0 && t[j]-t[j-1] > maxGap
+		if !outside && !gap {
+			if !out.Filled[i] {
+				previous = out.Samples[i]
+			}
+			continue
+		}
+		out.Filled[i] = true
+		switch options.GapFill {
+		case GapRepeatsPrevious:
+			out.Samples[i] = previous
+		case GapIsZero:
+			out.Samples[i] = 0
+		case GapIsNaN:
+			out.Samples[i] = FLOAT(math.NaN())
+		}
+	}
+	return out, nil
+}
+
+// sortedSamples returns the samples sorted by time, values with equal times
+// are averaged.
+func sortedSamples(time, values []FLOAT) (t, v []float64, err error) {
+	order := make([]int, len(time))
+	for i := range order {
+		order[i] = i
+		x := float64(time[i])
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, nil, errors.New("dsp: sample times must be finite")
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return time[order[i]] < time[order[j]]
+	})
+	count := 0
+	for _, i := range order {
+		x, y := float64(time[i]), float64(values[i])
+		if len(t) > 0 && t[len(t)-1] == x {
+			count++
+			last := len(v) - 1
+			v[last] += (y - v[last]) / float64(count)
+			continue
+		}
+		t = append(t, x)
+		v = append(v, y)
+		count = 1
+	}
+	return t, v, nil
+}
+
+// medianStep returns the median time between samples or 1 for a single
+// sample.
+func medianStep(t []float64) float64 {
+	if len(t) < 2 {
+		return 1
+	}
+	d := make([]float64, len(t)-1)
+	for i := range d {
+		d[i] = t[i+1] - t[i]
+	}
+	sort.Float64s(d)
+	return d[len(d)/2]
+}
+
+// binAverage sets the output samples to the average of all input samples in
+// the bins around each output time. Empty bins are interpolated linearly.
+func binAverage(t, v, times []float64, step float64, out *UniformSignal) {
+	sums := make([]float64, len(times))
+	counts := make([]int, len(times))
+	for i := range t {
+		bin := int(math.Floor((t[i]-times[0])/step + 0.5))
+		if 0 <= bin && bin < len(times) {
+			sums[bin] += v[i]
+			counts[bin]++
+		}
+	}
+	var x, y []FLOAT
+	for i := range times {
+		if counts[i] > 0 {
+			x = append(x, FLOAT(times[i]))
+			y = append(y, FLOAT(sums[i]/float64(counts[i])))
+		}
+	}
+	p, _ := NewInterpolatorXY(x, y, Interpolation{})
+	for i := range times {
+		if counts[i] > 0 {
+			out.Samples[i] = FLOAT(sums[i] / float64(counts[i]))
+		} else {
+			out.Samples[i] = FLOAT(p.at(times[i]))
+			out.Filled[i] = true
+		}
+	}
+}
+
+// LombScargle returns the Lomb-Scargle periodogram of values that were
+// measured at the given, possibly irregular, times. The frequencies are in
+// cycles per time unit. The power is normalized to the range 0 to 1, where 1
+// means that a sine wave of that frequency fits the data perfectly. If time
+// and values have different lengths, the shorter one is used.
+func LombScargle(time, values, frequencies []FLOAT) []FLOAT {
+	n := len(time)
+	if len(values) < n {
+		n = len(values)
+	}
+	power := make([]FLOAT, len(frequencies))
+	if n == 0 {
+		return power
+	}
+
+	// Center the times and values for numerical accuracy.
+	var meanT, meanY float64
+	for i := 0; i < n; i++ {
+		meanT += float64(time[i])
+		meanY += float64(values[i])
+	}
+	meanT /= float64(n)
+	meanY /= float64(n)
+	t := make([]float64, n)
+	y := make([]float64, n)
+	var variance float64
+	for i := range t {
+		t[i] = float64(time[i]) - meanT
+		y[i] = float64(values[i]) - meanY
+		variance += y[i] * y[i]
+	}
+	if variance == 0 {
+		return power
+	}
+
+	for k, f := range frequencies {
+		w := 2 * math.Pi * float64(f)
+		// The time offset tau makes the sine and cosine terms orthogonal.
+		var s2, c2 float64
+		for _, x := range t {
+			s2 += math.Sin(2 * w * x)
+			c2 += math.Cos(2 * w * x)
+		}
+		var tau float64
+		if w != 0 {
+			tau = math.Atan2(s2, c2) / (2 * w)
+		}
+		var yc, ys, cc, ss float64
+		for i, x := range t {
+			c, s := math.Cos(w*(x-tau)), math.Sin(w*(x-tau))
+			yc += y[i] * c
+			ys += y[i] * s
+			cc += c * c
+			ss += s * s
+		}
+		var p float64
+		if cc > 0 {
+			p += yc * yc / cc
+		}
+		if ss > 0 {
+			p += ys * ys / ss
+		}
+		power[k] = FLOAT(p / variance)
+	}
+	return power
+}
+
+// LombScargleFrequencies returns evenly spaced frequencies for LombScargle.
+// The spacing is 1/(oversampling*T), where T is the time span of the data,
+// and the highest frequency is half the average sample rate. An oversampling
+// less than 1 is treated as 1.
+func LombScargleFrequencies(time []FLOAT, oversampling FLOAT) []FLOAT {
+	if len(time) < 2 {
+		return nil
+	}
+	if oversampling < 1 {
+		oversampling = 1
+	}
+	_, first, _, last := MinMax(time)
+	span := float64(last - first)
+	if !(span > 0) {
+		return nil
+	}
+	step := 1 / (float64(oversampling) * span)
+	highest := float64(len(time)) / (2 * span)
+	n := int(highest / step)
+	f := make([]FLOAT, n)
+	for i := range f {
+		f[i] = FLOAT(float64(i+1) * step)
+	}
+	return f
+}
@@ -0,0 +1,168 @@
+package dsp
+
+import "math"
+
+// NCO is a numerically controlled oscillator. It generates the complex
+// exponential exp(i*phase) = cos(phase) + i*sin(phase) with a phase that
+// advances by 2*pi*Frequency/SampleRate per sample. The phase is kept in a
+// 32 bit accumulator that wraps around without any loss of precision, so the
+// phase stays continuous across blocks and does not drift over time.
+//
+// Complex signals are represented by two slices, one for the real or in-phase
+// part (I) and one for the imaginary or quadrature part (Q).
+type NCO struct {
+	// Frequency is in Hz, or cycles per sample if SampleRate is 1. Negative
+	// frequencies make the phase go backwards.
+	Frequency  FLOAT
+	SampleRate FLOAT
+
+	phase uint32
+	// table holds cos over one whole cycle for table lookup, it is nil for
+	// direct evaluation.
+	table     []float64
+	tableBits uint
+}
+
+// ncoCycle is one cycle of the phase accumulator.
+const ncoCycle = 1 << 32
+
+// NewNCO returns an NCO that starts at phase 0. If tableBits is 0, sin and cos
+// are evaluated directly. Otherwise they are looked up in a table with
+// 2^tableBits entries per cycle and interpolated linearly, which is faster
+// and, with 10 bits, accurate to about 5e-6. tableBits is clamped to 2..20.
+func NewNCO(frequency, sampleRate FLOAT, tableBits int) *NCO {
+	n := &NCO{Frequency: frequency, SampleRate: sampleRate}
+	if tableBits > 0 {
+		if tableBits < 2 {
+			tableBits = 2
+		}
+		if tableBits > 20 {
+			tableBits = 20
+		}
+		n.tableBits = uint(tableBits)
+		n.table = make([]float64, 1<<n.tableBits+1)
+		for i := range n.table {
+			n.table[i] = math.Cos(2 * math.Pi * float64(i) / float64(len(n.table)-1))
+		}
+	}
+	return n
+}
+
+// Phase returns the phase of the next sample in radians, from 0 to 2*pi.
+func (n *NCO) Phase() FLOAT {
+	return FLOAT(2 * math.Pi * float64(n.phase) / ncoCycle)
+}
+
+// SetPhase sets the phase of the next sample in radians.
+func (n *NCO) SetPhase(phase FLOAT) {
+	n.phase = ncoPhase(float64(phase) / (2 * math.Pi))
+}
+
+// Reset sets the phase back to 0.
+func (n *NCO) Reset() {
+	n.phase = 0
+}
+
+// ncoPhase converts cycles to accumulator units, wrapping around at whole
+// cycles.
+func ncoPhase(cycles float64) uint32 {
+	cycles -= math.Floor(cycles)
+	return uint32(uint64(math.Floor(cycles*ncoCycle+0.5)) & (ncoCycle - 1))
+}
+
+// increment returns the phase increment per sample for the given frequency.
+func (n *NCO) increment(frequency float64) uint32 {
+	cycles := frequency / float64(n.SampleRate)
+	if math.IsNaN(cycles) || math.IsInf(cycles, 0) {
+		return 0
+	}
+	return ncoPhase(cycles)
+}
+
+// cosSin returns the cos and sin of the accumulator phase p.
+func (n *NCO) cosSin(p uint32) (float64, float64) {
+	if n.table == nil {
+		s, c := math.Sincos(2 * math.Pi * float64(p) / ncoCycle)
+		return c, s
+	}
+	// sin(x) = cos(x - pi/2).
+	return n.lookup(p), n.lookup(p - ncoCycle/4)
+}
+
+func (n *NCO) lookup(p uint32) float64 {
+	shift := 32 - n.tableBits
+	i := p >> shift
+	f := float64(p&(1<<shift-1)) / float64(uint64(1)<<shift)
+	return n.table[i] + f*(n.table[i+1]-n.table[i])
+}
+
+// Read fills i and q with the cos and sin of the next len(i) samples. q must be
+// at least as long as i.
+func (n *NCO) Read(i, q []FLOAT) {
+	n.ReadModulated(i, q, nil, nil)
+}
+
+// ReadModulated is like Read but modulates the oscillator. frequency holds
+// the offset to Frequency in Hz for each sample and phase the offset to the
+// phase in radians for each sample. Frequency modulation changes the rate at
+// which the phase advances, phase modulation only shifts the output phase of
+// each sample and is not accumulated. Both can be nil, otherwise they must be
+// at least as long as i.
+func (n *NCO) ReadModulated(i, q, frequency, phase []FLOAT) {
+	inc := n.increment(float64(n.Frequency))
+	for k := range i {
+		p := n.phase
+		if phase != nil {
+			p += ncoPhase(float64(phase[k]) / (2 * math.Pi))
+		}
+		c, s := n.cosSin(p)
+		i[k], q[k] = FLOAT(c), FLOAT(s)
+		if frequency != nil {
+			n.phase += n.increment(float64(n.Frequency) + float64(frequency[k]))
+		} else {
+			n.phase += inc
+		}
+	}
+}
+
+// MixReal multiplies the real signal in by the oscillator and writes the
+// complex result to outI and outQ, which must be at least as long as in. This
+// shifts the spectrum of in by Frequency, both its positive and its negative
+// frequencies.
+func (n *NCO) MixReal(in, outI, outQ []FLOAT) {
+	inc := n.increment(float64(n.Frequency))
+	for k, x := range in {
+		c, s := n.cosSin(n.phase)
+		outI[k], outQ[k] = x*FLOAT(c), x*FLOAT(s)
+		n.phase += inc
+	}
+}
+
+// MixComplex multiplies the complex signal inI + i*inQ by the oscillator and
+// writes the result to outI and outQ. This shifts all frequencies of the
+// signal by Frequency. All slices must be at least as long as inI. The output
+// may be the same slices as the input.
+func (n *NCO) MixComplex(inI, inQ, outI, outQ []FLOAT) {
+	inc := n.increment(float64(n.Frequency))
+	for k := range inI {
+		c, s := n.cosSin(n.phase)
+		a, b := float64(inI[k]), float64(inQ[k])
+		outI[k], outQ[k] = FLOAT(a*c-b*s), FLOAT(a*s+b*c)
+		n.phase += inc
+	}
+}
+
+// FrequencyShift returns the complex signal inI + i*inQ with all frequencies
+// shifted by shift Hz at the given sample rate. inQ can be nil for real
+// signals.
+func FrequencyShift(inI, inQ []FLOAT, shift, sampleRate FLOAT) (outI, outQ []FLOAT) {
+	nco := NewNCO(shift, sampleRate, 0)
+	outI = make([]FLOAT, len(inI))
+	outQ = make([]FLOAT, len(inI))
+	if inQ == nil {
+		nco.MixReal(inI, outI, outQ)
+	} else {
+		nco.MixComplex(inI, inQ, outI, outQ)
+	}
+	return
+}
@@ -0,0 +1,117 @@
+package dsp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestNCOGeneratesComplexExponential(t *testing.T) {
+	for _, bits := range []int{0, 10} {
+		n := NewNCO(1000, 48000, bits)
+		i := make([]FLOAT, 500)
+		q := make([]FLOAT, 500)
+		n.Read(i, q)
+		for k := range i {
+			x := 2 * math.Pi * 1000 * float64(k) / 48000
+			check.EqEps(t, float64(i[k]), math.Cos(x), 1e-5, bits, k)
+			check.EqEps(t, float64(q[k]), math.Sin(x), 1e-5, bits, k)
+		}
+	}
+}
+
+func TestNCOPhaseIsContinuousAcrossBlocks(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, bits := range []int{0, 8} {
+		n := NewNCO(-1234.5, 44100, bits)
+		wantI := make([]FLOAT, 10000)
+		wantQ := make([]FLOAT, 10000)
+		n.Read(wantI, wantQ)
+
+		n.Reset()
+		i := make([]FLOAT, 10000)
+		q := make([]FLOAT, 10000)
+		for start := 0; start < len(i); {
+			end := start + rnd.Intn(700)
+			if end > len(i) {
+				end = len(i)
+			}
+			n.Read(i[start:end], q[start:end])
+			start = end
+		}
+		check.Eq(t, i, wantI, bits)
+		check.Eq(t, q, wantQ, bits)
+	}
+}
+
+func TestNCOPhaseWrapsAround(t *testing.T) {
+	n := NewNCO(1, 4, 0)
+	n.SetPhase(-math.Pi / 2)
+	check.EqEps(t, n.Phase(), 3*math.Pi/2, 1e-6)
+	i := make([]FLOAT, 3)
+	q := make([]FLOAT, 3)
+	n.Read(i, q)
+	check.EqEps(t, q[0], -1, 1e-6)
+	check.EqEps(t, i[1], 1, 1e-6)
+	check.EqEps(t, q[2], 1, 1e-6)
+	check.EqEps(t, n.Phase(), math.Pi, 1e-6)
+}
+
+func TestNCOModulation(t *testing.T) {
+	const size = 200
+	i := make([]FLOAT, size)
+	q := make([]FLOAT, size)
+	wantI := make([]FLOAT, size)
+	wantQ := make([]FLOAT, size)
+
+	// A constant frequency offset is the same as a different frequency.
+	NewNCO(100, 8000, 0).ReadModulated(i, q, Repeat(50, size), nil)
+	NewNCO(150, 8000, 0).Read(wantI, wantQ)
+	check.Eq(t, i, wantI)
+	check.Eq(t, q, wantQ)
+
+	// A constant phase offset is the same as a different start phase.
+	NewNCO(100, 8000, 0).ReadModulated(i, q, nil, Repeat(1, size))
+	n := NewNCO(100, 8000, 0)
+	n.SetPhase(1)
+	n.Read(wantI, wantQ)
+	for k := range i {
+		check.EqEps(t, i[k], wantI[k], 1e-6, k)
+		check.EqEps(t, q[k], wantQ[k], 1e-6, k)
+	}
+
+	// Phase modulation is not accumulated.
+	n = NewNCO(0, 8000, 0)
+	n.ReadModulated(i[:2], q[:2], nil, []FLOAT{math.Pi / 2, math.Pi})
+	check.EqEps(t, q[0], 1, 1e-6)
+	check.EqEps(t, i[1], -1, 1e-6)
+	check.Eq(t, n.Phase(), 0)
+}
+
+func TestMixingShiftsFrequency(t *testing.T) {
+	const size = 1000
+	// A complex tone at 300 Hz moves to 500 Hz.
+	i, q := FrequencyShift(Cosine(size, 1, 300, 0, 8000), Sine(size, 1, 300, 0, 8000), 200, 8000)
+	for k := range i {
+		check.EqEps(t, i[k], FLOAT(math.Cos(2*math.Pi*500*float64(k)/8000)), 1e-5, k)
+		check.EqEps(t, q[k], FLOAT(math.Sin(2*math.Pi*500*float64(k)/8000)), 1e-5, k)
+	}
+
+	// A real cosine at 300 Hz has components at +300 and -300 Hz, mixing with
+	// -300 Hz moves them to 0 and -600 Hz. The average is the DC part.
+	i, q = FrequencyShift(Cosine(size, 2, 300, 0, 8000), nil, -300, 8000)
+	check.EqEps(t, Average(i), 1, 1e-3)
+	check.EqEps(t, Average(q), 0, 1e-3)
+}
+
+func TestMixComplexWorksInPlace(t *testing.T) {
+	i := []FLOAT{1, 1, 1, 1}
+	q := []FLOAT{0, 0, 0, 0}
+	NewNCO(1, 4, 0).MixComplex(i, q, i, q)
+	check.EqEps(t, i[1], 0, 1e-6)
+	check.EqEps(t, q[1], 1, 1e-6)
+	check.EqEps(t, i[2], -1, 1e-6)
+	check.EqEps(t, q[3], -1, 1e-6)
+}
@@ -0,0 +1,92 @@
+package dsp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+// spectralSlope estimates the power spectral density of a with Welch's method
+// and returns its slope in dB per decade between the normalized frequencies
+// 0.005 and 0.1.
+func spectralSlope(a []FLOAT) float64 {
+	const n = 1024
+	first, last := 5, 102
+	window := make([]float64, n)
+	cos := make([]float64, n)
+	sin := make([]float64, n)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/n)
+		cos[i] = math.Cos(2 * math.Pi * float64(i) / n)
+		sin[i] = math.Sin(2 * math.Pi * float64(i) / n)
+	}
+	power := make([]float64, last+1)
+	for start := 0; start+n <= len(a); start += n / 2 {
+		for k := first; k <= last; k++ {
+			var re, im float64
+			for i := 0; i < n; i++ {
+				x := window[i] * float64(a[start+i])
+				re += x * cos[k*i%n]
+				im -= x * sin[k*i%n]
+			}
+			power[k] += re*re + im*im
+		}
+	}
+	// Fit a line to the power in dB over the logarithm of the frequency.
+	var sx, sy, sxx, sxy, count float64
+	for k := first; k <= last; k++ {
+		x := math.Log10(float64(k) / n)
+		y := 10 * math.Log10(power[k])
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+		count++
+	}
+	return (count*sxy - sx*sy) / (count*sxx - sx*sx)
+}
+
+func TestNoiseColorsHaveSpectralSlopes(t *testing.T) {
+	for _, test := range []struct {
+		color NoiseColor
+		slope float64
+	}{
+		{WhiteNoise, 0},
+		{UniformNoise, 0},
+		{PinkNoise, -10},
+		{BrownNoise, -20},
+		{BlueNoise, 10},
+		{VioletNoise, 20},
+	} {
+		a := Noise(1<<16, test.color, 1, 1)
+		check.EqEps(t, spectralSlope(a), test.slope, 1.5, test.color)
+	}
+}
+
+func TestNoiseHasAmplitudeAsStandardDeviation(t *testing.T) {
+	for _, color := range []NoiseColor{WhiteNoise, PinkNoise, BrownNoise, BlueNoise, VioletNoise} {
+		a := Noise(1<<20, color, 2, 3)
+		check.EqEps(t, rms(a), 2, 0.15, color)
+	}
+	u := Noise(10000, UniformNoise, 2, 3)
+	_, min, _, max := MinMax(u)
+	check.Eq(t, min >= -2, true)
+	check.Eq(t, max < 2, true)
+	check.EqEps(t, rms(u), 2/math.Sqrt(3), 0.05)
+}
+
+func TestNoiseIsReproducible(t *testing.T) {
+	for _, color := range []NoiseColor{WhiteNoise, UniformNoise, PinkNoise, BrownNoise, BlueNoise, VioletNoise} {
+		check.Eq(t, Noise(100, color, 1, 5), Noise(100, color, 1, 5), color)
+		check.Neq(t, Noise(100, color, 1, 5), Noise(100, color, 1, 6), color)
+
+		g := NewNoiseGenerator(color, 1, rand.New(rand.NewSource(5)))
+		var blocks []FLOAT
+		for _, n := range []int{3, 0, 50, 47} {
+			blocks = append(blocks, g.Generate(n)...)
+		}
+		check.Eq(t, blocks, Noise(100, color, 1, 5), color)
+	}
+}
@@ -0,0 +1,370 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// OnsetMethod selects the onset detection function of OnsetStrength.
+type OnsetMethod int
+
+const (
+	// SpectralFluxOnset sums the increases of the magnitudes of all frequency
+	// bins from one frame to the next. It works well for most signals.
+	SpectralFluxOnset OnsetMethod = iota
+	// EnergyOnset is the increase of the signal energy from one frame to the
+	// next. It is cheap but only detects loud percussive onsets.
+	EnergyOnset
+	// HighFrequencyContentOnset weights the power in each frequency bin with
+	// the bin index, which emphasizes the broadband noise of percussive
+	// onsets.
+	HighFrequencyContentOnset
+	// ComplexDomainOnset predicts the magnitude and phase of each frequency
+	// bin from the two frames before and sums the deviations where the
+	// magnitude increases. It also detects soft onsets that only change the
+	// phase, like a new note at the same volume.
+	ComplexDomainOnset
+)
+
+// OnsetOptions configures OnsetStrength and DetectOnsets. Zero values select
+// the defaults.
+type OnsetOptions struct {
+	Method OnsetMethod
+	// FrameLength is the number of samples per analysis frame. It defaults to
+	// the power of 2 closest to 46 ms, e.g. 2048 at 44.1 kHz.
+	FrameLength int
+	// Hop is the number of samples between frames. It defaults to
+	// FrameLength/4.
+	Hop int
+	// Delta is how far the onset strength, normalized to the range 0 to 1,
+	// must rise above its median over the surrounding 0.2 seconds for an
+	// onset to be detected. It defaults to 0.07.
+	Delta FLOAT
+	// Wait is the shortest time between two onsets in seconds, it defaults to
+	// 0.03.
+	Wait FLOAT
+}
+
+func (o OnsetOptions) withDefaults(sampleRate FLOAT) OnsetOptions {
+	if o.FrameLength <= 0 {
+		n := 0.046 * float64(sampleRate)
+		o.FrameLength = nextPowerOf2(int(n))
+		if float64(o.FrameLength)/n > n/float64(o.FrameLength/2) {
+			o.FrameLength /= 2
+		}
+		if o.FrameLength < 2 {
+			o.FrameLength = 2
+		}
+	}
+	if o.Hop <= 0 {
+		o.Hop = o.FrameLength / 4
+	}
+	if o.Hop < 1 {
+		o.Hop = 1
+	}
+	return o
+}
+
+// OnsetStrength returns the onset detection function of a, one value per
+// frame. Value i is for the frame centered at sample i*Hop, the returned
+// frame rate is sampleRate/Hop. Onsets are where the strength rises sharply,
+// see DetectOnsets.
+func OnsetStrength(a []FLOAT, sampleRate FLOAT, options OnsetOptions) (strength []FLOAT, frameRate FLOAT) {
+	o := options.withDefaults(sampleRate)
+	frameRate = FLOAT(float64(sampleRate) / float64(o.Hop))
+	frames := stft(a, o.FrameLength, o.Hop, o.Method != EnergyOnset)
+	odf := make([]float64, len(frames))
+	switch o.Method {
+	case EnergyOnset:
+		last := 0.0
+		for n, x := range frames {
+			var e float64
+			for _, v := range x {
+				e += real(v) * real(v)
+			}
+			odf[n] = math.Max(0, e-last)
+			last = e
+		}
+	case HighFrequencyContentOnset:
+		for n, x := range frames {
+			for k, v := range x {
+				odf[n] += float64(k) * (real(v)*real(v) + imag(v)*imag(v))
+			}
+		}
+	case ComplexDomainOnset:
+		for n := range frames {
+			for k, v := range frames[n] {
+				var prev, prev2 complex128
+				if n >= 1 {
+					prev = frames[n-1][k]
+				}
+				if n >= 2 {
+					prev2 = frames[n-2][k]
+				}
+				mag, prevMag := cmplx.Abs(v), cmplx.Abs(prev)
+				if mag < prevMag {
+					continue
+				}
+				phase := 2*cmplx.Phase(prev) - cmplx.Phase(prev2)
+				s, c := math.Sincos(phase)
+				odf[n] += cmplx.Abs(v - complex(prevMag*c, prevMag*s))
+			}
+		}
+	default:
+		for n := range frames {
+			for k, v := range frames[n] {
+				var prev complex128
+				if n >= 1 {
+					prev = frames[n-1][k]
+				}
+				odf[n] += math.Max(0, cmplx.Abs(v)-cmplx.Abs(prev))
+			}
+		}
+	}
+	return toFLOAT(odf), frameRate
+}
+
+// DetectOnsets returns the times of all onsets in a in seconds, the starts of
+// notes and percussive sounds. See OnsetStrength and PickOnsets.
+func DetectOnsets(a []FLOAT, sampleRate FLOAT, options OnsetOptions) []FLOAT {
+	strength, frameRate := OnsetStrength(a, sampleRate, options)
+	frames := PickOnsets(strength, frameRate, options)
+	times := make([]FLOAT, len(frames))
+	for i, f := range frames {
+		times[i] = FLOAT(float64(f) / float64(frameRate))
+	}
+	return times
+}
+
+// PickOnsets returns the frame indices of the onsets in an onset detection
+// function at the given frame rate. The strength is normalized to the range
+// 0 to 1. A frame is an onset if it is the maximum of the previous 30 ms, is
+// at least options.Delta above the median of the surrounding 0.2 seconds and
+// is at least options.Wait after the previous onset.
+func PickOnsets(strength []FLOAT, frameRate FLOAT, options OnsetOptions) []int {
+	delta, wait := float64(options.Delta), float64(options.Wait)
+	if delta <= 0 {
+		delta = 0.07
+	}
+	if wait <= 0 {
+		wait = 0.03
+	}
+	_, lo, _, hi := MinMax(strength)
+	if !(hi > lo) {
+		return nil
+	}
+	x := make([]float64, len(strength))
+	for i := range x {
+		x[i] = float64(strength[i]-lo) / float64(hi-lo)
+	}
+	frames := func(seconds float64) int {
+		return int(seconds * float64(frameRate))
+	}
+	preMax := frames(0.03)
+	around := frames(0.1)
+	waitFrames := frames(wait)
+
+	var onsets []int
+	window := make([]float64, 0, 2*around+1)
+	for i := range x {
+		isMax := true
+		for j := i - preMax; j < i; j++ {
+			if j >= 0 && x[j] > x[i] {
+				isMax = false
+			}
+		}
+		// Plateaus count once, at their start.
+		if !isMax || i+1 < len(x) && x[i+1] > x[i] || i > 0 && x[i-1] == x[i] {
+			continue
+		}
+		window = window[:0]
+		for j := i - around; j <= i+around; j++ {
+			if j >= 0 && j < len(x) {
+				window = append(window, x[j])
+			}
+		}
+		sort.Float64s(window)
+		if x[i] < window[len(window)/2]+delta {
+			continue
+		}
+		if len(onsets) > 0 && i-onsets[len(onsets)-1] <= waitFrames {
+			continue
+		}
+		onsets = append(onsets, i)
+	}
+	return onsets
+}
+
+// TempoOptions configures EstimateTempo. Zero values select the defaults.
+type TempoOptions struct {
+	// MinBPM and MaxBPM limit the tempo in beats per minute, they default to
+	// 30 and 300.
+	MinBPM, MaxBPM FLOAT
+	// StartBPM is the most likely tempo. Tempos an octave away from it are
+	// weighted down by a factor of about 0.6. It defaults to 120.
+	StartBPM FLOAT
+}
+
+// EstimateTempo returns the tempo in beats per minute of an onset detection
+// function at the given frame rate. It picks the highest peak of the
+// autocorrelation of the onset strength, weighted with a log-normal
+// distribution around options.StartBPM.
+func EstimateTempo(strength []FLOAT, frameRate FLOAT, options TempoOptions) FLOAT {
+	if options.MinBPM <= 0 {
+		options.MinBPM = 30
+	}
+	if options.MaxBPM <= options.MinBPM {
+		options.MaxBPM = 300
+	}
+	if options.StartBPM <= 0 {
+		options.StartBPM = 120
+	}
+	rate := float64(frameRate)
+	minLag := int(math.Floor(60 * rate / float64(options.MaxBPM)))
+	maxLag := int(math.Ceil(60 * rate / float64(options.MinBPM)))
+	if minLag < 1 {
+		minLag = 1
+	}
+	if maxLag > len(strength)-2 {
+		maxLag = len(strength) - 2
+	}
+	if maxLag < minLag {
+		return 0
+	}
+
+	x := make([]float64, len(strength))
+	mean := float64(Average(strength))
+	for i := range x {
+		x[i] = float64(strength[i]) - mean
+	}
+	r := lagProducts(x, len(x)-maxLag-1, maxLag+1)
+	weighted := make([]float64, len(r))
+	for lag := 1; lag < len(r); lag++ {
+		octaves := math.Log2(60 * rate / float64(lag) / float64(options.StartBPM))
+		weighted[lag] = r[lag] * math.Exp(-0.5*octaves*octaves)
+	}
+	best := minLag
+	for lag := minLag; lag <= maxLag; lag++ {
+		if weighted[lag] > weighted[best] {
+			best = lag
+		}
+	}
+	return FLOAT(60 * rate / refinePeak(weighted, best))
+}
+
+// TrackBeats returns the times of the beats in seconds in an onset detection
+// function at the given frame rate, see OnsetStrength. It uses the dynamic
+// programming beat tracker by Ellis, which finds the beats that best line up
+// with strong onsets while keeping close to the tempo in beats per minute. A
+// tempo of 0 or less is estimated with EstimateTempo. tightness controls how
+// strictly the tempo is kept, 0 or less means 100.
+func TrackBeats(strength []FLOAT, frameRate, tempo, tightness FLOAT) []FLOAT {
+	if tempo <= 0 {
+		tempo = EstimateTempo(strength, frameRate, TempoOptions{})
+	}
+	if tightness <= 0 {
+		tightness = 100
+	}
+	if tempo <= 0 || len(strength) == 0 {
+		return nil
+	}
+	period := 60 * float64(frameRate) / float64(tempo)
+
+	// Normalize by the standard deviation and smooth with a Gaussian that is
+	// a 16th of a beat wide.
+	var sum, sumSquares float64
+	for _, v := range strength {
+		sum += float64(v)
+		sumSquares += float64(v) * float64(v)
+	}
+	n := float64(len(strength))
+	std := math.Sqrt(math.Max(0, sumSquares/n-(sum/n)*(sum/n)))
+	if std == 0 {
+		return nil
+	}
+	half := int(period)
+	kernel := make([]float64, 2*half+1)
+	for i := range kernel {
+		t := float64(i-half) * 32 / period
+		kernel[i] = math.Exp(-0.5 * t * t)
+	}
+	local := make([]float64, len(strength))
+	for i := range local {
+		for j, k := range kernel {
+			if s := i + j - half; s >= 0 && s < len(strength) {
+				local[i] += k * float64(strength[s]) / std
+			}
+		}
+	}
+
+	// score[i] is the best total score of a beat sequence that ends at frame
+	// i, back[i] the previous beat in that sequence or -1.
+	score := make([]float64, len(local))
+	back := make([]int, len(local))
+	for i := range local {
+		back[i] = -1
+		best := math.Inf(-1)
+		for prev := i - int(math.Floor(2*period+0.5)); prev <= i-int(period/2); prev++ {
+			if prev < 0 {
+				continue
+			}
+			d := math.Log(float64(i-prev) / period)
+			s := score[prev] - float64(tightness)*d*d
+			if s > best {
+				best = s
+				back[i] = prev
+			}
+		}
+		score[i] = local[i]
+		if back[i] >= 0 && best > 0 {
+			score[i] += best
+		} else {
+			back[i] = -1
+		}
+	}
+
+	// The last beat is the last local maximum of the score that reaches half
+	// of the median of all its local maxima.
+	var maxima []float64
+	for i := 1; i+1 < len(score); i++ {
+		if score[i] > score[i-1] && score[i] >= score[i+1] {
+			maxima = append(maxima, score[i])
+		}
+	}
+	if len(maxima) == 0 {
+		return nil
+	}
+	sort.Float64s(maxima)
+	limit := 0.5 * maxima[len(maxima)/2]
+	last := -1
+	for i := 1; i+1 < len(score); i++ {
+		if score[i] > score[i-1] && score[i] >= score[i+1] && score[i] >= limit {
+			last = i
+		}
+	}
+
+	var beats []int
+	for i := last; i >= 0; i = back[i] {
+		beats = append(beats, i)
+	}
+	// Remove weak beats at both ends, e.g. in silence before the music
+	// starts.
+	var rms float64
+	for _, b := range beats {
+		rms += local[b] * local[b]
+	}
+	rms = math.Sqrt(rms / float64(len(beats)))
+	for len(beats) > 0 && local[beats[0]] < 0.5*rms {
+		beats = beats[1:]
+	}
+	for len(beats) > 0 && local[beats[len(beats)-1]] < 0.5*rms {
+		beats = beats[:len(beats)-1]
+	}
+
+	times := make([]FLOAT, len(beats))
+	for i := range beats {
+		times[i] = FLOAT(float64(beats[len(beats)-1-i]) / float64(frameRate))
+	}
+	return times
+}
@@ -0,0 +1,95 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+// drumTrack returns a signal with decaying noise bursts at the given times.
+func drumTrack(times []FLOAT, seconds, sampleRate FLOAT) []FLOAT {
+	a := make([]FLOAT, int(seconds*sampleRate))
+	noise := Noise(len(a), WhiteNoise, 0.5, 3)
+	for _, t := range times {
+		start := int(t * sampleRate)
+		for i := start; i < len(a) && i < start+int(0.2*sampleRate); i++ {
+			decay := FLOAT(math.Exp(-float64(i-start) / (0.03 * float64(sampleRate))))
+			a[i] += noise[i] * decay
+		}
+	}
+	return a
+}
+
+// regularTimes returns the times of beats at the given tempo.
+func regularTimes(bpm, start, seconds FLOAT) []FLOAT {
+	var times []FLOAT
+	for t := start; t < seconds; t += 60 / bpm {
+		times = append(times, t)
+	}
+	return times
+}
+
+func TestDetectOnsetsFindsDrumHits(t *testing.T) {
+	const sampleRate = 22050
+	times := []FLOAT{0.3, 0.7, 1.05, 1.2, 1.9, 2.5, 2.6}
+	a := drumTrack(times, 3, sampleRate)
+	a = Add(a, Noise(len(a), WhiteNoise, 0.001, 4))
+	for _, method := range []OnsetMethod{
+		SpectralFluxOnset, EnergyOnset, HighFrequencyContentOnset, ComplexDomainOnset,
+	} {
+		onsets := DetectOnsets(a, sampleRate, OnsetOptions{Method: method})
+		check.Eq(t, len(onsets), len(times), method, onsets)
+		if len(onsets) == len(times) {
+			for i := range onsets {
+				// Frames are 1024 samples long with a hop of 256.
+				check.EqEps(t, onsets[i], times[i], 0.025, method, i)
+			}
+		}
+	}
+}
+
+func TestOnsetStrengthFrames(t *testing.T) {
+	strength, frameRate := OnsetStrength(make([]FLOAT, 1000), 1000, OnsetOptions{Hop: 10})
+	check.Eq(t, len(strength), 101)
+	check.Eq(t, frameRate, 100)
+	check.Eq(t, len(DetectOnsets(make([]FLOAT, 1000), 1000, OnsetOptions{})), 0)
+}
+
+func TestPickOnsetsUsesDeltaAndWait(t *testing.T) {
+	s := []FLOAT{0, 1, 0, 0, 0.5, 0, 0.05, 0, 0, 0.8, 0.9, 0, 0, 0, 0}
+	// At 10 frames per second, the median is over 3 frames.
+	check.Eq(t, PickOnsets(s, 10, OnsetOptions{}), []int{1, 4, 10})
+	check.Eq(t, PickOnsets(s, 10, OnsetOptions{Delta: 0.01}), []int{1, 4, 6, 10})
+	check.Eq(t, PickOnsets(s, 10, OnsetOptions{Wait: 0.5}), []int{1, 10})
+	// At 100 frames per second, a peak must be the maximum of 3 frames before
+	// it.
+	check.Eq(t, PickOnsets(s, 100, OnsetOptions{}), []int{1, 10})
+	check.Eq(t, len(PickOnsets([]FLOAT{1, 1, 1}, 100, OnsetOptions{})), 0)
+}
+
+func TestEstimateTempo(t *testing.T) {
+	const sampleRate = 22050
+	for _, bpm := range []FLOAT{80, 100, 128, 150} {
+		a := drumTrack(regularTimes(bpm, 0.1, 12), 12, sampleRate)
+		strength, frameRate := OnsetStrength(a, sampleRate, OnsetOptions{})
+		tempo := EstimateTempo(strength, frameRate, TempoOptions{})
+		check.EqEps(t, tempo, bpm, 0.02*float64(bpm), bpm)
+	}
+	check.Eq(t, EstimateTempo(nil, 100, TempoOptions{}), 0)
+}
+
+func TestTrackBeatsFollowsDrums(t *testing.T) {
+	const sampleRate = 22050
+	drums := regularTimes(110, 1, 12)
+	a := drumTrack(drums, 13, sampleRate)
+	strength, frameRate := OnsetStrength(a, sampleRate, OnsetOptions{})
+	beats := TrackBeats(strength, frameRate, 0, 0)
+	check.Eq(t, len(beats), len(drums))
+	if len(beats) == len(drums) {
+		for i := range beats {
+			check.EqEps(t, beats[i], drums[i], 0.025, i)
+		}
+	}
+	check.Eq(t, len(TrackBeats(make([]FLOAT, 100), 100, 120, 0)), 0)
+}
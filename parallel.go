@@ -0,0 +1,208 @@
+package dsp
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Parallel provides versions of the array functions that split their work
+// into chunks and process these chunks in multiple goroutines. The results are
+// identical to those of the serial functions.
+//
+// The zero value is ready to use and runs on runtime.GOMAXPROCS(0) goroutines.
+type Parallel struct {
+	// Workers is the maximum number of goroutines used for one call. If it is
+	// <= 0, runtime.GOMAXPROCS(0) is used.
+	Workers int
+	// MinChunkSize is the minimum number of output elements that one goroutine
+	// computes. Inputs too small to be split into at least two such chunks are
+	// processed in the calling goroutine. If it is <= 0, a default of 65536 is
+	// used.
+	MinChunkSize int
+}
+
+// AverageFilter is the parallel version of the package function AverageFilter.
+func (p Parallel) AverageFilter(a []FLOAT, width int) []FLOAT {
+	if width >= len(a) {
+		width = len(a)
+	}
+
+	if width <= 1 {
+		return p.Copy(a)
+	}
+
+	b := make([]FLOAT, len(a)-(width-1))
+	p.run(len(b), averageFilterBlock(width), func(lo, hi int) {
+		averageFilterRange(a, b, width, lo, hi)
+	})
+	return b
+}
+
+// MedianFilter is the parallel version of the package function MedianFilter.
+func (p Parallel) MedianFilter(a []FLOAT, width int) []FLOAT {
+	if width >= len(a) {
+		width = len(a)
+	}
+
+	if width <= 1 {
+		return p.Copy(a)
+	}
+
+	b := make([]FLOAT, len(a)-width+1)
+	p.run(len(b), 1, func(lo, hi int) {
+		medianFilterRange(a, b, width, lo, hi)
+	})
+	return b
+}
+
+// Copy is the parallel version of the package function Copy.
+func (p Parallel) Copy(a []FLOAT) []FLOAT {
+	c := make([]FLOAT, len(a))
+	p.run(len(c), 1, func(lo, hi int) {
+		copy(c[lo:hi], a[lo:hi])
+	})
+	return c
+}
+
+// Negative is the parallel version of the package function Negative.
+func (p Parallel) Negative(a []FLOAT) []FLOAT {
+	n := make([]FLOAT, len(a))
+	p.run(len(n), 1, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			n[i] = -a[i]
+		}
+	})
+	return n
+}
+
+// Derivative is the parallel version of the package function Derivative.
+func (p Parallel) Derivative(a []FLOAT) []FLOAT {
+	if len(a) <= 1 {
+		return make([]FLOAT, len(a))
+	}
+
+	b := make([]FLOAT, len(a)-1)
+	p.run(len(b), 1, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			b[i] = a[i+1] - a[i]
+		}
+	})
+	return b
+}
+
+// Add is the parallel version of the package function Add.
+func (p Parallel) Add(a ...[]FLOAT) []FLOAT {
+	if len(a) == 0 {
+		return nil
+	}
+	n := len(a[0])
+	for _, v := range a {
+		if len(v) < n {
+			n = len(v)
+		}
+	}
+	sum := make([]FLOAT, n)
+	p.run(n, 1, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			for j := range a {
+				sum[i] += a[j][i]
+			}
+		}
+	})
+	return sum
+}
+
+// Sub is the parallel version of the package function Sub.
+func (p Parallel) Sub(a ...[]FLOAT) []FLOAT {
+	if len(a) == 0 {
+		return nil
+	}
+	n := len(a[0])
+	for _, v := range a {
+		if len(v) < n {
+			n = len(v)
+		}
+	}
+	diff := make([]FLOAT, n)
+	p.run(n, 1, func(lo, hi int) {
+		copy(diff[lo:hi], a[0][lo:hi])
+		for i := lo; i < hi; i++ {
+			for j := 1; j < len(a); j++ {
+				diff[i] -= a[j][i]
+			}
+		}
+	})
+	return diff
+}
+
+// AddOffset is the parallel version of the package function AddOffset.
+func (p Parallel) AddOffset(a []FLOAT, offset FLOAT) []FLOAT {
+	b := make([]FLOAT, len(a))
+	p.run(len(b), 1, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			b[i] = a[i] + offset
+		}
+	})
+	return b
+}
+
+// Scale is the parallel version of the package function Scale.
+func (p Parallel) Scale(a []FLOAT, factor FLOAT) []FLOAT {
+	b := make([]FLOAT, len(a))
+	p.run(len(b), 1, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			b[i] = a[i] * factor
+		}
+	})
+	return b
+}
+
+// Abs is the parallel version of the package function Abs.
+func (p Parallel) Abs(x []FLOAT) []FLOAT {
+	a := make([]FLOAT, len(x))
+	p.run(len(a), 1, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			a[i] = AbsValue(x[i])
+		}
+	})
+	return a
+}
+
+// run splits the range [0,n) into chunks and calls f for each chunk. All chunks
+// but the last start at a multiple of align. f is called concurrently from at
+// most p.Workers goroutines and run returns after all calls are done.
+func (p Parallel) run(n, align int, f func(lo, hi int)) {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	minChunk := p.MinChunkSize
+	if minChunk <= 0 {
+		minChunk = 65536
+	}
+
+	if n/minChunk < workers {
+		workers = n / minChunk
+	}
+	if workers <= 1 {
+		f(0, n)
+		return
+	}
+
+	chunk := (n + workers - 1) / workers
+	chunk = (chunk + align - 1) / align * align
+
+	var wg sync.WaitGroup
+	for lo := 0; lo < n; lo += chunk {
+		hi := lo + chunk
+		if hi > n {
+			hi = n
+		}
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			f(lo, hi)
+		}(lo, hi)
+	}
+	wg.Wait()
+}
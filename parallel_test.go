@@ -0,0 +1,80 @@
+package dsp
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func randomFloats(n int, seed int64) []FLOAT {
+	r := rand.New(rand.NewSource(seed))
+	a := make([]FLOAT, n)
+	for i := range a {
+		a[i] = FLOAT(r.NormFloat64())
+	}
+	return a
+}
+
+func TestParallelAverageFilterIsIdenticalToSerialVersion(t *testing.T) {
+	a := randomFloats(20000, 1)
+	for _, width := range []int{-1, 1, 2, 7, 100, 5000, 19999, 20000, 30000} {
+		for _, workers := range []int{1, 2, 3, 8} {
+			p := Parallel{Workers: workers, MinChunkSize: 1}
+			check.EqExact(t, p.AverageFilter(a, width), AverageFilter(a, width), width, workers)
+		}
+	}
+	check.Eq(t, Parallel{}.AverageFilter(nil, 3), nil)
+}
+
+func TestParallelMedianFilterIsIdenticalToSerialVersion(t *testing.T) {
+	a := randomFloats(1000, 2)
+	for _, width := range []int{-1, 1, 2, 7, 100, 999, 1000, 3000} {
+		for _, workers := range []int{1, 2, 3, 8} {
+			p := Parallel{Workers: workers, MinChunkSize: 1}
+			check.EqExact(t, p.MedianFilter(a, width), MedianFilter(a, width), width, workers)
+		}
+	}
+	check.Eq(t, Parallel{}.MedianFilter(nil, 3), nil)
+}
+
+func TestParallelElementwiseFunctionsAreIdenticalToSerialVersions(t *testing.T) {
+	a := randomFloats(1001, 3)
+	b := randomFloats(1000, 4)
+	p := Parallel{Workers: 4, MinChunkSize: 10}
+	check.EqExact(t, p.Copy(a), Copy(a))
+	check.EqExact(t, p.Negative(a), Negative(a))
+	check.EqExact(t, p.Derivative(a), Derivative(a))
+	check.EqExact(t, p.Add(a, b, a), Add(a, b, a))
+	check.EqExact(t, p.Sub(a, b, a), Sub(a, b, a))
+	check.EqExact(t, p.AddOffset(a, 0.1), AddOffset(a, 0.1))
+	check.EqExact(t, p.Scale(a, 1.7), Scale(a, 1.7))
+	check.EqExact(t, p.Abs(a), Abs(a))
+
+	check.Eq(t, p.Add(), nil)
+	check.Eq(t, p.Sub(), nil)
+	check.Eq(t, p.Derivative([]FLOAT{1}), []FLOAT{0})
+}
+
+func TestParallelNeverUsesMoreThanTheGivenNumberOfWorkers(t *testing.T) {
+	for _, workers := range []int{1, 2, 3, 5} {
+		for n := 0; n < 50; n++ {
+			var mu sync.Mutex
+			chunks := 0
+			covered := make([]bool, n)
+			Parallel{Workers: workers, MinChunkSize: 1}.run(n, 4, func(lo, hi int) {
+				mu.Lock()
+				chunks++
+				mu.Unlock()
+				for i := lo; i < hi; i++ {
+					covered[i] = true
+				}
+			})
+			check.Eq(t, chunks <= workers, true, workers, n)
+			for i := range covered {
+				check.Eq(t, covered[i], true, workers, n, i)
+			}
+		}
+	}
+}
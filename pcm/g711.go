@@ -0,0 +1,104 @@
+package pcm
+
+import "math"
+
+// The µ-law and A-law conversions follow the G.711 reference implementation.
+
+const (
+	muLawBias = 0x84
+	muLawClip = 8159
+)
+
+var muLawSegmentEnds = [8]int{0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF, 0x1FFF}
+
+var aLawSegmentEnds = [8]int{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+
+func segment(x int, ends *[8]int) int {
+	for i, end := range ends {
+		if x <= end {
+			return i
+		}
+	}
+	return len(ends)
+}
+
+func muLawToLinear(u byte) int16 {
+	u = ^u
+	t := (int(u&0x0F) << 3) + muLawBias
+	t <<= (u & 0x70) >> 4
+	if u&0x80 != 0 {
+		return int16(muLawBias - t)
+	}
+	return int16(t - muLawBias)
+}
+
+func linearToMuLaw(x int16) byte {
+	v := int(x) >> 2
+	mask := 0xFF
+	if v < 0 {
+		v = -v
+		mask = 0x7F
+	}
+	if v > muLawClip {
+		v = muLawClip
+	}
+	v += muLawBias >> 2
+	seg := segment(v, &muLawSegmentEnds)
+	if seg >= 8 {
+		return byte(0x7F ^ mask)
+	}
+	u := seg<<4 | (v>>uint(seg+1))&0x0F
+	return byte(u ^ mask)
+}
+
+func aLawToLinear(a byte) int16 {
+	a ^= 0x55
+	t := int(a&0x0F) << 4
+	seg := (a & 0x70) >> 4
+	switch seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+func linearToALaw(x int16) byte {
+	v := int(x) >> 3
+	mask := 0xD5
+	if v < 0 {
+		mask = 0x55
+		v = -v - 1
+	}
+	seg := segment(v, &aLawSegmentEnds)
+	if seg >= 8 {
+		return byte(0x7F ^ mask)
+	}
+	a := seg << 4
+	if seg < 2 {
+		a |= (v >> 1) & 0x0F
+	} else {
+		a |= (v >> uint(seg)) & 0x0F
+	}
+	return byte(a ^ mask)
+}
+
+// toInt16 scales x from [-1,1] to a 16 bit integer, clamping it to the valid
+// range.
+func toInt16(x float64) int16 {
+	v := math.Floor(x*32768 + 0.5)
+	if v < -32768 || math.IsNaN(v) {
+		v = -32768
+	}
+	if v > 32767 {
+		v = 32767
+	}
+	return int16(v)
+}
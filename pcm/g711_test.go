@@ -0,0 +1,48 @@
+package pcm
+
+import (
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestMuLawMatchesReferenceValues(t *testing.T) {
+	check.Eq(t, muLawToLinear(0xFF), 0)
+	check.Eq(t, muLawToLinear(0x7F), 0)
+	check.Eq(t, muLawToLinear(0x80), 32124)
+	check.Eq(t, muLawToLinear(0x00), -32124)
+	check.Eq(t, linearToMuLaw(0), 0xFF)
+	check.Eq(t, linearToMuLaw(32767), 0x80)
+	check.Eq(t, linearToMuLaw(-32768), 0x00)
+}
+
+func TestALawMatchesReferenceValues(t *testing.T) {
+	check.Eq(t, aLawToLinear(0xD5), 8)
+	check.Eq(t, aLawToLinear(0x55), -8)
+	check.Eq(t, aLawToLinear(0xAA), 32256)
+	check.Eq(t, aLawToLinear(0x2A), -32256)
+	check.Eq(t, linearToALaw(0), 0xD5)
+	check.Eq(t, linearToALaw(32767), 0xAA)
+	check.Eq(t, linearToALaw(-32768), 0x2A)
+}
+
+func TestCompandedValuesRoundTrip(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		b := byte(i)
+		check.Eq(t, muLawToLinear(linearToMuLaw(muLawToLinear(b))), muLawToLinear(b), i)
+		check.Eq(t, linearToALaw(aLawToLinear(b)), b, i)
+	}
+
+	for _, f := range []SampleFormat{
+		{Encoding: MuLaw, Bits: 8},
+		{Encoding: ALaw, Bits: 8},
+	} {
+		check.Eq(t, f.Validate(), nil)
+		b := make([]byte, 1)
+		f.Encode(b, 0.5)
+		check.EqEps(t, f.Decode(b), 0.5, 0.02)
+		f.Encode(b, -0.01)
+		check.EqEps(t, f.Decode(b), -0.01, 0.001)
+	}
+	check.Neq(t, SampleFormat{Encoding: MuLaw, Bits: 16}.Validate(), nil)
+}
@@ -0,0 +1,227 @@
+// Package pcm converts between raw, interleaved PCM sample data and
+// deinterleaved floating point samples, normalized to the range [-1,1].
+package pcm
+
+import (
+	"errors"
+	"math"
+)
+
+// Encoding is the way a single sample value is stored.
+type Encoding int
+
+const (
+	// Signed integers in two's complement.
+	Signed Encoding = iota
+	// Unsigned integers, the center value 2^(Bits-1) represents 0.
+	Unsigned
+	// Float is an IEEE 754 floating point number of 32 or 64 bits.
+	Float
+	// MuLaw is 8 bit G.711 µ-law companded audio.
+	MuLaw
+	// ALaw is 8 bit G.711 A-law companded audio.
+	ALaw
+)
+
+// SampleFormat describes how a single sample is stored.
+type SampleFormat struct {
+	Encoding Encoding
+	// Bits is the number of bits that a sample occupies, valid values are 8,
+	// 16, 24 and 32 for integer encodings, 32 and 64 for Float and 8 for MuLaw
+	// and ALaw.
+	Bits int
+	// BigEndian is true for samples that store their most significant byte
+	// first.
+	BigEndian bool
+}
+
+// Validate returns an error if f does not describe a supported sample format.
+func (f SampleFormat) Validate() error {
+	switch f.Encoding {
+	case Signed, Unsigned:
+		if f.Bits == 8 || f.Bits == 16 || f.Bits == 24 || f.Bits == 32 {
+			return nil
+		}
+		return errors.New("pcm: integer samples must have 8, 16, 24 or 32 bits")
+	case Float:
+		if f.Bits == 32 || f.Bits == 64 {
+			return nil
+		}
+		return errors.New("pcm: float samples must have 32 or 64 bits")
+	case MuLaw, ALaw:
+		if f.Bits == 8 {
+			return nil
+		}
+		return errors.New("pcm: companded samples must have 8 bits")
+	}
+	return errors.New("pcm: unknown sample encoding")
+}
+
+// Size returns the number of bytes of one sample.
+func (f SampleFormat) Size() int {
+	return f.Bits / 8
+}
+
+// Decode returns the sample value stored in the first Size() bytes of b.
+// Integer samples are scaled so that the most negative value becomes -1.
+func (f SampleFormat) Decode(b []byte) float64 {
+	if f.Encoding == Float {
+		if f.Bits == 32 {
+			return float64(math.Float32frombits(uint32(f.getUint(b))))
+		}
+		return math.Float64frombits(f.getUint(b))
+	}
+	if f.Encoding == MuLaw {
+		return float64(muLawToLinear(b[0])) / 32768
+	}
+	if f.Encoding == ALaw {
+		return float64(aLawToLinear(b[0])) / 32768
+	}
+
+	shift := uint(64 - f.Bits)
+	var v int64
+	if f.Encoding == Unsigned {
+		v = int64(f.getUint(b)) - 1<<uint(f.Bits-1)
+	} else {
+		// Move the sign bit to the top and back again to sign-extend.
+		v = int64(f.getUint(b)<<shift) >> shift
+	}
+	return float64(v) / float64(int64(1)<<uint(f.Bits-1))
+}
+
+// Encode stores x in the first Size() bytes of b. Integer samples are clamped
+// to the valid range and rounded to the nearest integer value.
+func (f SampleFormat) Encode(b []byte, x float64) {
+	if f.Encoding == Float {
+		if f.Bits == 32 {
+			f.putUint(b, uint64(math.Float32bits(float32(x))))
+		} else {
+			f.putUint(b, math.Float64bits(x))
+		}
+		return
+	}
+	if f.Encoding == MuLaw {
+		b[0] = linearToMuLaw(toInt16(x))
+		return
+	}
+	if f.Encoding == ALaw {
+		b[0] = linearToALaw(toInt16(x))
+		return
+	}
+
+	scale := float64(int64(1) << uint(f.Bits-1))
+	v := math.Floor(x*scale + 0.5)
+	if v < -scale || math.IsNaN(v) {
+		v = -scale
+	}
+	if v > scale-1 {
+		v = scale - 1
+	}
+	i := int64(v)
+	if f.Encoding == Unsigned {
+		i += int64(scale)
+	}
+	f.putUint(b, uint64(i))
+}
+
+func (f SampleFormat) getUint(b []byte) uint64 {
+	n := f.Size()
+	var v uint64
+	if f.BigEndian {
+		for i := 0; i < n; i++ {
+			v = v<<8 | uint64(b[i])
+		}
+	} else {
+		for i := n - 1; i >= 0; i-- {
+			v = v<<8 | uint64(b[i])
+		}
+	}
+	return v
+}
+
+func (f SampleFormat) putUint(b []byte, v uint64) {
+	n := f.Size()
+	if f.BigEndian {
+		for i := n - 1; i >= 0; i-- {
+			b[i] = byte(v)
+			v >>= 8
+		}
+	} else {
+		for i := 0; i < n; i++ {
+			b[i] = byte(v)
+			v >>= 8
+		}
+	}
+}
+
+// Deinterleave decodes whole frames from src. A frame consists of one sample
+// for every channel in dst. The samples of frame i are written to
+// dst[channel][offset+i]. The number of decoded frames is returned, it is
+// limited by the length of src and the lengths of the slices in dst.
+func (f SampleFormat) Deinterleave(dst [][]float64, offset int, src []byte) int {
+	n := f.frameLimit(dst, offset, len(src))
+	size := f.Size()
+	for i := 0; i < n; i++ {
+		for c := range dst {
+			dst[c][offset+i] = f.Decode(src)
+			src = src[size:]
+		}
+	}
+	return n
+}
+
+// Interleave encodes whole frames from the channels in src into dst. The
+// samples src[channel][offset+i] make up frame i. The number of encoded frames
+// is returned, it is limited by the length of dst and the lengths of the
+// slices in src.
+func (f SampleFormat) Interleave(dst []byte, src [][]float64, offset int) int {
+	n := f.frameLimit(src, offset, len(dst))
+	size := f.Size()
+	for i := 0; i < n; i++ {
+		for c := range src {
+			f.Encode(dst, src[c][offset+i])
+			dst = dst[size:]
+		}
+	}
+	return n
+}
+
+func (f SampleFormat) frameLimit(channels [][]float64, offset, byteCount int) int {
+	if len(channels) == 0 {
+		return 0
+	}
+	n := byteCount / (f.Size() * len(channels))
+	for _, c := range channels {
+		if len(c)-offset < n {
+			n = len(c) - offset
+		}
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// Float32 converts all channels to float32.
+func Float32(channels [][]float64) [][]float32 {
+	c32 := make([][]float32, len(channels))
+	for i, c := range channels {
+		c32[i] = make([]float32, len(c))
+		for j, x := range c {
+			c32[i][j] = float32(x)
+		}
+	}
+	return c32
+}
+
+// Float64 converts all channels to float64.
+func Float64(channels [][]float32) [][]float64 {
+	c64 := make([][]float64, len(channels))
+	for i, c := range channels {
+		c64[i] = make([]float64, len(c))
+		for j, x := range c {
+			c64[i][j] = float64(x)
+		}
+	}
+	return c64
+}
@@ -0,0 +1,115 @@
+package pcm
+
+import (
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestIntegerSamplesAreNormalized(t *testing.T) {
+	s16 := SampleFormat{Encoding: Signed, Bits: 16}
+	check.Eq(t, s16.Decode([]byte{0x00, 0x80}), -1)
+	check.Eq(t, s16.Decode([]byte{0x00, 0x00}), 0)
+	check.Eq(t, s16.Decode([]byte{0x00, 0x40}), 0.5)
+	check.Eq(t, s16.Decode([]byte{0xFF, 0x7F}), 32767.0/32768)
+
+	u8 := SampleFormat{Encoding: Unsigned, Bits: 8}
+	check.Eq(t, u8.Decode([]byte{0}), -1)
+	check.Eq(t, u8.Decode([]byte{128}), 0)
+	check.Eq(t, u8.Decode([]byte{192}), 0.5)
+
+	s24be := SampleFormat{Encoding: Signed, Bits: 24, BigEndian: true}
+	check.Eq(t, s24be.Decode([]byte{0xC0, 0x00, 0x00}), -0.5)
+	s24le := SampleFormat{Encoding: Signed, Bits: 24}
+	check.Eq(t, s24le.Decode([]byte{0x00, 0x00, 0xC0}), -0.5)
+
+	s32 := SampleFormat{Encoding: Signed, Bits: 32}
+	check.Eq(t, s32.Decode([]byte{0, 0, 0, 0x80}), -1)
+}
+
+func TestFloatSamplesAreStoredAsIs(t *testing.T) {
+	f32 := SampleFormat{Encoding: Float, Bits: 32}
+	check.Eq(t, f32.Decode([]byte{0x00, 0x00, 0xC0, 0x3F}), 1.5)
+	f64be := SampleFormat{Encoding: Float, Bits: 64, BigEndian: true}
+	check.Eq(t, f64be.Decode([]byte{0xBF, 0xF8, 0, 0, 0, 0, 0, 0}), -1.5)
+
+	b := make([]byte, 8)
+	f64be.Encode(b, -1.5)
+	check.Eq(t, b, []byte{0xBF, 0xF8, 0, 0, 0, 0, 0, 0})
+}
+
+func TestEncodingClampsAndRounds(t *testing.T) {
+	s16 := SampleFormat{Encoding: Signed, Bits: 16}
+	b := make([]byte, 2)
+	s16.Encode(b, 2)
+	check.Eq(t, b, []byte{0xFF, 0x7F})
+	s16.Encode(b, -2)
+	check.Eq(t, b, []byte{0x00, 0x80})
+	s16.Encode(b, 1.4/32768)
+	check.Eq(t, b, []byte{0x01, 0x00})
+	s16.Encode(b, -1.4/32768)
+	check.Eq(t, b, []byte{0xFF, 0xFF})
+
+	u8 := SampleFormat{Encoding: Unsigned, Bits: 8}
+	u8.Encode(b, 0)
+	check.Eq(t, b[0], 128)
+}
+
+func TestAllFormatsRoundTrip(t *testing.T) {
+	formats := []SampleFormat{
+		{Encoding: Unsigned, Bits: 8},
+		{Encoding: Signed, Bits: 8},
+		{Encoding: Signed, Bits: 16},
+		{Encoding: Signed, Bits: 24, BigEndian: true},
+		{Encoding: Signed, Bits: 32},
+		{Encoding: Float, Bits: 32},
+		{Encoding: Float, Bits: 64, BigEndian: true},
+	}
+	for _, f := range formats {
+		check.Eq(t, f.Validate(), nil)
+		values := []float64{-1, -0.5, 0, 0.25, 0.5}
+		b := make([]byte, f.Size())
+		for _, v := range values {
+			f.Encode(b, v)
+			check.Eq(t, f.Decode(b), v, f, v)
+		}
+	}
+}
+
+func TestInvalidFormatsAreReported(t *testing.T) {
+	check.Neq(t, SampleFormat{Encoding: Signed, Bits: 12}.Validate(), nil)
+	check.Neq(t, SampleFormat{Encoding: Float, Bits: 16}.Validate(), nil)
+	check.Neq(t, SampleFormat{Encoding: Encoding(99), Bits: 16}.Validate(), nil)
+}
+
+func TestInterleavingIsReversible(t *testing.T) {
+	f := SampleFormat{Encoding: Signed, Bits: 16}
+	src := [][]float64{{0.5, -0.5, 0}, {0.25, 0, -1}}
+	b := make([]byte, 12)
+	check.Eq(t, f.Interleave(b, src, 0), 3)
+	check.Eq(t, b, []byte{
+		0x00, 0x40, 0x00, 0x20,
+		0x00, 0xC0, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x80,
+	})
+
+	dst := [][]float64{make([]float64, 4), make([]float64, 4)}
+	check.Eq(t, f.Deinterleave(dst, 1, b), 3)
+	check.Eq(t, dst, [][]float64{{0, 0.5, -0.5, 0}, {0, 0.25, 0, -1}})
+}
+
+func TestInterleavingStopsAtShortestBuffer(t *testing.T) {
+	f := SampleFormat{Encoding: Unsigned, Bits: 8}
+	dst := [][]float64{make([]float64, 5), make([]float64, 2)}
+	check.Eq(t, f.Deinterleave(dst, 0, make([]byte, 7)), 2)
+	check.Eq(t, f.Deinterleave(dst, 0, make([]byte, 3)), 1)
+	check.Eq(t, f.Deinterleave(dst, 3, make([]byte, 8)), 0)
+	check.Eq(t, f.Deinterleave(nil, 0, make([]byte, 8)), 0)
+}
+
+func TestChannelsConvertBetweenFloatSizes(t *testing.T) {
+	c := [][]float64{{1, 0.5}, {-0.25}}
+	c32 := Float32(c)
+	check.Eq(t, c32, [][]float32{{1, 0.5}, {-0.25}})
+	check.Eq(t, Float64(c32), c)
+}
@@ -0,0 +1,161 @@
+package pcm
+
+import (
+	"errors"
+	"io"
+)
+
+// Reader reads headerless, interleaved sample data in blocks.
+type Reader struct {
+	format       SampleFormat
+	channelCount int
+	r            io.Reader
+	buf          []byte
+	buf64        [][]float64
+	err          error
+}
+
+// readBufferSize is the maximum number of bytes that Read reads from the
+// underlying io.Reader at once.
+const readBufferSize = 64 * 1024
+
+// NewReader returns a Reader that decodes frames of channelCount samples in
+// the given format from r.
+func NewReader(r io.Reader, format SampleFormat, channelCount int) (*Reader, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	if channelCount <= 0 {
+		return nil, errors.New("pcm: channel count must be positive")
+	}
+	return &Reader{format: format, channelCount: channelCount, r: r}, nil
+}
+
+// Read reads the next frames into dst which must contain one slice per
+// channel. It reads at most as many frames as the shortest slice in dst can
+// hold and returns the number of frames read. At the end of the data it
+// returns 0 and io.EOF. If the data ends in the middle of a frame, the
+// incomplete frame is dropped and io.ErrUnexpectedEOF is returned.
+func (r *Reader) Read(dst [][]float64) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(dst) != r.channelCount {
+		return 0, errors.New("pcm: Read needs one buffer per channel")
+	}
+
+	frameSize := r.format.Size() * r.channelCount
+	n := readBufferSize / frameSize
+	if n == 0 {
+		n = 1
+	}
+	for _, c := range dst {
+		if len(c) < n {
+			n = len(c)
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	if len(r.buf) < n*frameSize {
+		r.buf = make([]byte, n*frameSize)
+	}
+	m, err := io.ReadFull(r.r, r.buf[:n*frameSize])
+	if m%frameSize != 0 {
+		r.err = io.ErrUnexpectedEOF
+	} else if err == io.ErrUnexpectedEOF {
+		r.err = io.EOF
+	} else {
+		r.err = err
+	}
+
+	frames := r.format.Deinterleave(dst, 0, r.buf[:m])
+	if frames > 0 {
+		return frames, nil
+	}
+	return 0, r.err
+}
+
+// ReadFloat32 is like Read but converts the samples to float32.
+func (r *Reader) ReadFloat32(dst [][]float32) (int, error) {
+	if len(dst) != r.channelCount {
+		return 0, errors.New("pcm: Read needs one buffer per channel")
+	}
+	if r.buf64 == nil {
+		r.buf64 = make([][]float64, len(dst))
+		for i := range r.buf64 {
+			r.buf64[i] = make([]float64, 4096)
+		}
+	}
+	buf := make([][]float64, len(dst))
+	for i := range buf {
+		buf[i] = r.buf64[i]
+		if len(dst[i]) < len(buf[i]) {
+			buf[i] = buf[i][:len(dst[i])]
+		}
+	}
+	n, err := r.Read(buf)
+	for i := range buf {
+		for j := 0; j < n; j++ {
+			dst[i][j] = float32(buf[i][j])
+		}
+	}
+	return n, err
+}
+
+// Decode reads all frames from r and returns one slice of samples per channel.
+func Decode(r io.Reader, format SampleFormat, channelCount int) ([][]float64, error) {
+	pr, err := NewReader(r, format, channelCount)
+	if err != nil {
+		return nil, err
+	}
+	channels := make([][]float64, channelCount)
+	block := make([][]float64, channelCount)
+	for i := range block {
+		block[i] = make([]float64, 4096)
+	}
+	for {
+		n, err := pr.Read(block)
+		for i := range channels {
+			channels[i] = append(channels[i], block[i][:n]...)
+		}
+		if err == io.EOF {
+			return channels, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Encode writes the samples interleaved in the given format to w. All
+// channels must have the same length.
+func Encode(w io.Writer, format SampleFormat, channels [][]float64) error {
+	if err := format.Validate(); err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+	n := len(channels[0])
+	for _, c := range channels {
+		if len(c) != n {
+			return errors.New("pcm: channels have different lengths")
+		}
+	}
+	frameSize := format.Size() * len(channels)
+	block := readBufferSize / frameSize
+	if block == 0 {
+		block = 1
+	}
+	buf := make([]byte, block*frameSize)
+	for offset := 0; offset < n; {
+		m := format.Interleave(buf, channels, offset)
+		if _, err := w.Write(buf[:m*frameSize]); err != nil {
+			return err
+		}
+		offset += m
+	}
+	return nil
+}
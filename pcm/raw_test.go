@@ -0,0 +1,74 @@
+package pcm
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestRawDataIsDecodedPerChannel(t *testing.T) {
+	f := SampleFormat{Encoding: Signed, Bits: 16, BigEndian: true}
+	data := []byte{
+		0x40, 0x00, 0xC0, 0x00,
+		0x80, 0x00, 0x00, 0x00,
+	}
+	channels, err := Decode(bytes.NewReader(data), f, 2)
+	check.Eq(t, err, nil)
+	check.Eq(t, channels, [][]float64{{0.5, -1}, {-0.5, 0}})
+}
+
+func TestIncompleteRawFrameIsUnexpectedEOF(t *testing.T) {
+	f := SampleFormat{Encoding: Signed, Bits: 16}
+	_, err := Decode(bytes.NewReader(make([]byte, 7)), f, 2)
+	check.Eq(t, err, io.ErrUnexpectedEOF)
+
+	r, _ := NewReader(bytes.NewReader(make([]byte, 7)), f, 2)
+	dst := [][]float64{make([]float64, 10), make([]float64, 10)}
+	n, err := r.Read(dst)
+	check.Eq(t, n, 1)
+	check.Eq(t, err, nil)
+	n, err = r.Read(dst)
+	check.Eq(t, n, 0)
+	check.Eq(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestRawDataRoundTripsInBlocks(t *testing.T) {
+	f := SampleFormat{Encoding: Float, Bits: 32}
+	in := [][]float64{make([]float64, 50000), make([]float64, 50000), make([]float64, 50000)}
+	for i := range in[0] {
+		in[0][i] = float64(i%100) / 100
+		in[1][i] = -in[0][i]
+		in[2][i] = float64(i%7) / 8
+	}
+	var b bytes.Buffer
+	check.Eq(t, Encode(&b, f, in), nil)
+	check.Eq(t, b.Len(), 50000*3*4)
+
+	r, err := NewReader(&b, f, 3)
+	check.Eq(t, err, nil)
+	out := make([][]float32, 3)
+	block := [][]float32{make([]float32, 999), make([]float32, 999), make([]float32, 999)}
+	for {
+		n, err := r.ReadFloat32(block)
+		for i := range out {
+			out[i] = append(out[i], block[i][:n]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		check.Eq(t, err, nil)
+	}
+	check.Eq(t, Float64(out), in)
+}
+
+func TestRawArgumentsAreChecked(t *testing.T) {
+	_, err := NewReader(nil, SampleFormat{Encoding: Signed, Bits: 16}, 0)
+	check.Neq(t, err, nil)
+	_, err = NewReader(nil, SampleFormat{Encoding: Signed, Bits: 7}, 1)
+	check.Neq(t, err, nil)
+	var b bytes.Buffer
+	err = Encode(&b, SampleFormat{Encoding: Signed, Bits: 16}, [][]float64{{1}, {}})
+	check.Neq(t, err, nil)
+}
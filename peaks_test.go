@@ -0,0 +1,121 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+var peakTestSignal = []FLOAT{0, 1, 0, 3, 1, 2, 1, 5, 5, 5, 0, 2, 0}
+
+func peakIndices(peaks []Peak) []int {
+	indices := make([]int, len(peaks))
+	for i, p := range peaks {
+		indices[i] = p.Index
+	}
+	return indices
+}
+
+func TestFindPeaksReturnsAllLocalMaxima(t *testing.T) {
+	peaks := FindPeaks(peakTestSignal, PeakOptions{})
+	check.Eq(t, peakIndices(peaks), []int{1, 3, 5, 8, 11})
+	check.Eq(t, len(FindPeaks(nil, PeakOptions{})), 0)
+	check.Eq(t, len(FindPeaks([]FLOAT{1, 2, 2}, PeakOptions{})), 0)
+}
+
+func TestFindPeaksDescribesPeak(t *testing.T) {
+	peaks := FindPeaks(peakTestSignal, PeakOptions{})
+	p := peaks[1]
+	check.EqEps(t, p.Width, 2.5/3, 1e-6)
+	p.Width = 0
+	check.Eq(t, p, Peak{
+		Index:          3,
+		Value:          3,
+		LeftEdge:       3,
+		RightEdge:      3,
+		LeftThreshold:  3,
+		RightThreshold: 2,
+		Prominence:     2,
+		LeftBase:       2,
+		RightBase:      4,
+		WidthHeight:    2,
+		LeftPosition:   FLOAT(2) + FLOAT(2)/3,
+		RightPosition:  3.5,
+	})
+
+	flat := peaks[3]
+	check.Eq(t, flat.Index, 8)
+	check.Eq(t, flat.LeftEdge, 7)
+	check.Eq(t, flat.RightEdge, 9)
+	check.Eq(t, flat.Prominence, 5)
+	check.Eq(t, flat.LeftBase, 2)
+	check.Eq(t, flat.RightBase, 10)
+	check.Eq(t, flat.LeftPosition, 6.375)
+	check.Eq(t, flat.RightPosition, 9.5)
+	check.Eq(t, flat.Width, 3.125)
+}
+
+func TestFindPeaksFiltersPeaks(t *testing.T) {
+	inf := FLOAT(math.Inf(1))
+	for _, test := range []struct {
+		options PeakOptions
+		want    []int
+	}{
+		{PeakOptions{MinHeight: 2, MaxHeight: inf}, []int{3, 5, 8, 11}},
+		{PeakOptions{MinHeight: 2, MaxHeight: 4}, []int{3, 5, 11}},
+		{PeakOptions{MinHeight: 2}, []int{3, 5, 8, 11}},
+		{PeakOptions{MinHeight: 3, MaxHeight: 1}, []int{3, 8}},
+		{PeakOptions{MaxHeight: 2}, []int{1, 5, 11}},
+		{PeakOptions{MinThreshold: 1.5}, []int{3, 8, 11}},
+		{PeakOptions{MinDistance: 4}, []int{3, 8}},
+		{PeakOptions{MinProminence: 2}, []int{3, 8, 11}},
+		{PeakOptions{MinPlateau: 2}, []int{8}},
+		{PeakOptions{MinWidth: 1.5}, []int{8}},
+		{PeakOptions{MaxWidth: 1}, []int{1, 3, 5, 11}},
+	} {
+		peaks := FindPeaks(peakTestSignal, test.options)
+		check.Eq(t, peakIndices(peaks), test.want, test.options)
+	}
+}
+
+func TestPeakWindowLimitsBaseSearch(t *testing.T) {
+	peaks := FindPeaks(peakTestSignal, PeakOptions{Window: 4})
+	p := peaks[3]
+	check.Eq(t, p.Index, 8)
+	check.Eq(t, p.LeftBase, 6)
+	check.Eq(t, p.Prominence, 4)
+}
+
+func TestPeakWidthAtRelativeHeight(t *testing.T) {
+	p := FindPeaks(peakTestSignal, PeakOptions{RelativeHeight: 1})[3]
+	check.Eq(t, p.WidthHeight, 0)
+	check.Eq(t, p.LeftPosition, 2)
+	check.Eq(t, p.RightPosition, 10)
+}
+
+func TestSubSamplePeakInterpolation(t *testing.T) {
+	parabola := make([]FLOAT, 5)
+	gauss := make([]FLOAT, 5)
+	for i := range parabola {
+		x := float64(i) - 2.3
+		parabola[i] = FLOAT(5 - x*x)
+		gauss[i] = FLOAT(3 * math.Exp(-x*x/2))
+	}
+	pos, value := ParabolicPeak(parabola, 2)
+	check.EqEps(t, pos, 2.3, 1e-5)
+	check.EqEps(t, value, 5, 1e-5)
+	pos, value = GaussianPeak(gauss, 2)
+	check.EqEps(t, pos, 2.3, 1e-5)
+	check.EqEps(t, value, 3, 1e-5)
+
+	pos, value = ParabolicPeak(parabola, 0)
+	check.Eq(t, pos, 0)
+	check.Eq(t, value, parabola[0])
+	pos, value = GaussianPeak([]FLOAT{-1, 2, 1}, 1)
+	check.Eq(t, pos, 1.25)
+	check.Eq(t, value, 2.125)
+	pos, value = ParabolicPeak([]FLOAT{1, 1, 1}, 1)
+	check.Eq(t, pos, 1)
+	check.Eq(t, value, 1)
+}
@@ -0,0 +1,364 @@
+package dsp
+
+import "math"
+
+// PitchMethod selects the algorithm for pitch detection.
+type PitchMethod int
+
+const (
+	// YINPitch is the YIN algorithm by de Cheveigné and Kawahara, with the
+	// voicing probability of the first stage of pYIN by Mauch and Dixon: the
+	// search threshold is not fixed but follows a beta distribution with a
+	// mean of 0.1.
+	YINPitch PitchMethod = iota
+	// AutocorrelationPitch picks the first peak of the normalized
+	// autocorrelation that is at least 0.9 times as high as the highest one.
+	// Its probability is the height of that peak.
+	AutocorrelationPitch
+	// HarmonicProductSpectrumPitch multiplies the magnitude spectrum with
+	// copies of itself that are compressed by the factors 2 to 5, which lines
+	// up the harmonics at the fundamental frequency. Its probability is the
+	// fraction of the signal power in the first five harmonics. The highest
+	// detected frequency is a tenth of the sample rate.
+	HarmonicProductSpectrumPitch
+)
+
+// PitchOptions configures pitch detection. Zero values select the defaults.
+type PitchOptions struct {
+	Method PitchMethod
+	// MinFrequency and MaxFrequency limit the detected pitch, in Hz. They
+	// default to 50 and 2000.
+	MinFrequency, MaxFrequency FLOAT
+	// FrameLength is the number of samples per frame for TrackPitch. It
+	// defaults to the smallest power of 2 that holds two periods of
+	// MinFrequency.
+	FrameLength int
+	// Hop is the number of samples between frames for TrackPitch. It defaults
+	// to FrameLength/4.
+	Hop int
+}
+
+// PitchEstimate is the fundamental frequency of a frame.
+type PitchEstimate struct {
+	// Time is the center of the frame in seconds.
+	Time FLOAT
+	// Frequency is the best estimate of the fundamental frequency in Hz, or
+	// 0 if none was found.
+	Frequency FLOAT
+	// Probability is the probability, from 0 to 1, that the frame is voiced,
+	// i.e. has a pitch. Frames with a probability below 0.5 are usually
+	// treated as unvoiced.
+	Probability FLOAT
+}
+
+func (o PitchOptions) withDefaults(sampleRate FLOAT) PitchOptions {
+	if o.MinFrequency <= 0 {
+		o.MinFrequency = 50
+	}
+	if o.MaxFrequency <= 0 {
+		o.MaxFrequency = 2000
+	}
+	if o.MaxFrequency > sampleRate/2 {
+		o.MaxFrequency = sampleRate / 2
+	}
+	if o.FrameLength <= 0 {
+		o.FrameLength = nextPowerOf2(int(math.Ceil(2 * float64(sampleRate/o.MinFrequency))))
+	}
+	if o.Hop <= 0 {
+		o.Hop = o.FrameLength / 4
+	}
+	if o.Hop < 1 {
+		o.Hop = 1
+	}
+	return o
+}
+
+// nextPowerOf2 returns the smallest power of 2 that is at least n.
+func nextPowerOf2(n int) int {
+	p := 1
+	for p < n {
+		p *= 2
+	}
+	return p
+}
+
+// Pitch estimates the fundamental frequency of the given frame. The returned
+// Time is 0. The frame should hold at least two periods of the lowest
+// frequency that is to be detected.
+func Pitch(frame []FLOAT, sampleRate FLOAT, options PitchOptions) PitchEstimate {
+	options = options.withDefaults(sampleRate)
+	x := make([]float64, len(frame))
+	for i := range frame {
+		x[i] = float64(frame[i])
+	}
+	var f, p float64
+	switch options.Method {
+	case AutocorrelationPitch:
+		f, p = autocorrelationPitch(x, float64(sampleRate), options)
+	case HarmonicProductSpectrumPitch:
+		f, p = harmonicProductPitch(x, float64(sampleRate), options)
+	default:
+		f, p = yinPitch(x, float64(sampleRate), options)
+	}
+	return PitchEstimate{Frequency: FLOAT(f), Probability: FLOAT(p)}
+}
+
+// TrackPitch splits a into frames of options.FrameLength samples that start
+// every options.Hop samples and returns the pitch of each frame. Only whole
+// frames are used.
+func TrackPitch(a []FLOAT, sampleRate FLOAT, options PitchOptions) []PitchEstimate {
+	options = options.withDefaults(sampleRate)
+	var pitches []PitchEstimate
+	for start := 0; start+options.FrameLength <= len(a); start += options.Hop {
+		p := Pitch(a[start:start+options.FrameLength], sampleRate, options)
+		p.Time = FLOAT((float64(start) + float64(options.FrameLength)/2) / float64(sampleRate))
+		pitches = append(pitches, p)
+	}
+	return pitches
+}
+
+// lagRange returns the range of periods in samples for the frequency limits.
+// The largest lag is at most half the frame length.
+func lagRange(n int, sampleRate float64, o PitchOptions) (minLag, maxLag int) {
+	minLag = int(math.Floor(sampleRate / float64(o.MaxFrequency)))
+	maxLag = int(math.Ceil(sampleRate / float64(o.MinFrequency)))
+	if maxLag > n/2 {
+		maxLag = n / 2
+	}
+	if minLag < 1 {
+		minLag = 1
+	}
+	return
+}
+
+// lagProducts returns r[lag] = sum of x[j] * x[j+lag] for j from 0 to w-1 and
+// lag from 0 to maxLag, computed with an FFT.
+func lagProducts(x []float64, w, maxLag int) []float64 {
+	m := nextPowerOf2(w + maxLag)
+	a := make([]complex128, m)
+	b := make([]complex128, m)
+	for i := 0; i < w+maxLag && i < len(x); i++ {
+		a[i] = complex(x[i], 0)
+	}
+	for i := 0; i < w; i++ {
+		b[i] = complex(x[i], 0)
+	}
+	fft(a, false)
+	fft(b, false)
+	for i := range a {
+		a[i] *= complex(real(b[i]), -imag(b[i]))
+	}
+	fft(a, true)
+	r := make([]float64, maxLag+1)
+	for i := range r {
+		r[i] = real(a[i]) / float64(m)
+	}
+	return r
+}
+
+// squareSums returns s[i] = sum of x[j]^2 for j < i.
+func squareSums(x []float64) []float64 {
+	s := make([]float64, len(x)+1)
+	for i, v := range x {
+		s[i+1] = s[i] + v*v
+	}
+	return s
+}
+
+// yinThresholds are the thresholds of pYIN with their probabilities from a
+// beta distribution with parameters 2 and 18.
+var yinThresholds, yinThresholdWeights = func() ([]float64, []float64) {
+	t := make([]float64, 100)
+	w := make([]float64, 100)
+	var sum float64
+	for i := range t {
+		t[i] = float64(i+1) / 100
+		w[i] = t[i] * math.Pow(1-t[i], 17)
+		sum += w[i]
+	}
+	for i := range w {
+		w[i] /= sum
+	}
+	return t, w
+}()
+
+func yinPitch(x []float64, sampleRate float64, o PitchOptions) (frequency, probability float64) {
+	minLag, maxLag := lagRange(len(x), sampleRate, o)
+	if maxLag <= minLag {
+		return 0, 0
+	}
+	w := len(x) - maxLag
+	r := lagProducts(x, w, maxLag)
+	s := squareSums(x)
+
+	// d is the cumulative mean normalized difference function.
+	d := make([]float64, maxLag+1)
+	d[0] = 1
+	var sum float64
+	for lag := 1; lag <= maxLag; lag++ {
+		diff := s[w] + s[lag+w] - s[lag] - 2*r[lag]
+		if diff < 0 {
+			diff = 0
+		}
+		sum += diff
+		if sum > 0 {
+			d[lag] = diff * float64(lag) / sum
+		} else {
+			d[lag] = 1
+		}
+	}
+
+	// For each threshold, YIN picks the first local minimum below it.
+	var dips []int
+	for lag := minLag; lag <= maxLag; lag++ {
+		if (lag == minLag || d[lag] <= d[lag-1]) && (lag == maxLag || d[lag] < d[lag+1]) {
+			dips = append(dips, lag)
+		}
+	}
+	weights := make([]float64, len(dips))
+	for i, t := range yinThresholds {
+		for j, lag := range dips {
+			if d[lag] < t {
+				weights[j] += yinThresholdWeights[i]
+				break
+			}
+		}
+	}
+	best := -1
+	for i := range dips {
+		probability += weights[i]
+		if weights[i] > 0 && (best == -1 || weights[i] > weights[best]) {
+			best = i
+		}
+	}
+	if best == -1 {
+		return 0, 0
+	}
+	return sampleRate / refinePeak(d, dips[best]), math.Min(probability, 1)
+}
+
+func autocorrelationPitch(x []float64, sampleRate float64, o PitchOptions) (frequency, probability float64) {
+	minLag, maxLag := lagRange(len(x), sampleRate, o)
+	if maxLag <= minLag {
+		return 0, 0
+	}
+	// One lag beyond the range is needed to find a peak at its end.
+	w := len(x) - maxLag - 1
+	r := lagProducts(x, w, maxLag+1)
+	s := squareSums(x)
+	n := make([]float64, len(r))
+	for lag := range n {
+		e := s[w] * (s[lag+w] - s[lag])
+		if e > 0 {
+			n[lag] = r[lag] / math.Sqrt(e)
+		}
+	}
+
+	var peaks []int
+	highest := 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		if n[lag] > n[lag-1] && n[lag] >= n[lag+1] && n[lag] > 0 {
+			peaks = append(peaks, lag)
+			if n[lag] > highest {
+				highest = n[lag]
+			}
+		}
+	}
+	for _, lag := range peaks {
+		if n[lag] >= 0.9*highest {
+			return sampleRate / refinePeak(n, lag), math.Min(n[lag], 1)
+		}
+	}
+	return 0, 0
+}
+
+// refinePeak returns the position of the extremum of a parabola through d
+// around i.
+func refinePeak(d []float64, i int) float64 {
+	if i <= 0 || i >= len(d)-1 {
+		return float64(i)
+	}
+	offset, _ := parabolicVertex(d[i-1], d[i], d[i+1])
+	return float64(i) + offset
+}
+
+// hpsHarmonics is the number of harmonics for the harmonic product spectrum.
+const hpsHarmonics = 5
+
+func harmonicProductPitch(x []float64, sampleRate float64, o PitchOptions) (frequency, probability float64) {
+	if len(x) < 2 {
+		return 0, 0
+	}
+	// Zero padding by a factor of 4 gives a finer frequency resolution.
+	const pad = 4
+	m := pad * nextPowerOf2(len(x))
+	spectrum := make([]complex128, m)
+	for i, v := range x {
+		hann := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(len(x)-1))
+		spectrum[i] = complex(v*hann, 0)
+	}
+	fft(spectrum, false)
+	mag := make([]float64, m/2+1)
+	var total float64
+	for k := range mag {
+		mag[k] = math.Hypot(real(spectrum[k]), imag(spectrum[k]))
+		total += mag[k] * mag[k]
+	}
+	if total == 0 {
+		return 0, 0
+	}
+
+	binWidth := sampleRate / float64(m)
+	lo := int(math.Ceil(float64(o.MinFrequency) / binWidth))
+	hi := int(math.Floor(float64(o.MaxFrequency) / binWidth))
+	if lo < 1 {
+		lo = 1
+	}
+	if hi > (len(mag)-1)/hpsHarmonics {
+		hi = (len(mag) - 1) / hpsHarmonics
+	}
+	if hi < lo {
+		return 0, 0
+	}
+	// The product is computed as a sum of logarithms, for bins around the
+	// range to refine the peak. Magnitudes are limited to 60 dB below the
+	// maximum so that missing harmonics, e.g. of a pure sine wave, do not
+	// make the product depend on noise.
+	floor := 0.0
+	for _, v := range mag {
+		floor = math.Max(floor, v)
+	}
+	floor *= 1e-3
+	product := make([]float64, hi+2)
+	for k := lo - 1; k <= hi+1; k++ {
+		for h := 1; h <= hpsHarmonics; h++ {
+			if h*k < len(mag) {
+				product[k] += math.Log(math.Max(mag[h*k], floor))
+			}
+		}
+	}
+	// Subharmonics of a pure sine wave have the same product, prefer the
+	// highest frequency.
+	best := lo
+	for k := lo; k <= hi; k++ {
+		if product[k] >= product[best] {
+			best = k
+		}
+	}
+	f0 := refinePeak(product, best) * binWidth
+
+	// The main lobe of the Hann window is 2 unpadded bins wide on each side.
+	width := 2 * m / len(x)
+	var harmonic float64
+	last := -1
+	for h := 1; h <= hpsHarmonics; h++ {
+		center := int(math.Floor(float64(h)*f0/binWidth + 0.5))
+		for k := center - width; k <= center+width; k++ {
+			if k > last && k >= 0 && k < len(mag) {
+				harmonic += mag[k] * mag[k]
+				last = k
+			}
+		}
+	}
+	return f0, math.Min(harmonic/total, 1)
+}
@@ -0,0 +1,72 @@
+package dsp
+
+import (
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+// harmonicTone returns n samples of a tone with 8 harmonics that fall off
+// with 1/h.
+func harmonicTone(n int, f0, sampleRate FLOAT) []FLOAT {
+	a := make([]FLOAT, n)
+	for h := 1; h <= 8; h++ {
+		if FLOAT(h)*f0 < sampleRate/2 {
+			a = Add(a, Sine(n, 0.5/FLOAT(h), FLOAT(h)*f0, FLOAT(h), sampleRate))
+		}
+	}
+	return a
+}
+
+var pitchMethods = []PitchMethod{YINPitch, AutocorrelationPitch, HarmonicProductSpectrumPitch}
+
+func TestPitchOfHarmonicTone(t *testing.T) {
+	const sampleRate = 16000
+	for _, method := range pitchMethods {
+		for _, f0 := range []FLOAT{82.4, 110, 220, 261.6, 440, 987.8} {
+			frame := harmonicTone(2048, f0, sampleRate)
+			p := Pitch(frame, sampleRate, PitchOptions{Method: method, MinFrequency: 60})
+			check.EqEps(t, p.Frequency, f0, 0.005*float64(f0), method, f0)
+			check.Eq(t, p.Probability > 0.8, true, method, f0, p.Probability)
+			check.Eq(t, p.Time, 0)
+		}
+	}
+}
+
+func TestPitchOfPureSine(t *testing.T) {
+	for _, method := range pitchMethods {
+		p := Pitch(Sine(4096, 1, 300, 0, 44100), 44100, PitchOptions{Method: method})
+		check.EqEps(t, p.Frequency, 300, 1, method)
+	}
+}
+
+func TestNoiseIsUnvoiced(t *testing.T) {
+	noise := Noise(2048, WhiteNoise, 1, 5)
+	for _, method := range pitchMethods {
+		p := Pitch(noise, 16000, PitchOptions{Method: method})
+		check.Eq(t, p.Probability < 0.3, true, method, p.Probability)
+		p = Pitch(make([]FLOAT, 2048), 16000, PitchOptions{Method: method})
+		check.Eq(t, p.Probability, 0, method)
+	}
+}
+
+func TestTrackPitchFollowsMelody(t *testing.T) {
+	const sampleRate = 8000
+	a := append(harmonicTone(4000, 200, sampleRate), harmonicTone(4000, 300, sampleRate)...)
+	for _, method := range pitchMethods {
+		options := PitchOptions{Method: method, MinFrequency: 100, MaxFrequency: 400, Hop: 500}
+		pitches := TrackPitch(a, sampleRate, options)
+		// Frames are 256 samples long.
+		check.Eq(t, len(pitches), 16, method)
+		for _, p := range pitches {
+			want := FLOAT(200)
+			if p.Time > 0.5 {
+				want = 300
+			}
+			if p.Time < 0.48 || p.Time > 0.52 {
+				check.EqEps(t, p.Frequency, want, 0.01*float64(want), method, p.Time)
+			}
+		}
+		check.Eq(t, pitches[0].Time, 128.0/sampleRate)
+	}
+}
@@ -0,0 +1,299 @@
+package dsp
+
+import "sort"
+
+// Processor processes a stream of samples in blocks of arbitrary size. The
+// output of a Processor does not depend on how the input is split into blocks.
+type Processor interface {
+	// Process reads all samples from in and writes the same number of samples
+	// to out. out must be at least as long as in. in and out may be the same
+	// slice for in-place processing but must not overlap otherwise.
+	Process(in, out []FLOAT)
+	// Reset puts the Processor back into its initial state, as if no samples
+	// were processed yet.
+	Reset()
+	// Latency returns the number of samples by which the output lags behind
+	// the input. The first Latency() output samples after a Reset depend on the
+	// zero initial state.
+	Latency() int
+}
+
+// Gain is a Processor that scales its input by Factor, see Scale.
+type Gain struct {
+	Factor FLOAT
+}
+
+// Process implements Processor.
+func (g *Gain) Process(in, out []FLOAT) {
+	for i := range in {
+		out[i] = in[i] * g.Factor
+	}
+}
+
+// Reset implements Processor, Gain has no state.
+func (*Gain) Reset() {}
+
+// Latency implements Processor, Gain has no latency.
+func (*Gain) Latency() int { return 0 }
+
+// Offset is a Processor that adds Offset to its input, see AddOffset.
+type Offset struct {
+	Offset FLOAT
+}
+
+// Process implements Processor.
+func (o *Offset) Process(in, out []FLOAT) {
+	for i := range in {
+		out[i] = in[i] + o.Offset
+	}
+}
+
+// Reset implements Processor, Offset has no state.
+func (*Offset) Reset() {}
+
+// Latency implements Processor, Offset has no latency.
+func (*Offset) Latency() int { return 0 }
+
+// MovingAverage is the streaming version of AverageFilter. After the first
+// width-1 samples, its output is the same as that of AverageFilter.
+type MovingAverage struct {
+	width   int
+	history ring
+	sum     FLOAT
+	count   int
+}
+
+// NewMovingAverage returns a MovingAverage over width samples. A width of 1 or
+// less passes the input through unchanged.
+func NewMovingAverage(width int) *MovingAverage {
+	if width < 1 {
+		width = 1
+	}
+	return &MovingAverage{width: width, history: newRing(width)}
+}
+
+// Process implements Processor.
+func (m *MovingAverage) Process(in, out []FLOAT) {
+	if m.width == 1 {
+		copy(out, in)
+		return
+	}
+	f := 1.0 / FLOAT(m.width)
+	block := averageFilterBlock(m.width)
+	for i, x := range in {
+		old := m.history.push(x)
+		// Index in the output of AverageFilter, mirror its computation to
+		// produce the exact same values.
+		k := m.count - (m.width - 1)
+		if k >= 0 && k%block == 0 {
+			m.sum = 0
+			for j := 0; j < m.width; j++ {
+				m.sum += m.history.at(j)
+			}
+		} else {
+			m.sum += x - old
+		}
+		out[i] = m.sum * f
+		m.count++
+	}
+}
+
+// Reset implements Processor.
+func (m *MovingAverage) Reset() {
+	m.history.clear()
+	m.sum = 0
+	m.count = 0
+}
+
+// Latency implements Processor and returns width-1.
+func (m *MovingAverage) Latency() int { return m.width - 1 }
+
+// MovingMedian is the streaming version of MedianFilter. After the first
+// width-1 samples, its output is the same as that of MedianFilter.
+type MovingMedian struct {
+	width   int
+	history ring
+	buf     []FLOAT
+}
+
+// NewMovingMedian returns a MovingMedian over width samples. A width of 1 or
+// less passes the input through unchanged.
+func NewMovingMedian(width int) *MovingMedian {
+	if width < 1 {
+		width = 1
+	}
+	return &MovingMedian{
+		width:   width,
+		history: newRing(width),
+		buf:     make([]FLOAT, width),
+	}
+}
+
+// Process implements Processor.
+func (m *MovingMedian) Process(in, out []FLOAT) {
+	for i, x := range in {
+		m.history.push(x)
+		for j := range m.buf {
+			m.buf[j] = m.history.at(j)
+		}
+		sort.Sort(floats(m.buf))
+		out[i] = m.buf[m.width/2]
+	}
+}
+
+// Reset implements Processor.
+func (m *MovingMedian) Reset() { m.history.clear() }
+
+// Latency implements Processor and returns width-1.
+func (m *MovingMedian) Latency() int { return m.width - 1 }
+
+// Differentiator is the streaming version of Derivative. After the first
+// sample, its output is the same as that of Derivative.
+type Differentiator struct {
+	last FLOAT
+}
+
+// Process implements Processor.
+func (d *Differentiator) Process(in, out []FLOAT) {
+	for i, x := range in {
+		out[i] = x - d.last
+		d.last = x
+	}
+}
+
+// Reset implements Processor.
+func (d *Differentiator) Reset() { d.last = 0 }
+
+// Latency implements Processor and returns 1.
+func (*Differentiator) Latency() int { return 1 }
+
+// FIRFilter returns a new array of the values in a, filtered with the finite
+// impulse response h, i.e. a convolved with h. Only outputs for which all
+// of h overlaps a are computed, the result is len(h)-1 smaller than a.
+// If h is empty, a copy of a is returned.
+// If h is longer than a, an empty array is returned.
+func FIRFilter(a, h []FLOAT) []FLOAT {
+	if len(h) == 0 {
+		return Copy(a)
+	}
+	if len(h) > len(a) {
+		return nil
+	}
+
+	b := make([]FLOAT, len(a)-len(h)+1)
+	last := len(h) - 1
+	for i := range b {
+		var sum FLOAT
+		for k, c := range h {
+			sum += c * a[i+last-k]
+		}
+		b[i] = sum
+	}
+	return b
+}
+
+// FIR is the streaming version of FIRFilter. After the first len(h)-1 samples,
+// its output is the same as that of FIRFilter.
+type FIR struct {
+	h       []FLOAT
+	history ring
+}
+
+// NewFIR returns a FIR filter with the impulse response h. h is copied. An
+// empty h passes the input through unchanged.
+func NewFIR(h []FLOAT) *FIR {
+	if len(h) == 0 {
+		h = []FLOAT{1}
+	}
+	return &FIR{h: Copy(h), history: newRing(len(h))}
+}
+
+// Process implements Processor.
+func (f *FIR) Process(in, out []FLOAT) {
+	last := len(f.h) - 1
+	for i, x := range in {
+		f.history.push(x)
+		var sum FLOAT
+		for k, c := range f.h {
+			sum += c * f.history.at(last-k)
+		}
+		out[i] = sum
+	}
+}
+
+// Reset implements Processor.
+func (f *FIR) Reset() { f.history.clear() }
+
+// Latency implements Processor and returns len(h)-1.
+func (f *FIR) Latency() int { return len(f.h) - 1 }
+
+// Chain is a Processor that feeds its input through all its Processors, one
+// after the other. The output of one Processor is the input of the next.
+type Chain []Processor
+
+// Process implements Processor.
+func (c Chain) Process(in, out []FLOAT) {
+	if len(c) == 0 {
+		copy(out, in)
+		return
+	}
+	c[0].Process(in, out)
+	for _, p := range c[1:] {
+		p.Process(out[:len(in)], out[:len(in)])
+	}
+}
+
+// Reset implements Processor and resets all Processors in the Chain.
+func (c Chain) Reset() {
+	for _, p := range c {
+		p.Reset()
+	}
+}
+
+// Latency implements Processor and returns the sum of latencies of all
+// Processors in the Chain.
+func (c Chain) Latency() int {
+	sum := 0
+	for _, p := range c {
+		sum += p.Latency()
+	}
+	return sum
+}
+
+// ring holds the last n values pushed into it, initially all zeros.
+type ring struct {
+	values []FLOAT
+	next   int
+}
+
+func newRing(n int) ring {
+	return ring{values: make([]FLOAT, n)}
+}
+
+// push adds x as the newest value and returns the oldest value that it
+// replaces.
+func (r *ring) push(x FLOAT) FLOAT {
+	old := r.values[r.next]
+	r.values[r.next] = x
+	r.next++
+	if r.next == len(r.values) {
+		r.next = 0
+	}
+	return old
+}
+
+// at returns the i'th value, 0 being the oldest and n-1 the newest value.
+func (r *ring) at(i int) FLOAT {
+	i += r.next
+	if i >= len(r.values) {
+		i -= len(r.values)
+	}
+	return r.values[i]
+}
+
+func (r *ring) clear() {
+	for i := range r.values {
+		r.values[i] = 0
+	}
+	r.next = 0
+}
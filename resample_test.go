@@ -0,0 +1,137 @@
+package dsp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func sine(n int, frequency float64) []FLOAT {
+	a := make([]FLOAT, n)
+	for i := range a {
+		a[i] = FLOAT(math.Sin(2 * math.Pi * frequency * float64(i)))
+	}
+	return a
+}
+
+func TestResampledLengthIsRoundedUp(t *testing.T) {
+	a := make([]FLOAT, 100)
+	check.Eq(t, len(Resample(a, 3, 2, ResampleLow)), 150)
+	check.Eq(t, len(Resample(a, 1, 3, ResampleLow)), 34)
+	check.Eq(t, len(Resample(a, 160, 147, ResampleLow)), 109)
+	check.Eq(t, len(ResampleRatio(a, 0.333, ResampleLow)), 34)
+	check.Eq(t, len(ResampleRatio(a, 2.5, ResampleLow)), 250)
+	check.Eq(t, len(Resample(nil, 2, 1, ResampleLow)), 0)
+}
+
+func TestResamplingByOneKeepsSignal(t *testing.T) {
+	a := randomFloats(1000, 1)
+	check.Eq(t, Resample(a, 7, 7, ResampleHigh), a)
+}
+
+func TestResamplingKeepsPassband(t *testing.T) {
+	in := sine(4000, 0.1)
+	for _, test := range []struct {
+		q        ResampleQuality
+		maxError float64
+	}{
+		{ResampleLow, 1e-3},
+		{ResampleMedium, 3e-5},
+		{ResampleHigh, 3e-6},
+	} {
+		rational := Resample(in, 160, 147, test.q)
+		ratio := ResampleRatio(in, 160.0/147, test.q)
+		for m := 300; m < len(rational)-300; m++ {
+			want := math.Sin(2 * math.Pi * 0.1 * float64(m) * 147 / 160)
+			check.EqEps(t, float64(rational[m]), want, test.maxError, test.q, m)
+			check.EqEps(t, float64(ratio[m]), want, test.maxError, test.q, m)
+		}
+	}
+
+	up := Resample(in, 3, 1, ResampleMedium)
+	for m := 300; m < len(up)-300; m++ {
+		want := math.Sin(2 * math.Pi * 0.1 * float64(m) / 3)
+		check.EqEps(t, float64(up[m]), want, 3e-5, m)
+	}
+}
+
+func TestAliasingOfSweepNearNyquistIsSuppressed(t *testing.T) {
+	// The sweep goes from just above to just below the Nyquist frequency of
+	// the decimated signal to half the input sample rate. All of it must be
+	// filtered out.
+	n := 20000
+	sweep := make([]FLOAT, n)
+	f0, f1 := 0.26, 0.49
+	for i := range sweep {
+		x := float64(i)
+		sweep[i] = FLOAT(math.Sin(2 * math.Pi * (f0*x + (f1-f0)*x*x/(2*float64(n)))))
+	}
+	level := func(a []FLOAT) float64 {
+		a = a[200 : len(a)-200]
+		var sum float64
+		for _, x := range a {
+			sum += float64(x) * float64(x)
+		}
+		// Relative to the power of the sweep.
+		return 10 * math.Log10(sum/float64(len(a))/0.5)
+	}
+	for _, test := range []struct {
+		q        ResampleQuality
+		maxLevel float64
+	}{
+		{ResampleLow, -60},
+		{ResampleMedium, -90},
+		{ResampleHigh, -120},
+	} {
+		rational := level(Resample(sweep, 1, 2, test.q))
+		ratio := level(ResampleRatio(sweep, 0.5, test.q))
+		check.Eq(t, rational < test.maxLevel, true, test.q, rational)
+		check.Eq(t, ratio < test.maxLevel, true, test.q, ratio)
+	}
+}
+
+func TestStreamingResamplerMatchesBatch(t *testing.T) {
+	in := randomFloats(5000, 2)
+	rnd := rand.New(rand.NewSource(3))
+	for _, r := range []*Resampler{
+		NewResampler(2, 3, ResampleMedium),
+		NewRatioResampler(1.2345, ResampleLow),
+	} {
+		var want []FLOAT
+		if r.up > 0 {
+			want = Resample(in, 2, 3, ResampleMedium)
+		} else {
+			want = ResampleRatio(in, 1.2345, ResampleLow)
+		}
+		for round := 0; round < 2; round++ {
+			var out []FLOAT
+			for i := 0; i < len(in); {
+				n := rnd.Intn(300)
+				if i+n > len(in) {
+					n = len(in) - i
+				}
+				out = append(out, r.Process(in[i:i+n])...)
+				i += n
+			}
+			out = append(out, r.Flush()...)
+			check.Eq(t, out, want)
+		}
+	}
+}
+
+func TestInvalidResamplingFactorsAreClamped(t *testing.T) {
+	check.Eq(t, NewResampler(0, -1, ResampleLow).Ratio(), 1)
+	check.Eq(t, NewResampler(6, 4, ResampleLow).Ratio(), 1.5)
+	check.Eq(t, NewRatioResampler(-2, ResampleLow).Ratio(), 1)
+	check.Eq(t, NewResampler(2003, 1000, ResampleLow).Ratio(), 2.003)
+}
+
+func TestTinyRatiosHaveBoundedFilterLength(t *testing.T) {
+	limit := NewRatioResampler(1.0/1024, ResampleHigh).halfTaps
+	check.Eq(t, NewRatioResampler(1e-12, ResampleHigh).halfTaps, limit)
+	check.Eq(t, NewResampler(1, 1000000, ResampleHigh).halfTaps, limit)
+	b := ResampleRatio(make([]FLOAT, 5000), 1e-6, ResampleHigh)
+	check.Eq(t, len(b), 1)
+}
@@ -0,0 +1,57 @@
+package dsp
+
+import (
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestLinspaceWithAndWithoutEndpoint(t *testing.T) {
+	check.Eq(t, Linspace(0, 1, 5, true), []FLOAT{0, 0.25, 0.5, 0.75, 1})
+	check.Eq(t, Linspace(0, 1, 4, false), []FLOAT{0, 0.25, 0.5, 0.75})
+	check.Eq(t, Linspace(2, -2, 3, true), []FLOAT{2, 0, -2})
+	check.Eq(t, Linspace(3, 5, 1, true), []FLOAT{3})
+	check.Eq(t, Linspace(3, 5, 0, true), []FLOAT{})
+	// The last value is exactly stop, not a sum of rounded steps.
+	a := Linspace(0, 0.3, 7, true)
+	check.Eq(t, a[6], FLOAT(0.3))
+}
+
+func TestLogspaceAndGeomspace(t *testing.T) {
+	check.Eq(t, Logspace(0, 3, 4, true), []FLOAT{1, 10, 100, 1000})
+	check.Eq(t, Geomspace(1, 1000, 4, true), []FLOAT{1, 10, 100, 1000})
+	check.Eq(t, Geomspace(-16, -1, 4, false), []FLOAT{-16, -8, -4, -2})
+	check.Eq(t, Geomspace(-1, 1, 4, true), []FLOAT{})
+	check.Eq(t, Geomspace(0, 1, 4, true), []FLOAT{})
+	check.Eq(t, Geomspace(-1, 0, 4, true), []FLOAT{})
+	// The product of these ends underflows in float32.
+	tiny := Geomspace(1e-30, 1e-20, 3, true)
+	check.Eq(t, len(tiny), 3)
+	check.EqEps(t, tiny[1]/1e-25, 1, 1e-4)
+	b := Logspace(1, 2, 3, false)
+	check.EqEps(t, b[1], 21.544347, 1e-4)
+}
+
+func TestArangeExcludesStop(t *testing.T) {
+	check.Eq(t, Arange(0, 1, 0.25), []FLOAT{0, 0.25, 0.5, 0.75})
+	check.Eq(t, Arange(3, 0, -1), []FLOAT{3, 2, 1})
+	check.Eq(t, len(Arange(1, 1.3, 0.1)), 3)
+	check.Eq(t, len(Arange(0, 1.01, 0.1)), 11)
+	check.Eq(t, Arange(0, 0.3, 0.1), []FLOAT{0, 0.1, 0.2})
+	check.Eq(t, len(Arange(1000, 1000.3, 0.1)), 3)
+	check.Eq(t, len(Arange(-0.3, 0, 0.1)), 3)
+	check.Eq(t, Arange(0, 1, 0), []FLOAT{})
+	check.Eq(t, Arange(0, 1, -1), []FLOAT{})
+	check.Eq(t, Arange(1, 1, 1), []FLOAT{})
+}
+
+func TestTimeAndFrequencyAxes(t *testing.T) {
+	check.Eq(t, TimeAxis(4, 2), []FLOAT{0, 0.5, 1, 1.5})
+	check.Eq(t, TimeAxis(-1, 2), []FLOAT{})
+	check.Eq(t, FFTFreq(4, 8), []FLOAT{0, 2, -4, -2})
+	check.Eq(t, FFTFreq(5, 5), []FLOAT{0, 1, 2, -2, -1})
+	check.Eq(t, FFTFreq(0, 5), []FLOAT{})
+	check.Eq(t, RFFTFreq(4, 8), []FLOAT{0, 2, 4})
+	check.Eq(t, RFFTFreq(5, 5), []FLOAT{0, 1, 2})
+	check.Eq(t, RFFTFreq(0, 5), []FLOAT{})
+}
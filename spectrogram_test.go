@@ -0,0 +1,40 @@
+package dsp
+
+import (
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+func TestSpectrogramCentersFrames(t *testing.T) {
+	a := make([]FLOAT, 64)
+	for i := range a {
+		a[i] = 1
+	}
+	s := Spectrogram(a, 16, 4)
+	check.Eq(t, len(s), 17)
+	check.Eq(t, len(s[0]), 9)
+	// A whole frame of ones sums up the periodic Hann window, which is half
+	// the frame length. The first frame only holds the right half of the
+	// window.
+	check.EqEps(t, s[8][0], 64, 1e-4)
+	check.EqEps(t, s[8][1], 16, 1e-4)
+	check.EqEps(t, s[8][2], 0, 1e-4)
+	check.EqEps(t, s[0][0], 4.5*4.5, 1e-4)
+}
+
+func TestSpectrogramPeaksAtToneBin(t *testing.T) {
+	a := Sine(1024, 1, 1000, 0, 8000)
+	s := Spectrogram(a, 64, 16)
+	frame := s[len(s)/2]
+	best := 0
+	for k := range frame {
+		if frame[k] > frame[best] {
+			best = k
+		}
+	}
+	check.Eq(t, best, 8)
+	// The Hann window halves the amplitude of a sine wave in its bin, which
+	// is half the frame length.
+	check.EqEps(t, frame[best], 16*16, 1e-2)
+}
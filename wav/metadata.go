@@ -0,0 +1,236 @@
+package wav
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Metadata holds all information in a WAVE file other than the format and the
+// samples.
+type Metadata struct {
+	// Info holds the entries of the LIST/INFO chunk, e.g. INAM for the title
+	// or ICMT for a comment.
+	Info []InfoEntry
+	// Cues holds the entries of the cue chunk.
+	Cues []CuePoint
+	// Sampler holds the smpl chunk, it is nil if there is none.
+	Sampler *Sampler
+	// Chunks holds all other chunks, unparsed.
+	Chunks []Chunk
+}
+
+// InfoEntry is one text entry in a LIST/INFO chunk.
+type InfoEntry struct {
+	// ID is the four character code of the entry, e.g. "INAM".
+	ID   string
+	Text string
+}
+
+// CuePoint marks a position in the sample data.
+type CuePoint struct {
+	ID       uint32
+	Position uint32
+	// DataChunkID is the four character code of the chunk that contains the
+	// cue point, usually "data".
+	DataChunkID  string
+	ChunkStart   uint32
+	BlockStart   uint32
+	SampleOffset uint32
+}
+
+// Sampler holds the contents of a smpl chunk which describes how to play the
+// file in a sampler.
+type Sampler struct {
+	Manufacturer      uint32
+	Product           uint32
+	SamplePeriod      uint32
+	MIDIUnityNote     uint32
+	MIDIPitchFraction uint32
+	SMPTEFormat       uint32
+	SMPTEOffset       uint32
+	Loops             []SampleLoop
+	// Data is manufacturer specific data following the loops.
+	Data []byte
+}
+
+// SampleLoop is a loop in a Sampler.
+type SampleLoop struct {
+	CuePointID uint32
+	Type       uint32
+	Start      uint32
+	End        uint32
+	Fraction   uint32
+	PlayCount  uint32
+}
+
+// Chunk is a RIFF chunk that this package does not parse.
+type Chunk struct {
+	// ID is the four character code of the chunk.
+	ID   string
+	Data []byte
+}
+
+// decode parses the chunk if it contains metadata and adds it to m. If the
+// chunk is not understood, it is stored in m.Chunks.
+func (m *Metadata) decode(id string, data []byte) error {
+	switch {
+	case id == "LIST" && len(data) >= 4 && string(data[:4]) == "INFO":
+		return m.decodeInfo(data[4:])
+	case id == "cue ":
+		return m.decodeCues(data)
+	case id == "smpl" && m.Sampler == nil:
+		return m.decodeSampler(data)
+	}
+	m.Chunks = append(m.Chunks, Chunk{ID: id, Data: append([]byte(nil), data...)})
+	return nil
+}
+
+func (m *Metadata) decodeInfo(data []byte) error {
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return errors.New("wav: LIST/INFO chunk is truncated")
+		}
+		id := string(data[:4])
+		size := int(u32(data[4:]))
+		data = data[8:]
+		if size > len(data) {
+			return errors.New("wav: LIST/INFO entry is truncated")
+		}
+		text := bytes.TrimRight(data[:size], "\x00")
+		m.Info = append(m.Info, InfoEntry{ID: id, Text: string(text)})
+		size += size % 2
+		if size > len(data) {
+			size = len(data)
+		}
+		data = data[size:]
+	}
+	return nil
+}
+
+func (m *Metadata) decodeCues(data []byte) error {
+	if len(data) < 4 {
+		return errors.New("wav: cue chunk is too short")
+	}
+	n := int(u32(data))
+	data = data[4:]
+	if n > len(data)/24 {
+		return errors.New("wav: cue chunk is truncated")
+	}
+	for i := 0; i < n; i++ {
+		c := data[i*24:]
+		m.Cues = append(m.Cues, CuePoint{
+			ID:           u32(c[0:]),
+			Position:     u32(c[4:]),
+			DataChunkID:  string(c[8:12]),
+			ChunkStart:   u32(c[12:]),
+			BlockStart:   u32(c[16:]),
+			SampleOffset: u32(c[20:]),
+		})
+	}
+	return nil
+}
+
+func (m *Metadata) decodeSampler(data []byte) error {
+	if len(data) < 36 {
+		return errors.New("wav: smpl chunk is too short")
+	}
+	s := &Sampler{
+		Manufacturer:      u32(data[0:]),
+		Product:           u32(data[4:]),
+		SamplePeriod:      u32(data[8:]),
+		MIDIUnityNote:     u32(data[12:]),
+		MIDIPitchFraction: u32(data[16:]),
+		SMPTEFormat:       u32(data[20:]),
+		SMPTEOffset:       u32(data[24:]),
+	}
+	loopCount := int(u32(data[28:]))
+	dataSize := int(u32(data[32:]))
+	data = data[36:]
+	if loopCount > len(data)/24 || dataSize > len(data)-loopCount*24 {
+		return errors.New("wav: smpl chunk is truncated")
+	}
+	for i := 0; i < loopCount; i++ {
+		l := data[i*24:]
+		s.Loops = append(s.Loops, SampleLoop{
+			CuePointID: u32(l[0:]),
+			Type:       u32(l[4:]),
+			Start:      u32(l[8:]),
+			End:        u32(l[12:]),
+			Fraction:   u32(l[16:]),
+			PlayCount:  u32(l[20:]),
+		})
+	}
+	data = data[loopCount*24:]
+	if dataSize > 0 {
+		s.Data = append([]byte(nil), data[:dataSize]...)
+	}
+	m.Sampler = s
+	return nil
+}
+
+// encode writes all metadata as chunks to w.
+func (m *Metadata) encode(w *bytes.Buffer) error {
+	if len(m.Info) > 0 {
+		var b bytes.Buffer
+		b.WriteString("INFO")
+		for _, e := range m.Info {
+			if len(e.ID) != 4 {
+				return errors.New("wav: LIST/INFO entry IDs must have 4 characters")
+			}
+			writeChunk(&b, e.ID, append([]byte(e.Text), 0))
+		}
+		writeChunk(w, "LIST", b.Bytes())
+	}
+
+	if len(m.Cues) > 0 {
+		var b bytes.Buffer
+		b.Write(le32(uint32(len(m.Cues))))
+		for _, c := range m.Cues {
+			if len(c.DataChunkID) != 4 {
+				return errors.New("wav: cue point data chunk IDs must have 4 characters")
+			}
+			b.Write(le32(c.ID))
+			b.Write(le32(c.Position))
+			b.WriteString(c.DataChunkID)
+			b.Write(le32(c.ChunkStart))
+			b.Write(le32(c.BlockStart))
+			b.Write(le32(c.SampleOffset))
+		}
+		writeChunk(w, "cue ", b.Bytes())
+	}
+
+	if s := m.Sampler; s != nil {
+		var b bytes.Buffer
+		for _, v := range []uint32{
+			s.Manufacturer,
+			s.Product,
+			s.SamplePeriod,
+			s.MIDIUnityNote,
+			s.MIDIPitchFraction,
+			s.SMPTEFormat,
+			s.SMPTEOffset,
+			uint32(len(s.Loops)),
+			uint32(len(s.Data)),
+		} {
+			b.Write(le32(v))
+		}
+		for _, l := range s.Loops {
+			b.Write(le32(l.CuePointID))
+			b.Write(le32(l.Type))
+			b.Write(le32(l.Start))
+			b.Write(le32(l.End))
+			b.Write(le32(l.Fraction))
+			b.Write(le32(l.PlayCount))
+		}
+		b.Write(s.Data)
+		writeChunk(w, "smpl", b.Bytes())
+	}
+
+	for _, c := range m.Chunks {
+		if len(c.ID) != 4 {
+			return errors.New("wav: chunk IDs must have 4 characters")
+		}
+		writeChunk(w, c.ID, c.Data)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package wav
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// Reader reads the samples of a WAVE file in blocks, without loading the whole
+// file into memory.
+type Reader struct {
+	// Format is the format of the samples.
+	Format Format
+	// Metadata holds all metadata chunks read so far. Chunks that follow the
+	// sample data are only available after Read returned io.EOF.
+	Metadata Metadata
+
+	r          io.Reader
+	riffLeft   int64
+	frameCount int
+	framesLeft int
+	// dataTail is the number of bytes in the data chunk after the last whole
+	// frame, including the pad byte.
+	dataTail int64
+	buf      []byte
+	buf64    [][]float64
+	err      error
+}
+
+// readBufferSize is the maximum number of bytes that Read reads from the
+// underlying io.Reader at once.
+const readBufferSize = 64 * 1024
+
+// unknownSize is stored as the data chunk size by writers that do not know the
+// size in advance.
+const unknownSize = 0xFFFFFFFF
+
+// NewReader reads the WAVE header from r, up to the start of the sample data.
+func NewReader(r io.Reader) (*Reader, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, errors.New("wav: file is too short")
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, errors.New("wav: not a RIFF/WAVE file")
+	}
+
+	wr := &Reader{r: r, riffLeft: int64(u32(header[4:])) - 4}
+	haveFormat := false
+	for {
+		id, size, err := wr.readChunkHeader()
+		if err == io.EOF {
+			return nil, errors.New("wav: missing data chunk")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if id == "data" {
+			if !haveFormat {
+				return nil, errors.New("wav: data chunk before fmt chunk")
+			}
+			blockAlign := int64(wr.Format.blockAlign())
+			if size == unknownSize || size > wr.riffLeft {
+				// Streaming writers cannot know the size in advance and
+				// leave it at 0xFFFFFFFF or some other value that is too
+				// large, the samples then extend to the end of the file.
+				wr.frameCount = -1
+				wr.framesLeft = -1
+				return wr, nil
+			}
+			wr.frameCount = int(size / blockAlign)
+			wr.framesLeft = wr.frameCount
+			wr.dataTail = size%blockAlign + size%2
+			return wr, nil
+		}
+
+		data, err := wr.readChunkData(size)
+		if err != nil {
+			return nil, err
+		}
+		switch id {
+		case "fmt ":
+			wr.Format, err = decodeFormat(data)
+			haveFormat = true
+		case "fact":
+			// The fact chunk only repeats the number of frames, it is
+			// re-created when writing.
+		default:
+			err = wr.Metadata.decode(id, data)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// FrameCount returns the total number of frames in the file. A frame contains
+// one sample for each channel. If the data chunk size is 0xFFFFFFFF or larger
+// than the RIFF chunk allows, -1 is returned and the samples extend to the end
+// of the file.
+func (r *Reader) FrameCount() int {
+	return r.frameCount
+}
+
+// Read reads the next frames into dst which must contain one slice per
+// channel. It reads at most as many frames as the shortest slice in dst can
+// hold and returns the number of frames read. At the end of the sample data it
+// returns 0 and io.EOF.
+func (r *Reader) Read(dst [][]float64) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(dst) != r.Format.ChannelCount {
+		return 0, errors.New("wav: Read needs one buffer per channel")
+	}
+
+	if r.framesLeft == 0 {
+		r.err = r.readTrailingChunks()
+		if r.err == nil {
+			r.err = io.EOF
+		}
+		return 0, r.err
+	}
+
+	blockAlign := r.Format.blockAlign()
+	n := readBufferSize / blockAlign
+	if n == 0 {
+		n = 1
+	}
+	if r.framesLeft > 0 && n > r.framesLeft {
+		n = r.framesLeft
+	}
+	for _, c := range dst {
+		if len(c) < n {
+			n = len(c)
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	if len(r.buf) < n*blockAlign {
+		r.buf = make([]byte, n*blockAlign)
+	}
+	buf := r.buf[:n*blockAlign]
+	if r.framesLeft < 0 {
+		return r.readToEOF(dst, buf)
+	}
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		r.err = err
+		return 0, err
+	}
+	r.riffLeft -= int64(len(buf))
+	r.framesLeft -= n
+	return r.Format.sampleFormat().Deinterleave(dst, 0, buf), nil
+}
+
+// readToEOF reads the frames of a data chunk of unknown size into dst. A
+// partial frame at the end of the file is dropped.
+func (r *Reader) readToEOF(dst [][]float64, buf []byte) (int, error) {
+	read, err := io.ReadFull(r.r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		r.err = io.EOF
+	} else if err != nil {
+		r.err = err
+		return 0, err
+	}
+	n := read / r.Format.blockAlign()
+	if n == 0 {
+		return 0, r.err
+	}
+	buf = buf[:n*r.Format.blockAlign()]
+	return r.Format.sampleFormat().Deinterleave(dst, 0, buf), nil
+}
+
+// ReadFloat32 is like Read but converts the samples to float32.
+func (r *Reader) ReadFloat32(dst [][]float32) (int, error) {
+	if len(dst) != r.Format.ChannelCount {
+		return 0, errors.New("wav: Read needs one buffer per channel")
+	}
+	if r.buf64 == nil {
+		r.buf64 = make([][]float64, len(dst))
+		for i := range r.buf64 {
+			r.buf64[i] = make([]float64, 4096)
+		}
+	}
+	buf := make([][]float64, len(dst))
+	for i := range buf {
+		buf[i] = r.buf64[i]
+		if len(dst[i]) < len(buf[i]) {
+			buf[i] = buf[i][:len(dst[i])]
+		}
+	}
+	n, err := r.Read(buf)
+	for i := range buf {
+		for j := 0; j < n; j++ {
+			dst[i][j] = float32(buf[i][j])
+		}
+	}
+	return n, err
+}
+
+func (r *Reader) readTrailingChunks() error {
+	if _, err := r.skip(r.dataTail); err != nil {
+		// A missing pad byte at the end of the file is not a problem.
+		return nil
+	}
+	for {
+		id, size, err := r.readChunkHeader()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data, err := r.readChunkData(size)
+		if err != nil {
+			return err
+		}
+		if id != "fmt " && id != "fact" && id != "data" {
+			if err := r.Metadata.decode(id, data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readChunkHeader returns io.EOF if the RIFF chunk or the file ends before the
+// next chunk.
+func (r *Reader) readChunkHeader() (id string, size int64, err error) {
+	if r.riffLeft < 8 {
+		return "", 0, io.EOF
+	}
+	var header [8]byte
+	n, err := io.ReadFull(r.r, header[:])
+	if n == 0 && err == io.EOF {
+		return "", 0, io.EOF
+	}
+	if err != nil {
+		return "", 0, errors.New("wav: truncated chunk header")
+	}
+	r.riffLeft -= 8
+	return string(header[:4]), int64(u32(header[4:])), nil
+}
+
+// readChunkData reads the chunk contents and skips the pad byte.
+func (r *Reader) readChunkData(size int64) ([]byte, error) {
+	if size > r.riffLeft {
+		return nil, errors.New("wav: chunk is larger than the file")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, errors.New("wav: truncated chunk")
+	}
+	r.riffLeft -= size
+	if size%2 == 1 && r.riffLeft > 0 {
+		if _, err := r.skip(1); err != nil {
+			return nil, errors.New("wav: truncated chunk")
+		}
+	}
+	return data, nil
+}
+
+func (r *Reader) skip(n int64) (int64, error) {
+	m, err := io.CopyN(ioutil.Discard, r.r, n)
+	r.riffLeft -= m
+	return m, err
+}
@@ -0,0 +1,294 @@
+// Package wav reads and writes WAVE audio files. Samples are deinterleaved
+// into one slice per channel and normalized to the range [-1,1].
+//
+// Supported are PCM samples of 8, 16, 24 and 32 bits and IEEE float samples of
+// 32 and 64 bits, both in the plain and the WAVE_FORMAT_EXTENSIBLE format.
+// Metadata in LIST/INFO, cue and smpl chunks is parsed, all other chunks are
+// kept as raw bytes so they can be written back unchanged.
+package wav
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/gonutz/dsp/pcm"
+)
+
+// Encoding is the type of samples in a file.
+type Encoding int
+
+const (
+	// PCM samples are integers, 8 bit samples are unsigned, all others signed.
+	PCM Encoding = 1
+	// Float samples are IEEE 754 floats of 32 or 64 bits.
+	Float Encoding = 3
+)
+
+const formatExtensible = 0xFFFE
+
+// Format describes the layout of the sample data.
+type Format struct {
+	Encoding      Encoding
+	ChannelCount  int
+	SampleRate    int
+	BitsPerSample int
+	// Extensible is true if the format is stored as WAVE_FORMAT_EXTENSIBLE.
+	// ValidBitsPerSample and ChannelMask are only stored in this case.
+	Extensible bool
+	// ValidBitsPerSample is the number of significant bits in a sample, it may
+	// be smaller than BitsPerSample. If it is 0, BitsPerSample is used.
+	ValidBitsPerSample int
+	// ChannelMask assigns speaker positions to the channels.
+	ChannelMask uint32
+}
+
+func (f Format) validate() error {
+	if f.Encoding != PCM && f.Encoding != Float {
+		return errors.New("wav: unsupported encoding")
+	}
+	if f.ChannelCount <= 0 || f.ChannelCount > 0xFFFF {
+		return errors.New("wav: invalid channel count")
+	}
+	if f.SampleRate <= 0 || int64(f.SampleRate) > 0xFFFFFFFF {
+		return errors.New("wav: invalid sample rate")
+	}
+	if err := f.sampleFormat().Validate(); err != nil {
+		return errors.New("wav: unsupported bits per sample")
+	}
+	return nil
+}
+
+func (f Format) sampleFormat() pcm.SampleFormat {
+	s := pcm.SampleFormat{Encoding: pcm.Signed, Bits: f.BitsPerSample}
+	if f.Encoding == Float {
+		s.Encoding = pcm.Float
+	} else if f.BitsPerSample == 8 {
+		s.Encoding = pcm.Unsigned
+	}
+	return s
+}
+
+func (f Format) blockAlign() int {
+	return f.ChannelCount * f.BitsPerSample / 8
+}
+
+// File is a complete WAVE file in memory.
+type File struct {
+	Format
+	Metadata
+	// Samples contains one slice per channel, all of the same length.
+	Samples [][]float64
+}
+
+// Float32 returns the samples converted to float32.
+func (f *File) Float32() [][]float32 {
+	return pcm.Float32(f.Samples)
+}
+
+// Decode reads a whole WAVE file from r.
+func Decode(r io.Reader) (*File, error) {
+	wr, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	size := wr.FrameCount()
+	if size < 0 {
+		size = 4096
+	}
+	samples := make([][]float64, wr.Format.ChannelCount)
+	for i := range samples {
+		samples[i] = make([]float64, size)
+	}
+	n := 0
+	for {
+		if wr.FrameCount() < 0 && n == len(samples[0]) {
+			for i := range samples {
+				samples[i] = append(samples[i], make([]float64, len(samples[i]))...)
+			}
+		}
+		var m int
+		m, err = wr.Read(offset(samples, n))
+		n += m
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range samples {
+		samples[i] = samples[i][:n]
+	}
+	return &File{
+		Format:   wr.Format,
+		Metadata: wr.Metadata,
+		Samples:  samples,
+	}, nil
+}
+
+func offset(channels [][]float64, n int) [][]float64 {
+	o := make([][]float64, len(channels))
+	for i := range o {
+		o[i] = channels[i][n:]
+	}
+	return o
+}
+
+// Encode writes f as a WAVE file to w. All channels in f.Samples must have the
+// same length and there must be f.ChannelCount of them.
+func Encode(w io.Writer, f *File) error {
+	if err := f.Format.validate(); err != nil {
+		return err
+	}
+	if len(f.Samples) != f.ChannelCount {
+		return errors.New("wav: number of sample channels does not match format")
+	}
+	frames := 0
+	if len(f.Samples) > 0 {
+		frames = len(f.Samples[0])
+	}
+	for _, c := range f.Samples {
+		if len(c) != frames {
+			return errors.New("wav: channels have different lengths")
+		}
+	}
+
+	header, err := encodeHeader(f.Format, &f.Metadata, frames)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	data := make([]byte, frames*f.blockAlign())
+	f.sampleFormat().Interleave(data, f.Samples, 0)
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// encodeHeader returns everything in the file up to and including the data
+// chunk header. Its length does not depend on frameCount.
+func encodeHeader(f Format, m *Metadata, frameCount int) ([]byte, error) {
+	dataSize := frameCount * f.blockAlign()
+	if int64(dataSize) > 0xFFFFFFFF-1024 {
+		return nil, errors.New("wav: too much sample data for a WAVE file")
+	}
+
+	var chunks bytes.Buffer
+	writeChunk(&chunks, "fmt ", encodeFormat(f))
+	if f.Encoding != PCM {
+		// Non-PCM formats require a fact chunk with the number of frames.
+		writeChunk(&chunks, "fact", le32(uint32(frameCount)))
+	}
+	if err := m.encode(&chunks); err != nil {
+		return nil, err
+	}
+	chunks.WriteString("data")
+	chunks.Write(le32(uint32(dataSize)))
+
+	riffSize := 4 + chunks.Len() + dataSize + dataSize%2
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	b.Write(le32(uint32(riffSize)))
+	b.WriteString("WAVE")
+	b.Write(chunks.Bytes())
+	return b.Bytes(), nil
+}
+
+// extensibleGUIDTail is the part of the sub-format GUID that follows the
+// 2-byte format code.
+var extensibleGUIDTail = []byte{
+	0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00,
+	0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71,
+}
+
+func encodeFormat(f Format) []byte {
+	var b bytes.Buffer
+	if f.Extensible {
+		b.Write(le16(formatExtensible))
+	} else {
+		b.Write(le16(uint16(f.Encoding)))
+	}
+	b.Write(le16(uint16(f.ChannelCount)))
+	b.Write(le32(uint32(f.SampleRate)))
+	b.Write(le32(uint32(f.SampleRate * f.blockAlign())))
+	b.Write(le16(uint16(f.blockAlign())))
+	b.Write(le16(uint16(f.BitsPerSample)))
+	if f.Extensible {
+		valid := f.ValidBitsPerSample
+		if valid == 0 {
+			valid = f.BitsPerSample
+		}
+		b.Write(le16(22))
+		b.Write(le16(uint16(valid)))
+		b.Write(le32(f.ChannelMask))
+		b.Write(le16(uint16(f.Encoding)))
+		b.Write(extensibleGUIDTail)
+	} else if f.Encoding != PCM {
+		b.Write(le16(0))
+	}
+	return b.Bytes()
+}
+
+func decodeFormat(data []byte) (Format, error) {
+	var f Format
+	if len(data) < 16 {
+		return f, errors.New("wav: fmt chunk too short")
+	}
+	tag := u16(data[0:])
+	f.ChannelCount = int(u16(data[2:]))
+	f.SampleRate = int(u32(data[4:]))
+	f.BitsPerSample = int(u16(data[14:]))
+	if tag == formatExtensible {
+		if len(data) < 40 {
+			return f, errors.New("wav: extensible fmt chunk too short")
+		}
+		f.Extensible = true
+		f.ValidBitsPerSample = int(u16(data[18:]))
+		f.ChannelMask = u32(data[20:])
+		tag = u16(data[24:])
+		if !bytes.Equal(data[26:40], extensibleGUIDTail) {
+			return f, errors.New("wav: unsupported extensible sub-format")
+		}
+	}
+	f.Encoding = Encoding(tag)
+	if err := f.validate(); err != nil {
+		return f, err
+	}
+	// The block align field only has 16 bits, larger frames are truncated by
+	// writers.
+	if u16(data[12:]) != uint16(f.blockAlign()) {
+		return f, errors.New("wav: block align does not match format")
+	}
+	return f, nil
+}
+
+func writeChunk(w *bytes.Buffer, id string, data []byte) {
+	w.WriteString(id)
+	w.Write(le32(uint32(len(data))))
+	w.Write(data)
+	if len(data)%2 == 1 {
+		w.WriteByte(0)
+	}
+}
+
+func le16(v uint16) []byte {
+	return []byte{byte(v), byte(v >> 8)}
+}
+
+func le32(v uint32) []byte {
+	return []byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)}
+}
+
+func u16(b []byte) uint16 {
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+func u32(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
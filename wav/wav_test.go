@@ -0,0 +1,401 @@
+package wav
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gonutz/check"
+)
+
+// riff builds a RIFF/WAVE file from the given chunks, each of which already
+// includes its header.
+func riff(chunks ...[]byte) []byte {
+	var body []byte
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	b := []byte("RIFF")
+	b = append(b, le32(uint32(4+len(body)))...)
+	b = append(b, "WAVE"...)
+	return append(b, body...)
+}
+
+func chunk(id string, data ...byte) []byte {
+	var b bytes.Buffer
+	writeChunk(&b, id, data)
+	return b.Bytes()
+}
+
+func TestDecode16BitStereoPCM(t *testing.T) {
+	f, err := Decode(bytes.NewReader(riff(
+		chunk("fmt ",
+			1, 0, // PCM
+			2, 0, // channels
+			0x44, 0xAC, 0, 0, // 44100 Hz
+			0x10, 0xB1, 2, 0, // bytes per second
+			4, 0, // block align
+			16, 0, // bits per sample
+		),
+		chunk("data",
+			0x00, 0x40, 0x00, 0xC0,
+			0xFF, 0x7F, 0x00, 0x80,
+			0x00, 0x00, 0x00, 0x20,
+		),
+	)))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Format, Format{
+		Encoding:      PCM,
+		ChannelCount:  2,
+		SampleRate:    44100,
+		BitsPerSample: 16,
+	})
+	check.Eq(t, f.Samples, [][]float64{
+		{0.5, 32767.0 / 32768, 0},
+		{-0.5, -1, 0.25},
+	})
+	check.Eq(t, f.Float32(), [][]float32{
+		{0.5, 32767.0 / 32768, 0},
+		{-0.5, -1, 0.25},
+	})
+}
+
+func TestDecode8BitMonoWithPadByteAndTrailingMetadata(t *testing.T) {
+	f, err := Decode(bytes.NewReader(riff(
+		chunk("fmt ",
+			1, 0, 1, 0,
+			0x40, 0x1F, 0, 0, // 8000 Hz
+			0x40, 0x1F, 0, 0,
+			1, 0, 8, 0,
+		),
+		chunk("data", 0, 128, 192),
+		chunk("LIST", append([]byte("INFO"), chunk("INAM", 'a', 'b', 0)...)...),
+	)))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.SampleRate, 8000)
+	check.Eq(t, f.BitsPerSample, 8)
+	check.Eq(t, f.Samples, [][]float64{{-1, 0, 0.5}})
+	check.Eq(t, f.Info, []InfoEntry{{ID: "INAM", Text: "ab"}})
+}
+
+func TestDecodeExtensible24Bit(t *testing.T) {
+	fmtChunk := []byte{
+		0xFE, 0xFF, // extensible
+		1, 0,
+		0x80, 0xBB, 0, 0, // 48000 Hz
+		0x80, 0x32, 2, 0,
+		3, 0, 24, 0,
+		22, 0, // extension size
+		20, 0, // valid bits
+		4, 0, 0, 0, // channel mask: front center
+		1, 0, // PCM
+	}
+	fmtChunk = append(fmtChunk, extensibleGUIDTail...)
+	f, err := Decode(bytes.NewReader(riff(
+		chunk("fmt ", fmtChunk...),
+		chunk("data", 0x00, 0x00, 0xC0, 0x00, 0x00, 0x40),
+	)))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Format, Format{
+		Encoding:           PCM,
+		ChannelCount:       1,
+		SampleRate:         48000,
+		BitsPerSample:      24,
+		Extensible:         true,
+		ValidBitsPerSample: 20,
+		ChannelMask:        4,
+	})
+	check.Eq(t, f.Samples, [][]float64{{-0.5, 0.5}})
+}
+
+func TestDecodeFloatWithFactChunk(t *testing.T) {
+	f, err := Decode(bytes.NewReader(riff(
+		chunk("fmt ",
+			3, 0, 1, 0,
+			0x40, 0x1F, 0, 0,
+			0x00, 0x7D, 0, 0,
+			4, 0, 32, 0,
+			0, 0,
+		),
+		chunk("fact", 2, 0, 0, 0),
+		chunk("data",
+			0x00, 0x00, 0xC0, 0x3F, // 1.5
+			0x00, 0x00, 0x80, 0xBE, // -0.25
+		),
+	)))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Encoding, Float)
+	check.Eq(t, f.Samples, [][]float64{{1.5, -0.25}})
+	check.Eq(t, len(f.Chunks), 0)
+}
+
+func TestEncodeWritesCanonicalFile(t *testing.T) {
+	var b bytes.Buffer
+	err := Encode(&b, &File{
+		Format: Format{
+			Encoding:      PCM,
+			ChannelCount:  1,
+			SampleRate:    8000,
+			BitsPerSample: 16,
+		},
+		Samples: [][]float64{{0.5, -1}},
+	})
+	check.Eq(t, err, nil)
+	check.Eq(t, b.Bytes(), riff(
+		chunk("fmt ",
+			1, 0, 1, 0,
+			0x40, 0x1F, 0, 0,
+			0x80, 0x3E, 0, 0,
+			2, 0, 16, 0,
+		),
+		chunk("data", 0x00, 0x40, 0x00, 0x80),
+	))
+}
+
+func TestAllFormatsRoundTrip(t *testing.T) {
+	samples := [][]float64{
+		{0, 0.5, -0.5, -1, 0.25},
+		{0.125, -0.125, 0.75, 0, -0.75},
+		{1.0 / 128, 0, 0, 0, 0},
+	}
+	formats := []Format{
+		{Encoding: PCM, BitsPerSample: 8},
+		{Encoding: PCM, BitsPerSample: 16},
+		{Encoding: PCM, BitsPerSample: 24},
+		{Encoding: PCM, BitsPerSample: 32},
+		{Encoding: Float, BitsPerSample: 32},
+		{Encoding: Float, BitsPerSample: 64},
+		{Encoding: PCM, BitsPerSample: 24, Extensible: true, ValidBitsPerSample: 24, ChannelMask: 7},
+		{Encoding: Float, BitsPerSample: 32, Extensible: true, ValidBitsPerSample: 32},
+	}
+	for _, format := range formats {
+		format.ChannelCount = 3
+		format.SampleRate = 22050
+		in := &File{Format: format, Samples: samples}
+		var b bytes.Buffer
+		check.Eq(t, Encode(&b, in), nil, format)
+		check.Eq(t, b.Len()%2, 0, format)
+		out, err := Decode(&b)
+		check.Eq(t, err, nil, format)
+		check.Eq(t, out, in, format)
+	}
+}
+
+func TestMetadataRoundTrips(t *testing.T) {
+	in := &File{
+		Format: Format{
+			Encoding:      PCM,
+			ChannelCount:  1,
+			SampleRate:    44100,
+			BitsPerSample: 8,
+		},
+		Metadata: Metadata{
+			Info: []InfoEntry{
+				{ID: "INAM", Text: "Title"},
+				{ID: "ICMT", Text: "odd"},
+			},
+			Cues: []CuePoint{
+				{ID: 1, Position: 2, DataChunkID: "data", SampleOffset: 2},
+				{ID: 2, Position: 5, DataChunkID: "data", SampleOffset: 5},
+			},
+			Sampler: &Sampler{
+				Manufacturer:  1,
+				SamplePeriod:  22675,
+				MIDIUnityNote: 60,
+				Loops: []SampleLoop{
+					{CuePointID: 1, Start: 2, End: 4, PlayCount: 0},
+				},
+				Data: []byte{1, 2, 3},
+			},
+			Chunks: []Chunk{
+				{ID: "LIST", Data: []byte("adtlxyz")},
+				{ID: "bext", Data: []byte{9, 8, 7}},
+			},
+		},
+		Samples: [][]float64{{0, 0.5, -0.5, 0, 0.5, -0.5, 0}},
+	}
+	var b bytes.Buffer
+	check.Eq(t, Encode(&b, in), nil)
+	out, err := Decode(&b)
+	check.Eq(t, err, nil)
+	check.Eq(t, out, in)
+}
+
+// seekBuffer is an in-memory io.WriteSeeker.
+type seekBuffer struct {
+	data []byte
+	pos  int
+}
+
+func (b *seekBuffer) Write(p []byte) (int, error) {
+	if b.pos+len(p) > len(b.data) {
+		b.data = append(b.data, make([]byte, b.pos+len(p)-len(b.data))...)
+	}
+	copy(b.data[b.pos:], p)
+	b.pos += len(p)
+	return len(p), nil
+}
+
+func (b *seekBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		b.pos = int(offset)
+	case io.SeekCurrent:
+		b.pos += int(offset)
+	case io.SeekEnd:
+		b.pos = len(b.data) + int(offset)
+	}
+	return int64(b.pos), nil
+}
+
+func TestStreamingWriteAndReadMatchesBatchFunctions(t *testing.T) {
+	format := Format{
+		Encoding:      PCM,
+		ChannelCount:  2,
+		SampleRate:    8000,
+		BitsPerSample: 16,
+	}
+	meta := Metadata{Info: []InfoEntry{{ID: "IART", Text: "me"}}}
+	left := make([]float64, 10000)
+	right := make([]float64, 10000)
+	for i := range left {
+		left[i] = float64(i%200-100) / 128
+		right[i] = -left[i]
+	}
+
+	var buf seekBuffer
+	w, err := NewWriter(&buf, format, meta)
+	check.Eq(t, err, nil)
+	for i := 0; i < len(left); i += 3000 {
+		end := i + 3000
+		if end > len(left) {
+			end = len(left)
+		}
+		check.Eq(t, w.Write([][]float64{left[i:end], right[i:end]}), nil)
+	}
+	check.Eq(t, w.Close(), nil)
+
+	var batch bytes.Buffer
+	Encode(&batch, &File{
+		Format:   format,
+		Metadata: meta,
+		Samples:  [][]float64{left, right},
+	})
+	check.Eq(t, buf.data, batch.Bytes())
+
+	r, err := NewReader(bytes.NewReader(buf.data))
+	check.Eq(t, err, nil)
+	check.Eq(t, r.FrameCount(), 10000)
+	var gotLeft, gotRight []float32
+	block := [][]float32{make([]float32, 777), make([]float32, 777)}
+	for {
+		n, err := r.ReadFloat32(block)
+		gotLeft = append(gotLeft, block[0][:n]...)
+		gotRight = append(gotRight, block[1][:n]...)
+		if err == io.EOF {
+			break
+		}
+		check.Eq(t, err, nil)
+	}
+	check.Eq(t, len(gotLeft), 10000)
+	for i := range left {
+		check.Eq(t, gotLeft[i], left[i])
+		check.Eq(t, gotRight[i], right[i])
+	}
+	check.Eq(t, r.Metadata, meta)
+}
+
+func TestInvalidFilesAreReported(t *testing.T) {
+	validFmt := chunk("fmt ", 1, 0, 1, 0, 1, 0, 0, 0, 2, 0, 0, 0, 2, 0, 16, 0)
+	files := [][]byte{
+		nil,
+		[]byte("RIFF\x04\x00\x00\x00WAVX"),
+		riff(),
+		riff(validFmt),
+		riff(chunk("data", 1, 2)),
+		riff(chunk("fmt ", 1, 0, 1, 0), chunk("data")),
+		riff(chunk("fmt ", 7, 0, 1, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 8, 0), chunk("data")),
+		riff(chunk("fmt ", 1, 0, 1, 0, 1, 0, 0, 0, 2, 0, 0, 0, 2, 0, 12, 0), chunk("data")),
+		riff(validFmt, chunk("cue ", 5, 0, 0, 0), chunk("data")),
+	}
+	for i, file := range files {
+		_, err := Decode(bytes.NewReader(file))
+		check.Neq(t, err, nil, i)
+	}
+}
+
+func TestTruncatedSampleDataIsAnError(t *testing.T) {
+	file := riff(
+		chunk("fmt ", 1, 0, 1, 0, 1, 0, 0, 0, 2, 0, 0, 0, 2, 0, 16, 0),
+		chunk("data", 1, 2, 3, 4),
+	)
+	_, err := Decode(bytes.NewReader(file[:len(file)-1]))
+	check.Eq(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestUnknownDataSizeReadsToEndOfFile(t *testing.T) {
+	// A streaming writer left both the RIFF and the data size at 0xFFFFFFFF.
+	file := []byte("RIFF\xFF\xFF\xFF\xFFWAVE")
+	file = append(file, chunk("fmt ", 1, 0, 1, 0, 1, 0, 0, 0, 2, 0, 0, 0, 2, 0, 16, 0)...)
+	file = append(file, "data\xFF\xFF\xFF\xFF"...)
+	file = append(file, 0x00, 0x40, 0x00, 0xC0, 0x00) // the last frame is cut off
+
+	r, err := NewReader(bytes.NewReader(file))
+	check.Eq(t, err, nil)
+	check.Eq(t, r.FrameCount(), -1)
+	dst := [][]float32{make([]float32, 10)}
+	n, err := r.ReadFloat32(dst)
+	check.Eq(t, n, 2)
+	check.Eq(t, err, nil)
+	check.Eq(t, dst[0][:n], []float32{0.5, -0.5})
+	n, err = r.ReadFloat32(dst)
+	check.Eq(t, n, 0)
+	check.Eq(t, err, io.EOF)
+
+	f, err := Decode(bytes.NewReader(file))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Samples, [][]float64{{0.5, -0.5}})
+}
+
+func TestDataSizeLargerThanTheFileReadsToEndOfFile(t *testing.T) {
+	file := riff(
+		chunk("fmt ", 1, 0, 1, 0, 1, 0, 0, 0, 2, 0, 0, 0, 2, 0, 16, 0),
+		[]byte("data\x00\x00\x01\x00"), // 65536 bytes
+	)
+	samples := make([]byte, 3*readBufferSize)
+	for i := 1; i < len(samples); i += 2 {
+		samples[i] = 0x20
+	}
+	file = append(file, samples...)
+
+	f, err := Decode(bytes.NewReader(file))
+	check.Eq(t, err, nil)
+	check.Eq(t, len(f.Samples[0]), len(samples)/2)
+	check.Eq(t, f.Samples[0][len(samples)/2-1], 0.25)
+}
+
+func TestFramesLargerThanTheReadBufferCanBeRead(t *testing.T) {
+	// 20000 channels of 32 bit floats make frames of 80000 bytes, which is
+	// more than the block align field can hold.
+	samples := make([][]float64, 20000)
+	for i := range samples {
+		samples[i] = []float64{float64(i%7) / 8, -float64(i%5) / 8}
+	}
+	format := Format{Encoding: Float, ChannelCount: 20000, SampleRate: 8000, BitsPerSample: 32}
+	var b bytes.Buffer
+	check.Eq(t, Encode(&b, &File{Format: format, Samples: samples}), nil)
+
+	f, err := Decode(bytes.NewReader(b.Bytes()))
+	check.Eq(t, err, nil)
+	check.Eq(t, f.Format, format)
+	check.Eq(t, f.Samples, samples)
+}
+
+func TestEncodeChecksSampleLayout(t *testing.T) {
+	format := Format{Encoding: PCM, ChannelCount: 2, SampleRate: 1, BitsPerSample: 16}
+	var b bytes.Buffer
+	check.Neq(t, Encode(&b, &File{Format: format, Samples: [][]float64{{1}}}), nil)
+	check.Neq(t, Encode(&b, &File{Format: format, Samples: [][]float64{{1}, {1, 2}}}), nil)
+	format.BitsPerSample = 64
+	check.Neq(t, Encode(&b, &File{Format: format, Samples: [][]float64{{1}, {1}}}), nil)
+}
@@ -0,0 +1,109 @@
+package wav
+
+import (
+	"errors"
+	"io"
+)
+
+// Writer writes a WAVE file in blocks of samples. The file header is updated
+// with the final sample count in Close.
+type Writer struct {
+	w        io.WriteSeeker
+	format   Format
+	metadata Metadata
+	start    int64
+	frames   int
+	buf      []byte
+	closed   bool
+}
+
+// NewWriter writes the WAVE header with the given format and metadata to w.
+// Samples are written with Write and Close must be called at the end.
+func NewWriter(w io.WriteSeeker, f Format, m Metadata) (*Writer, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	header, err := encodeHeader(f, &m, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, format: f, metadata: m, start: start}, nil
+}
+
+// Write appends frames to the file. src must contain one slice per channel,
+// all of the same length.
+func (w *Writer) Write(src [][]float64) error {
+	if w.closed {
+		return errors.New("wav: Write after Close")
+	}
+	if len(src) != w.format.ChannelCount {
+		return errors.New("wav: Write needs one buffer per channel")
+	}
+	n := len(src[0])
+	for _, c := range src {
+		if len(c) != n {
+			return errors.New("wav: channels have different lengths")
+		}
+	}
+
+	size := n * w.format.blockAlign()
+	if len(w.buf) < size {
+		w.buf = make([]byte, size)
+	}
+	w.format.sampleFormat().Interleave(w.buf[:size], src, 0)
+	if _, err := w.w.Write(w.buf[:size]); err != nil {
+		return err
+	}
+	w.frames += n
+	return nil
+}
+
+// WriteFloat32 is like Write but takes float32 samples.
+func (w *Writer) WriteFloat32(src [][]float32) error {
+	src64 := make([][]float64, len(src))
+	for i, c := range src {
+		src64[i] = make([]float64, len(c))
+		for j, x := range c {
+			src64[i][j] = float64(x)
+		}
+	}
+	return w.Write(src64)
+}
+
+// Close finishes the file by updating the header. It does not close the
+// underlying io.WriteSeeker.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.frames*w.format.blockAlign()%2 == 1 {
+		if _, err := w.w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	end, err := w.w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	header, err := encodeHeader(w.format, &w.metadata, w.frames)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Seek(w.start, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(header); err != nil {
+		return err
+	}
+	_, err = w.w.Seek(end, io.SeekStart)
+	return err
+}